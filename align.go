@@ -0,0 +1,122 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the report page aligning the translated /doc
+// HTML files with their english sources.
+//
+//	/align/?lang=zh_CN			summary of all translated docs
+//	/align/go_spec.html?lang=zh_CN		issues of a translated doc
+
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/golang-china/golangdoc/godoc"
+	"github.com/golang-china/golangdoc/local"
+)
+
+func alignHandler(w http.ResponseWriter, r *http.Request) {
+	lang := localLang(r.FormValue("lang"))
+	if lang == "" || !langRx.MatchString(lang) {
+		http.Error(w, "missing or invalid lang", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/align")
+
+	results, err := local.AlignDocuments(lang)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if name != "/" {
+		var found []*local.Alignment
+		for _, a := range results {
+			if a.Name == name {
+				found = append(found, a)
+			}
+		}
+		if len(found) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+		results = found
+	}
+
+	data := struct {
+		Lang    string
+		Detail  bool
+		Results []*local.Alignment
+	}{lang, name != "/", results}
+
+	var buf bytes.Buffer
+	if err := executeLang(&buf, alignTemplate, lang, data); err != nil {
+		log.Println("alignHandler:", err)
+	}
+	pres.ServePage(w, godoc.Page{
+		Title: pres.Translate(lang, "Translation alignment"),
+		Lang:  r.FormValue("lang"),
+		Body:  buf.Bytes(),
+	})
+}
+
+// alignCount returns the number of issues of kind in a.
+func alignCount(a *local.Alignment, kind string) int {
+	n := 0
+	for _, x := range a.Issues {
+		if x.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+var alignTemplate = template.Must(template.New("align").Funcs(langFuncs).Funcs(template.FuncMap{
+	"count": alignCount,
+}).Parse(`
+{{$lang := .Lang}}
+{{if .Detail}}
+{{range .Results}}
+{{$name := .Name}}
+<p><a href="/align/?lang={{$lang}}">{{T "All documents"}}</a> &middot; <a href="/doc{{.Name}}?lang={{$lang}}">{{T "translation"}}</a> &middot; <a href="/doc{{.Name}}?lang=en">{{T "english"}}</a></p>
+{{if .Missing}}<p>{{T "The english document has no translation."}}</p>{{else if not .Issues}}<p>{{T "The translation is aligned with the english document."}}</p>{{end}}
+<table class="dir">
+{{range .Issues}}
+<tr>
+	<td class="name"><b>{{T .Kind}}</b></td>
+	<td>{{if .Section}}<a href="/doc{{$name}}?lang={{$lang}}#{{.Section}}">{{.Section}}</a>{{end}}</td>
+	<td>{{with .English}}<pre>{{.}}</pre>{{end}}</td>
+	<td>{{with .Translation}}<pre>{{.}}</pre>{{end}}</td>
+</tr>
+{{end}}
+</table>
+{{end}}
+{{else}}
+{{if not .Results}}<p>{{T "No translated documents for %s." .Lang}}</p>{{end}}
+<table class="dir">
+<tr><th>{{T "Document"}}</th><th>{{T "Sections"}}</th><th>{{T "Missing"}}</th><th>{{T "Extra"}}</th><th>{{T "Code"}}</th><th>{{T "Paragraphs"}}</th><th>{{T "Untranslated"}}</th></tr>
+{{range .Results}}
+<tr>
+{{if .Missing}}
+	<td class="name"><a href="/doc{{.Name}}?lang=en">{{.Name}}</a></td>
+	<td>{{.Sections}}</td>
+	<td colspan="5">{{T "no translation"}}</td>
+{{else}}
+	<td class="name"><a href="/align{{.Name}}?lang={{$lang}}">{{.Name}}</a></td>
+	<td>{{.Sections}}</td>
+	<td>{{count . "missing"}}</td>
+	<td>{{count . "extra"}}</td>
+	<td>{{count . "code"}}</td>
+	<td>{{count . "paragraphs"}}</td>
+	<td>{{count . "untranslated"}}</td>
+{{end}}
+</tr>
+{{end}}
+</table>
+{{end}}
+`))
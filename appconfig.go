@@ -22,3 +22,9 @@ var flagLang = func() *string {
 	v := "zh_CN"
 	return &v
 }()
+
+// minimum review status of the shown translations
+var flagMinStatus = func() *string {
+	v := ""
+	return &v
+}()
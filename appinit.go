@@ -10,7 +10,6 @@ package main
 // See README.godoc-app for details.
 
 import (
-	"go/doc"
 	"log"
 	"regexp"
 
@@ -41,30 +40,12 @@ func init() {
 	corpus.IndexFiles = flagIndexFilenames
 
 	// translate hook
-	corpus.SummarizePackage = func(importPath string, langs ...string) (summary string, showList, ok bool) {
-		lang := *flagLang
-		if len(langs) > 0 && langs[0] != "" {
-			lang = langs[0]
-		}
-		if lang == "en" || lang == "raw" || lang == "EN" {
-			lang = ""
-		}
-		if pkg := local.Package(lang, importPath); pkg != nil {
-			summary = doc.Synopsis(pkg.Doc)
-		}
-		ok = (summary != "")
-		return
-	}
-	corpus.TranslateDocPackage = func(pkg *doc.Package, langs ...string) *doc.Package {
-		lang := *flagLang
-		if len(langs) > 0 && langs[0] != "" {
-			lang = langs[0]
-		}
-		if lang == "en" || lang == "raw" || lang == "EN" {
-			lang = ""
-		}
-		return local.Package(lang, pkg.ImportPath, pkg)
-	}
+	corpus.SummarizePackage = summarizePackage
+	corpus.TranslateDocPackage = translateDocPackage
+	corpus.DocumentFS = documentFS
+	corpus.Synonyms = synonyms
+	corpus.TranslateDoc = translateDoc
+	corpus.IndexLangs = indexLangs("")
 
 	if err := corpus.Init(); err != nil {
 		log.Fatal(err)
@@ -78,6 +59,10 @@ func init() {
 	pres.ShowPlayground = true
 	pres.ShowExamples = true
 	pres.DeclLinks = true
+	pres.Lang = localLang("")
+	pres.TranslateMessage = local.Message
+	pres.TemplatesFor = langTemplates
+	pres.PackageHeader = statusBadge
 	pres.NotesRx = regexp.MustCompile("BUG")
 
 	readTemplates(pres, true)
@@ -0,0 +1,54 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/golang-china/golangdoc/godoc"
+	"github.com/golang-china/golangdoc/local"
+)
+
+const specFilename = "/go_spec.html"
+
+// checkSpec prints the grammar issues of the served spec, and of the
+// translated specs compared with the english one. It returns the
+// number of issues.
+func checkSpec(w io.Writer) int {
+	n := 0
+	report := func(name string, issues []*godoc.GrammarIssue) {
+		for _, x := range issues {
+			fmt.Fprintf(w, "%s: %s\n", name, x)
+		}
+		n += len(issues)
+	}
+
+	served, err := vfs.ReadFile(fs, "/doc"+specFilename)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return 1
+	}
+	report("/doc"+specFilename, godoc.CheckGrammar(served))
+
+	english, err := vfs.ReadFile(local.DocumentFS(""), specFilename)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return n + 1
+	}
+	for _, lang := range local.DocumentLangs() {
+		src, err := vfs.ReadFile(local.DocumentFS(lang), specFilename)
+		if err != nil {
+			continue // spec is not translated
+		}
+		name := "/doc/" + lang + specFilename
+		report(name, godoc.CompareGrammar(english, src))
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang-china/golangdoc/local"
+)
+
+// align print the alignment of the translated /doc HTML files of lang
+// with the english ones, and returns the number of issues. The english
+// files without translation are listed, but are not issues.
+func align(w io.Writer, lang string) (int, error) {
+	results, err := local.AlignDocuments(lang)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, a := range results {
+		if a.Missing {
+			fmt.Fprintf(w, "%s: %d sections, no translation\n", a.Name, a.Sections)
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d sections, %d issues\n", a.Name, a.Sections, len(a.Issues))
+		for _, x := range a.Issues {
+			fmt.Fprintf(w, "\t%s %s", x.Kind, x.Section)
+			if x.English != "" {
+				fmt.Fprintf(w, "\n\t\t- %s", alignSnippet(x.English))
+			}
+			if x.Translation != "" {
+				fmt.Fprintf(w, "\n\t\t+ %s", alignSnippet(x.Translation))
+			}
+			fmt.Fprintln(w)
+		}
+		n += len(a.Issues)
+	}
+	return n, nil
+}
+
+// alignSnippet returns the first line of s, at most 72 runes.
+func alignSnippet(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[:i] + " ..."
+	}
+	if r := []rune(s); len(r) > 72 {
+		s = string(r[:72]) + " ..."
+	}
+	return s
+}
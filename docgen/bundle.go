@@ -0,0 +1,48 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/golang-china/golangdoc/local"
+)
+
+// bundle generate the precompiled bundle of lang, for the -GOOS/-GOARCH
+// flags or the GOOS/GOARCH of the environment.
+func bundle(lang string) (filename string, err error) {
+	local.SetTarget(flagGOOS, flagGOARCH)
+
+	b := local.MakeBundle(lang)
+	filename = bundleFilename(b)
+	if len(b.Packages) == 0 {
+		err = fmt.Errorf("no translated packages for %s", lang)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = local.WriteBundle(&buf, b); err != nil {
+		return
+	}
+	os.MkdirAll(path.Dir(filename), 0755)
+	err = ioutil.WriteFile(filename, buf.Bytes(), 0644)
+	return
+}
+
+// bundleFilename return the bundle file name of b, with its target if
+// it has one, as for the translation files. The bundles of other
+// targets are skipped by godoc; those without target are used on all.
+func bundleFilename(b *local.Bundle) string {
+	const base = "translations"
+	lang := b.Lang
+	if b.GOOS != "" || b.GOARCH != "" {
+		lang = fmt.Sprintf("%s_%s_%s", lang, b.GOOS, b.GOARCH)
+	}
+	return path.Join(base, local.BundleFilename(lang))
+}
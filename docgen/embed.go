@@ -0,0 +1,131 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// embedFilename is the generated file, in package main of golangdoc.
+const embedFilename = "embed_files.go"
+
+// embed generate the Go source of the translation files of langs, and
+// the static overrides in templateDir (if not empty).
+func embed(filename string, langs []string, templateDir string) error {
+	translationsDir := os.Getenv("GODOC_LOCAL_ROOT")
+	if translationsDir == "" {
+		translationsDir = "translations"
+	}
+
+	files := make(map[string]string)
+	err := readEmbedFiles(files, translationsDir, "translations", func(rel string) bool {
+		return isEmbedFile(rel, langs)
+	})
+	if err != nil {
+		return err
+	}
+	if templateDir != "" {
+		err := readEmbedFiles(files, templateDir, "lib/godoc", func(rel string) bool {
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files for %v", langs)
+	}
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"docgen embed %s\"; DO NOT EDIT.\n\n", strings.Join(langs, " "))
+	fmt.Fprintf(&buf, "package main\n\n")
+	fmt.Fprintf(&buf, "import \"github.com/golang-china/golangdoc/local\"\n\n")
+	fmt.Fprintf(&buf, "// registered in a variable initializer, so that it precedes\n")
+	fmt.Fprintf(&buf, "// the init functions calling local.Init (e.g. in appinit.go).\n")
+	fmt.Fprintf(&buf, "var _ = func() bool {\n\tlocal.RegisterEmbedFiles(embedFiles)\n\treturn true\n}()\n\n")
+	fmt.Fprintf(&buf, "var embedFiles = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&buf, "\t%q: %q,\n", name, files[name])
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	data, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// readEmbedFiles read the files in dir accepted by ok into files,
+// keyed by prefix/$(relative slash path).
+func readEmbedFiles(files map[string]string, dir, prefix string, ok func(rel string) bool) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path != dir && strings.HasPrefix(fi.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !ok(rel) {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[prefix+"/"+rel] = string(data)
+		return nil
+	})
+}
+
+// isEmbedFile reports whether the translation file rel belongs to
+// one of langs, e.g.
+//
+//	src/fmt/doc_zh_CN.go
+//	go1.5/src/syscall/doc_zh_CN_windows.go
+//	doc_zh_CN.bundle
+//	static/zh_CN/godoc.html
+//	doc/zh_CN/go_spec.html
+//	messages/zh_CN.json
+//	synonyms/zh_CN.json
+func isEmbedFile(rel string, langs []string) bool {
+	elems := strings.Split(rel, "/")
+	name := elems[len(elems)-1]
+	for _, lang := range langs {
+		if strings.HasPrefix(name, "doc_"+lang+".") || strings.HasPrefix(name, "doc_"+lang+"_") {
+			return true
+		}
+		if name == lang+".json" && len(elems) == 2 && (elems[0] == "messages" || elems[0] == "synonyms") {
+			return true
+		}
+		for _, elem := range elems[:len(elems)-1] {
+			if elem == lang {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestIsEmbedFile(t *testing.T) {
+	langs := []string{"zh_CN"}
+	for rel, want := range map[string]bool{
+		"src/fmt/doc_zh_CN.go":                   true,
+		"go1.5/src/syscall/doc_zh_CN_windows.go": true,
+		"doc_zh_CN.bundle":                       true,
+		"static/zh_CN/godoc.html":                true,
+		"doc/zh_CN/go_spec.html":                 true,
+		"src/fmt/doc_ja_JP.go":                   false,
+		"static/ja_JP/godoc.html":                false,
+		"messages/zh_CN.json":                    true,
+		"messages/ja_JP.json":                    false,
+		"synonyms/zh_CN.json":                    true,
+		"synonyms/ja_JP.json":                    false,
+		"README.md":                              false,
+	} {
+		if got := isEmbedFile(rel, langs); got != want {
+			t.Errorf("isEmbedFile(%q) = %v; want %v", rel, got, want)
+		}
+	}
+}
@@ -7,6 +7,9 @@
 //
 // Usage:
 //	docgen package lang... [-GOOS=...] [-GOARCH=...]
+//	docgen bundle lang... [-GOOS=...] [-GOARCH=...]
+//	docgen embed lang... [-templates=...]
+//	docgen align lang...
 //	docgen -h
 //
 // Example:
@@ -18,6 +21,10 @@
 //	docgen syscall zh_CN                                   # for non windows
 //	docgen std     zh_CN                                   # all standard packages
 //	docgen ./...   zh_CN                                   # all sub packages
+//	docgen bundle  zh_CN                                   # precompiled bundle
+//	docgen bundle  zh_CN -GOOS=windows -GOARCH=amd64       # precompiled bundle for windows/amd64
+//	docgen embed   zh_CN -templates=./lib/godoc            # embedded files
+//	docgen align   zh_CN                                   # check translated /doc files
 //
 // Output:
 //	translations/src/builtin/doc_zh_CN.go
@@ -28,6 +35,9 @@
 //	translations/src/syscall/doc_zh_CN.go                  # for non windows
 //	translations/src/*/doc_zh_CN.go                        # all standard packages
 //	translations/src/*/doc_zh_CN.go                        # all sub packages
+//	translations/doc_zh_CN.bundle                          # precompiled bundle for all targets
+//	translations/doc_zh_CN_windows_amd64.bundle            # precompiled bundle for windows/amd64
+//	embed_files.go                                         # embedded files
 //
 // Help:
 //	docgen -h
@@ -60,6 +70,9 @@ import (
 
 const usage = `
 Usage: docgen package lang... [-GOOS=...] [-GOARCH=...]
+  docgen bundle lang... [-GOOS=...] [-GOARCH=...]
+  docgen embed lang... [-templates=...]
+  docgen align lang...
   docgen -h
 
 Example:
@@ -71,6 +84,10 @@ Example:
   docgen syscall zh_CN                                   # for non windows
   docgen std     zh_CN                                   # all standard packages
   docgen ./...   zh_CN                                   # all sub packages
+  docgen bundle  zh_CN                                   # precompiled bundle
+  docgen bundle  zh_CN -GOOS=windows -GOARCH=amd64       # precompiled bundle for windows/amd64
+  docgen embed   zh_CN -templates=./lib/godoc            # embedded files
+  docgen align   zh_CN                                   # check translated /doc files
 
 Output:
   translations/src/builtin/doc_zh_CN.go
@@ -81,6 +98,9 @@ Output:
   translations/src/syscall/doc_zh_CN.go                  # for non windows
   translations/src/*/doc_zh_CN.go                        # all standard packages
   translations/src/*/doc_zh_CN.go                        # all sub packages
+  translations/doc_zh_CN.bundle                          # precompiled bundle for all targets
+  translations/doc_zh_CN_windows_amd64.bundle            # precompiled bundle for windows/amd64
+  embed_files.go                                         # embedded files
 
 Help:
   docgen -h
@@ -91,12 +111,49 @@ Report bugs to <chaishushan{AT}gmail.com>.
 var (
 	flagGOOS       = ""
 	flagGOARCH     = ""
+	flagTemplates  = ""
+	cmdArgBundle   = false
+	cmdArgEmbed    = false
+	cmdArgAlign    = false
 	cmdArgPackages = []string(nil)
 	cmdArgLangs    = []string(nil)
 )
 
 func main() {
 	parseCmdArgs()
+	if cmdArgBundle {
+		for _, lang := range cmdArgLangs {
+			if filename, err := bundle(lang); err != nil {
+				log.Fatalf("gen %s failed, err = %v", filename, err)
+			} else {
+				fmt.Printf("gen %s ok\n", filename)
+			}
+		}
+		fmt.Println("Done")
+		return
+	}
+	if cmdArgEmbed {
+		if err := embed(embedFilename, cmdArgLangs, flagTemplates); err != nil {
+			log.Fatalf("gen %s failed, err = %v", embedFilename, err)
+		}
+		fmt.Printf("gen %s ok\n", embedFilename)
+		fmt.Println("Done")
+		return
+	}
+	if cmdArgAlign {
+		n := 0
+		for _, lang := range cmdArgLangs {
+			k, err := align(os.Stdout, lang)
+			if err != nil {
+				log.Fatalf("align %s failed, err = %v", lang, err)
+			}
+			n += k
+		}
+		if n > 0 {
+			os.Exit(1)
+		}
+		return
+	}
 	for i := 0; i < len(cmdArgPackages); i++ {
 		for _, lang := range cmdArgLangs {
 			if importPath, err := docgen(cmdArgPackages[i], lang); err != nil {
@@ -128,6 +185,10 @@ func parseCmdArgs() {
 			flagGOARCH = os.Args[i][len("-GOARCH="):]
 			continue
 		}
+		if strings.HasPrefix(os.Args[i], "-templates=") {
+			flagTemplates = os.Args[i][len("-templates="):]
+			continue
+		}
 		args = append(args, os.Args[i])
 	}
 	if len(args) < 2 {
@@ -135,7 +196,16 @@ func parseCmdArgs() {
 		os.Exit(1)
 	}
 
-	cmdArgPackages = listPackages(args[0])
+	switch args[0] {
+	case "bundle":
+		cmdArgBundle = true
+	case "embed":
+		cmdArgEmbed = true
+	case "align":
+		cmdArgAlign = true
+	default:
+		cmdArgPackages = listPackages(args[0])
+	}
 	cmdArgLangs = args[1:]
 }
 
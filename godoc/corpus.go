@@ -8,6 +8,7 @@ import (
 	"errors"
 	"go/doc"
 	pathpkg "path"
+	"sync"
 	"time"
 
 	"golang.org/x/tools/godoc/analysis"
@@ -44,9 +45,25 @@ type Corpus struct {
 	// IndexInterval specifies the time to sleep between reindexing
 	// all the sources.
 	// If zero, a default is used. If negative, the index is only
-	// built once.
+	// built once, and then only updated on the changes reported by
+	// RunWatcher.
 	IndexInterval time.Duration
 
+	// WatchInterval specifies the time between two scans of the file
+	// system by RunWatcher, if the changes cannot be watched otherwise.
+	// If zero, a default is used.
+	WatchInterval time.Duration
+
+	// TranslationRoots optionally specifies the directories of the
+	// operating system holding the translations. RunWatcher watches
+	// them, and the translated docs are re-indexed when they change.
+	TranslationRoots []string
+
+	// ReloadTranslations optionally specifies a function called by
+	// RunWatcher to drop the loaded translations when files changed
+	// under TranslationRoots.
+	ReloadTranslations func()
+
 	// IndexDocs enables indexing of Go documentation.
 	// This will produce search results for exported types, functions,
 	// methods, variables, and constants, and will link to the godoc
@@ -65,6 +82,10 @@ type Corpus struct {
 	// Regexp searching is supported via full-text indexing.
 	IndexFullText bool
 
+	// IndexLangs specifies the languages of the translated package
+	// docs to index for full-text search, see TranslateDocPackage.
+	IndexLangs []string
+
 	// MaxResults optionally specifies the maximum results for indexing.
 	MaxResults int
 
@@ -81,7 +102,9 @@ type Corpus struct {
 	SummarizePackage func(pkg string, lang ...string) (summary string, showList, ok bool)
 
 	// TranslateDocPackage optionally specifies a function to
-	// translate the package document.
+	// translate the package document. The optional lang arguments
+	// are the requested language and the minimum review status of
+	// the translation.
 	TranslateDocPackage func(pkg *doc.Package, lang ...string) *doc.Package
 
 	// IndexDirectory optionally specifies a function to determine
@@ -91,6 +114,23 @@ type Corpus struct {
 	// If nil, all directories are indexed if indexing is enabled.
 	IndexDirectory func(dir string) bool
 
+	// Synonyms optionally specifies a function returning the Go
+	// identifiers (e.g. "sync.Mutex") of a search query in the
+	// request language lang.
+	Synonyms func(lang, query string) []string
+
+	// TranslateDoc optionally specifies a function returning the
+	// translated doc of the identifier id ("" for the package, "T.M"
+	// for methods) of the package importPath in lang.
+	TranslateDoc func(lang, importPath, id string) (doc string, ok bool)
+
+	// DocumentFS optionally specifies a function returning the /doc
+	// tree of the request language lang (the "lang" form value), or nil
+	// to use the default /doc tree. The non-nil results are cached per
+	// language, so it must return nil for the languages without a /doc
+	// tree.
+	DocumentFS func(lang string) vfs.FileSystem
+
 	testDir string // TODO(bradfitz,adg): migrate old godoc flag? looks unused.
 
 	// Send a value on this channel to trigger a metadata refresh.
@@ -98,11 +138,26 @@ type Corpus struct {
 	// during a refresh.
 	refreshMetadataSignal chan bool
 
+	// Send a value on this channel to wake up the indexer after
+	// a change of the file system, see RunWatcher.
+	indexSignal chan bool
+
 	// file system information
 	fsTree      util.RWValue // *Directory tree of packages, updated with each sync (but sync code is removed now)
 	fsModified  util.RWValue // timestamp of last call to invalidateIndex
 	docMetadata util.RWValue // mapping from paths to *Metadata
 
+	// per-language /doc trees, see DocumentFS
+	langMu       sync.Mutex
+	langFS       map[string]vfs.FileSystem       // nil for the default /doc tree
+	langMetadata map[string]map[string]*Metadata // docMetadata of each language
+
+	// content signature of the files under TranslationRoots, computed
+	// once, see translationsSignature
+	trSigMu sync.Mutex
+	trSig   uint64
+	trSigOK bool
+
 	// SearchIndex is the search index in use.
 	searchIndex util.RWValue
 
@@ -115,8 +170,9 @@ type Corpus struct {
 // Change or set any options on Corpus before calling the Corpus.Init method.
 func NewCorpus(fs vfs.FileSystem) *Corpus {
 	c := &Corpus{
-		fs: fs,
+		fs:                    fs,
 		refreshMetadataSignal: make(chan bool, 1),
+		indexSignal:           make(chan bool, 1),
 
 		MaxResults:    1000,
 		IndexEnabled:  true,
@@ -0,0 +1,289 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the full-text index of the translated package
+// docs. Text is split into lower case words, and unsegmented CJK text
+// into the bigrams (and single characters) of its runs.
+
+import (
+	"go/ast"
+	"go/doc"
+	"strings"
+	"unicode"
+)
+
+// A DocIndex is the full-text index of the translated docs of a language.
+type DocIndex struct {
+	Idents []DocIdent       // indexed identifiers
+	Terms  map[string][]int // term => ascending indices into Idents
+}
+
+// A DocIdent is an identifier of a DocIndex, with the synopsis of its
+// translated doc.
+type DocIdent struct {
+	Kind SpotKind
+	Ident
+}
+
+// isCJK reports whether r is written without spaces between words.
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r)
+}
+
+// docTerms calls f for each term of text: the lower case words, and
+// the bigrams of the CJK runs. If all is set, the single characters
+// of the CJK runs are terms too; otherwise they are only used for
+// runs of one character.
+func docTerms(text string, all bool, f func(term string)) {
+	var word, cjk []rune
+	flush := func() {
+		if len(word) > 0 {
+			f(strings.ToLower(string(word)))
+			word = word[:0]
+		}
+		for i := range cjk {
+			if all || len(cjk) == 1 {
+				f(string(cjk[i]))
+			}
+			if i+1 < len(cjk) {
+				f(string(cjk[i : i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			if len(word) > 0 {
+				flush()
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+}
+
+// add adds the identifier with the translated doc text to x.
+func (x *DocIndex) add(kind SpotKind, id Ident, text string) {
+	i := len(x.Idents)
+	x.Idents = append(x.Idents, DocIdent{kind, id})
+	docTerms(id.Name+" "+text, true, func(term string) {
+		list := x.Terms[term]
+		if n := len(list); n == 0 || list[n-1] != i {
+			x.Terms[term] = append(list, i)
+		}
+	})
+}
+
+// lookup returns the identifiers whose docs contain all terms of query,
+// by kind.
+func (x *DocIndex) lookup(query string, importCount map[string]int) map[SpotKind][]Ident {
+	var hits []int
+	first := true
+	docTerms(query, false, func(term string) {
+		if first {
+			hits = append(hits, x.Terms[term]...)
+			first = false
+		} else {
+			hits = intersect(hits, x.Terms[term])
+		}
+	})
+	if len(hits) == 0 {
+		return nil
+	}
+
+	idents := make(map[SpotKind][]Ident)
+	for _, i := range hits {
+		id := x.Idents[i]
+		idents[id.Kind] = append(idents[id.Kind], id.Ident)
+	}
+	for kind, list := range idents {
+		const rsltLimit = 50
+		idents[kind] = byImportCount{list, importCount}.top(rsltLimit)
+	}
+	return idents
+}
+
+// intersect returns the common elements of the ascending lists a and b.
+func intersect(a, b []int) []int {
+	var list []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			list = append(list, a[i])
+			i++
+			j++
+		}
+	}
+	return list
+}
+
+// LookupDoc returns the identifiers whose translated docs of lang
+// contain all words of query, by kind.
+func (x *Index) LookupDoc(lang, query string) map[SpotKind][]Ident {
+	if d := x.docs[lang]; d != nil {
+		return d.lookup(query, x.importCount)
+	}
+	return nil
+}
+
+// addTranslatedFile records the file of the package pkgPath, whose
+// translated docs are indexed by indexTranslatedDir once all files of
+// its directory are visited. The files of the packages without
+// translation in Corpus.IndexLangs are not kept.
+func (x *Indexer) addTranslatedFile(pkgPath, pkgName, filename string, astFile *ast.File) {
+	p, seen := x.trPkgs[pkgPath]
+	if !seen {
+		if x.isTranslated(pkgPath) {
+			p = &ast.Package{Name: pkgName, Files: make(map[string]*ast.File)}
+		}
+		x.trPkgs[pkgPath] = p // nil if not translated
+	}
+	if p != nil && p.Name == pkgName {
+		p.Files[filename] = astFile
+	}
+}
+
+// isTranslated reports whether the package pkgPath has a translation
+// in one of the languages of Corpus.IndexLangs, true if unknown.
+func (x *Indexer) isTranslated(pkgPath string) bool {
+	if x.c.TranslateDoc == nil {
+		return true
+	}
+	for _, lang := range x.c.IndexLangs {
+		if _, ok := x.c.TranslateDoc(lang, pkgPath, ""); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// indexTranslatedDir adds the translated docs of the package of the
+// directory dirname, recorded by addTranslatedFile, in the languages
+// of Corpus.IndexLangs, to the doc indices, and releases its files.
+// Each package is translated once per language.
+func (x *Indexer) indexTranslatedDir(dirname string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	pkgPath := dirImportPath(dirname)
+	p := x.trPkgs[pkgPath]
+	delete(x.trPkgs, pkgPath)
+	if p != nil {
+		x.indexTranslatedPackage(pkgPath, p.Name, doc.New(p, pkgPath, 0))
+	}
+}
+
+// indexTranslatedPackage adds the translated docs of pkg to the doc
+// indices.
+func (x *Indexer) indexTranslatedPackage(pkgPath, pkgName string, pkg *doc.Package) {
+	for _, lang := range x.c.IndexLangs {
+		if x.c.TranslateDoc != nil {
+			if _, ok := x.c.TranslateDoc(lang, pkgPath, ""); !ok {
+				continue // not translated
+			}
+		}
+		trPkg := x.c.TranslateDocPackage(copyDocPackage(pkg), lang)
+		if trPkg == nil {
+			continue // not translated
+		}
+		d := x.docs[lang]
+		if d == nil {
+			d = &DocIndex{Terms: make(map[string][]int)}
+			x.docs[lang] = d
+		}
+		add := func(kind SpotKind, name, text string) {
+			if text == "" {
+				return
+			}
+			d.add(kind, Ident{
+				Path:    pkgPath,
+				Package: pkgName,
+				Name:    x.intern(name),
+				Doc:     doc.Synopsis(text),
+			}, text)
+		}
+
+		add(PackageClause, pkgName, trPkg.Doc)
+		addValues := func(kind SpotKind, values []*doc.Value) {
+			for _, v := range values {
+				for _, name := range v.Names {
+					add(kind, name, v.Doc)
+				}
+			}
+		}
+		addValues(ConstDecl, trPkg.Consts)
+		addValues(VarDecl, trPkg.Vars)
+		for _, f := range trPkg.Funcs {
+			add(FuncDecl, f.Name, f.Doc)
+		}
+		for _, t := range trPkg.Types {
+			add(TypeDecl, t.Name, t.Doc)
+			addValues(ConstDecl, t.Consts)
+			addValues(VarDecl, t.Vars)
+			for _, f := range t.Funcs {
+				add(FuncDecl, f.Name, f.Doc)
+			}
+			for _, f := range t.Methods {
+				add(MethodDecl, t.Name+"."+f.Name, f.Doc)
+			}
+		}
+	}
+}
+
+// copyDocPackage returns a copy of pkg whose docs may be translated
+// without changing pkg.
+func copyDocPackage(pkg *doc.Package) *doc.Package {
+	copyValues := func(list []*doc.Value) []*doc.Value {
+		values := make([]*doc.Value, len(list))
+		for i, v := range list {
+			c := *v
+			values[i] = &c
+		}
+		return values
+	}
+	copyFuncs := func(list []*doc.Func) []*doc.Func {
+		funcs := make([]*doc.Func, len(list))
+		for i, f := range list {
+			c := *f
+			funcs[i] = &c
+		}
+		return funcs
+	}
+
+	p := *pkg
+	p.Notes = make(map[string][]*doc.Note, len(pkg.Notes))
+	for k, v := range pkg.Notes {
+		p.Notes[k] = v
+	}
+	p.Consts = copyValues(pkg.Consts)
+	p.Vars = copyValues(pkg.Vars)
+	p.Funcs = copyFuncs(pkg.Funcs)
+	p.Types = make([]*doc.Type, len(pkg.Types))
+	for i, t := range pkg.Types {
+		c := *t
+		c.Consts = copyValues(t.Consts)
+		c.Vars = copyValues(t.Vars)
+		c.Funcs = copyFuncs(t.Funcs)
+		c.Methods = copyFuncs(t.Methods)
+		p.Types[i] = &c
+	}
+	return &p
+}
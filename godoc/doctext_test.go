@@ -0,0 +1,158 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"bytes"
+	"go/doc"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestDocTerms(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		all   bool
+		terms []string
+	}{
+		{"Hello, World_2!", false, []string{"hello", "world_2"}},
+		{"格式化输出", false, []string{"格式", "式化", "化输", "输出"}},
+		{"包 fmt 实现", false, []string{"包", "fmt", "实现"}},
+		{"读取Reader", true, []string{"读", "读取", "取", "reader"}},
+	} {
+		var terms []string
+		docTerms(tt.text, tt.all, func(term string) { terms = append(terms, term) })
+		if !reflect.DeepEqual(terms, tt.terms) {
+			t.Errorf("docTerms(%q, %v) = %q, want %q", tt.text, tt.all, terms, tt.terms)
+		}
+	}
+}
+
+func TestLookupDoc(t *testing.T) {
+	c := newCorpus(t)
+	c.IndexLangs = []string{"zh_CN"}
+	c.TranslateDocPackage = func(pkg *doc.Package, lang ...string) *doc.Package {
+		if pkg.ImportPath != "foo" || lang[0] != "zh_CN" {
+			return nil
+		}
+		pkg.Doc = "包 foo 是一个例子.\n"
+		for _, t := range pkg.Types {
+			if t.Name == "Foo" {
+				t.Doc = "Foo 是一个格式化输出的类型.\n"
+			}
+		}
+		return pkg
+	}
+	c.UpdateIndex()
+	ix, _ := c.CurrentIndex()
+
+	for _, tt := range []struct {
+		lang, query string
+		want        map[SpotKind][]string
+	}{
+		{"zh_CN", "例子", map[SpotKind][]string{PackageClause: {"foo"}}},
+		{"zh_CN", "格式化 类型", map[SpotKind][]string{TypeDecl: {"Foo"}}},
+		{"zh_CN", "一个", map[SpotKind][]string{PackageClause: {"foo"}, TypeDecl: {"Foo"}}},
+		{"zh_CN", "格式化 例子", nil},
+		{"ja", "例子", nil},
+	} {
+		var got map[SpotKind][]string
+		for kind, idents := range ix.LookupDoc(tt.lang, tt.query) {
+			if got == nil {
+				got = make(map[SpotKind][]string)
+			}
+			for _, id := range idents {
+				got[kind] = append(got[kind], id.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LookupDoc(%q, %q) = %v, want %v", tt.lang, tt.query, got, tt.want)
+		}
+	}
+
+	// the original docs are unchanged
+	result := c.Lookup("Foo", "zh_CN")
+	if ids := result.Idents[TypeDecl]; len(ids) != 1 || ids[0].Doc != "Foo is stuff." {
+		t.Errorf("Lookup(Foo) = %+v", ids)
+	}
+	result = c.Lookup("格式化输出", "zh_CN")
+	if ids := result.Idents[TypeDecl]; len(ids) != 1 || ids[0].Doc != "Foo 是一个格式化输出的类型." {
+		t.Errorf("Lookup(格式化输出) = %+v", ids)
+	}
+
+	// the doc index is written with the index
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	ix2 := new(Index)
+	if _, err := ix2.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if ids := ix2.LookupDoc("zh_CN", "例子")[PackageClause]; len(ids) != 1 {
+		t.Errorf("LookupDoc after ReadFrom = %v", ids)
+	}
+}
+
+func TestLookupSynonyms(t *testing.T) {
+	c := newCorpus(t)
+	c.Synonyms = func(lang, query string) []string {
+		if lang == "zh_CN" && query == "构造" {
+			return []string{"foo.New", "Foo"}
+		}
+		return nil
+	}
+	c.UpdateIndex()
+
+	result := c.Lookup("构造", "zh_CN")
+	if result.Alt == nil || !reflect.DeepEqual(result.Alt.Alts, []string{"foo.New", "Foo"}) {
+		t.Errorf("Alt = %+v, want foo.New and Foo", result.Alt)
+	}
+	if ids := result.Idents[FuncDecl]; len(ids) != 1 || ids[0].Name != "New" {
+		t.Errorf("Idents[FuncDecl] = %+v, want New", ids)
+	}
+	if ids := result.Idents[TypeDecl]; len(ids) != 1 || ids[0].Name != "Foo" {
+		t.Errorf("Idents[TypeDecl] = %+v, want Foo", ids)
+	}
+
+	if result := c.Lookup("构造"); result.Alt != nil {
+		t.Errorf("Alt without lang = %+v, want nil", result.Alt)
+	}
+}
+
+func TestIndexTranslatedDocs(t *testing.T) {
+	c := NewCorpus(mapfs.New(map[string]string{
+		"src/foo/foo.go": "// Package foo is an example.\npackage foo\n\n// Foo is stuff.\ntype Foo struct{}\n",
+		"src/foo/bar.go": "package foo\n\n// Bar is a method.\nfunc (f *Foo) Bar() {}\n",
+		"src/bar/bar.go": "// Package bar is not translated.\npackage bar\n",
+	}))
+	c.IndexEnabled = true
+	c.IndexDocs = true
+	c.IndexLangs = []string{"zh_CN"}
+	c.TranslateDoc = func(lang, importPath, id string) (string, bool) {
+		return "", importPath == "foo"
+	}
+	calls := make(map[string]int)
+	c.TranslateDocPackage = func(pkg *doc.Package, lang ...string) *doc.Package {
+		calls[pkg.ImportPath]++
+		for _, t := range pkg.Types {
+			for _, m := range t.Methods {
+				m.Doc = "Bar 是一个方法.\n"
+			}
+		}
+		return pkg
+	}
+	initTestCorpus(t, c)
+	x := c.NewIndex()
+
+	if want := map[string]int{"foo": 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("TranslateDocPackage calls = %v; want %v", calls, want)
+	}
+	if ids := x.LookupDoc("zh_CN", "方法")[MethodDecl]; len(ids) != 1 || ids[0].Name != "Foo.Bar" {
+		t.Errorf("LookupDoc(方法) = %+v; want Foo.Bar", ids)
+	}
+}
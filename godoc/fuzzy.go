@@ -0,0 +1,247 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the fuzzy identifier search, used when a query
+// has no exact match: case-folded, camelCase initials (RWM finds
+// RWMutex), prefix, subsequence and typo (edit distance) matches,
+// ranked by match quality and popularity.
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFuzzy is the maximum number of fuzzy matches of a query.
+const maxFuzzy = 20
+
+// A FuzzyMatch is a package-level declaration matching a query
+// approximately.
+type FuzzyMatch struct {
+	Path    string   // import path, e.g. "net/http"
+	Package string   // package name, e.g. "http"
+	Name    string   // e.g. "ListenAndServe"
+	Kind    SpotKind // kind of the declaration
+	Score   float64  // rank, between 0 and 1
+}
+
+// camelInitials returns the initials of the camelCase identifier w:
+// its first letter, upper case letters and digits, and the letters
+// following an underscore. For instance, "RWM" for "RWMutex" and
+// "LAS" for "ListenAndServe".
+func camelInitials(w string) string {
+	var buf []rune
+	prev := '_'
+	for _, r := range w {
+		if r != '_' && (prev == '_' || unicode.IsUpper(r) || unicode.IsDigit(r)) {
+			buf = append(buf, unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return string(buf)
+}
+
+// isSubsequence reports whether the runes of q appear in s, in order.
+func isSubsequence(q, s string) bool {
+	for _, r := range s {
+		if q == "" {
+			break
+		}
+		if c, size := utf8.DecodeRuneInString(q); c == r {
+			q = q[size:]
+		}
+	}
+	return q == ""
+}
+
+// editDistance returns the Levenshtein distance of a and b, or max+1
+// if it is larger than max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		low := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if cur[j] < low {
+				low = cur[j]
+			}
+		}
+		if low > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// maxTypos returns the edit distance allowed for a query of n runes.
+func maxTypos(n int) int {
+	switch {
+	case n <= 2:
+		return 0
+	case n <= 4:
+		return 1
+	case n <= 8:
+		return 2
+	}
+	return 3
+}
+
+// fuzzyQuality returns how well query matches the identifier w,
+// between 0 (no match) and 1 (exact match).
+func fuzzyQuality(query, w string) float64 {
+	if query == w {
+		return 1
+	}
+	lq, lw := canonical(query), canonical(w)
+	if lq == lw {
+		return 0.95
+	}
+	nq, nw := utf8.RuneCountInString(query), utf8.RuneCountInString(w)
+	if nq > 1 && query == strings.ToUpper(query) {
+		// camelCase initials
+		if initials := camelInitials(w); initials == query {
+			return 0.9
+		} else if strings.HasPrefix(initials, query) {
+			return 0.7
+		}
+	}
+	if nq > 1 && strings.HasPrefix(lw, lq) {
+		return 0.6 + 0.3*float64(nq)/float64(nw)
+	}
+	if d := editDistance(lq, lw, maxTypos(nq)); d <= maxTypos(nq) {
+		return 0.85 - 0.15*float64(d)
+	}
+	if nq > 2 && isSubsequence(lq, lw) {
+		return 0.2 + 0.4*float64(nq)/float64(nw)
+	}
+	return 0
+}
+
+// isStdPath reports whether the import path is of the standard
+// library, whose first element contains no dot.
+func isStdPath(path string) bool {
+	i := strings.IndexByte(path, '/')
+	if i < 0 {
+		i = len(path)
+	}
+	return !strings.Contains(path[:i], ".")
+}
+
+// LookupFuzzy returns at most n package-level declarations matching
+// the simple or qualified identifier query approximately, best first.
+// The rank combines the match quality, the import count of the
+// package, whether the identifier is exported and whether the package
+// is in the standard library.
+func (x *Index) LookupFuzzy(query string, n int) []FuzzyMatch {
+	ident, pakname := query, ""
+	if i := strings.LastIndex(query, "."); i >= 0 {
+		pakname, ident = query[:i], query[i+1:]
+	}
+	if ident == "" {
+		return nil
+	}
+
+	maxCount := 1
+	for _, count := range x.importCount {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	var list []FuzzyMatch
+	seen := make(map[string]bool)
+	for w, match := range x.words {
+		quality := fuzzyQuality(ident, w)
+		if quality == 0 || quality == 1 || match == nil {
+			continue
+		}
+		exported := 0.0
+		if r, _ := utf8.DecodeRuneInString(w); unicode.IsUpper(r) {
+			exported = 1
+		}
+		for _, run := range match.Decls {
+			if pakname != "" && run.Pak.Name != pakname {
+				continue
+			}
+			path := dirImportPath(run.Pak.Path)
+			if seen[path+"."+w] || len(run.Files) == 0 || len(run.Files[0].Groups) == 0 {
+				continue
+			}
+			seen[path+"."+w] = true
+			std := 0.0
+			if isStdPath(path) {
+				std = 1
+			}
+			popularity := math.Log(1+float64(x.importCount[path])) / math.Log(1+float64(maxCount))
+			list = append(list, FuzzyMatch{
+				Path:    path,
+				Package: run.Pak.Name,
+				Name:    w,
+				Kind:    run.Files[0].Groups[0][0].Kind(),
+				Score:   0.6*quality + 0.2*popularity + 0.15*exported + 0.05*std,
+			})
+		}
+	}
+	sort.Sort(byScore(list))
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+type byScore []FuzzyMatch
+
+func (s byScore) Len() int      { return len(s) }
+func (s byScore) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byScore) Less(i, j int) bool {
+	if s[i].Score != s[j].Score {
+		return s[i].Score > s[j].Score
+	}
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].Path < s[j].Path
+}
+
+// addFuzzy adds the fuzzy matches of query to result: their names as
+// alternative words, and the exported identifiers among them.
+func (result *SearchResult) addFuzzy(index *Index, query string, matches []FuzzyMatch) {
+	result.Fuzzy = matches
+	var words []string
+	idents := make(map[SpotKind][]Ident)
+	for _, m := range matches {
+		words = append(words, m.Name)
+		for _, id := range index.idents[m.Kind][m.Name] {
+			if id.Path == m.Path {
+				idents[m.Kind] = append(idents[m.Kind], id)
+			}
+		}
+	}
+	result.addIdents(idents)
+	result.addAlts(query, words)
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import "testing"
+
+func TestCamelInitials(t *testing.T) {
+	for _, tt := range []struct{ word, want string }{
+		{"RWMutex", "RWM"},
+		{"ListenAndServe", "LAS"},
+		{"utf8", "U8"},
+		{"new_file_name", "NFN"},
+		{"x", "X"},
+	} {
+		if got := camelInitials(tt.word); got != tt.want {
+			t.Errorf("camelInitials(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"listen", "listen", 2, 0},
+		{"listne", "listen", 2, 2},
+		{"lisen", "listen", 2, 1},
+		{"kitten", "sitting", 3, 3},
+		{"kitten", "sitting", 2, 3},
+		{"a", "abcd", 1, 2},
+	} {
+		if got := editDistance(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyQuality(t *testing.T) {
+	// each query matches the first word better than the second
+	for _, tt := range []struct{ query, better, worse string }{
+		{"RWM", "RWMutex", "ReadWriter"},
+		{"LAS", "ListenAndServe", "ListenAndServeTLS"},
+		{"ListenAndSevre", "ListenAndServe", "ListenAndServeTLS"},
+		{"mutex", "Mutex", "RWMutex"},
+		{"Printl", "Println", "Print"},
+	} {
+		b, w := fuzzyQuality(tt.query, tt.better), fuzzyQuality(tt.query, tt.worse)
+		if b == 0 || b <= w {
+			t.Errorf("fuzzyQuality(%q): %s %.2f, %s %.2f", tt.query, tt.better, b, tt.worse, w)
+		}
+	}
+	for _, tt := range []struct{ query, word string }{
+		{"Foo", "Bar"},
+		{"ab", "ba"},
+		{"Reader", "Writer"},
+	} {
+		if q := fuzzyQuality(tt.query, tt.word); q != 0 {
+			t.Errorf("fuzzyQuality(%q, %q) = %.2f, want 0", tt.query, tt.word, q)
+		}
+	}
+}
+
+func TestLookupFuzzy(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+	ix, _ := c.CurrentIndex()
+
+	matches := ix.LookupFuzzy("Fooo", maxFuzzy)
+	if len(matches) == 0 || matches[0].Name != "Foo" || matches[0].Path != "foo" || matches[0].Kind != TypeDecl {
+		t.Fatalf("LookupFuzzy(Fooo) = %+v", matches)
+	}
+	if matches := ix.LookupFuzzy("bar.Fooo", maxFuzzy); len(matches) != 0 {
+		t.Errorf("LookupFuzzy(bar.Fooo) = %+v", matches)
+	}
+
+	result := c.Lookup("Fooo")
+	if len(result.Fuzzy) == 0 || result.Fuzzy[0].Name != "Foo" {
+		t.Errorf("Lookup(Fooo): fuzzy %+v", result.Fuzzy)
+	}
+	if ids := result.Idents[TypeDecl]; len(ids) != 1 || ids[0].Path != "foo" || ids[0].Name != "Foo" {
+		t.Errorf("Lookup(Fooo): types %+v", ids)
+	}
+	if result.Alt == nil || len(result.Alt.Alts) == 0 || result.Alt.Alts[0] != "Foo" {
+		t.Errorf("Lookup(Fooo): alts %+v", result.Alt)
+	}
+
+	// no fuzzy matches for exact matches
+	if result := c.Lookup("Foo"); len(result.Fuzzy) != 0 {
+		t.Errorf("Lookup(Foo): fuzzy %+v", result.Fuzzy)
+	}
+}
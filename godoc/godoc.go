@@ -45,6 +45,22 @@ func (p *Presentation) FuncMap() template.FuncMap {
 	return p.funcMap
 }
 
+// FuncMapFor returns the template functions for the templates of lang,
+// which translate the template messages and dates into lang.
+func (p *Presentation) FuncMapFor(lang string) template.FuncMap {
+	funcs := make(template.FuncMap)
+	for name, f := range p.FuncMap() {
+		funcs[name] = f
+	}
+	funcs["T"] = func(msg string, args ...interface{}) string {
+		return p.Translate(lang, msg, args...)
+	}
+	funcs["fileInfoTime"] = func(fi os.FileInfo) string {
+		return p.fileInfoTime(lang, fi)
+	}
+	return funcs
+}
+
 func (p *Presentation) TemplateFuncs() template.FuncMap {
 	p.initFuncMapOnce.Do(p.initFuncMap)
 	return p.templateFuncs
@@ -62,9 +78,12 @@ func (p *Presentation) initFuncMap() {
 		"filename": filenameFunc,
 		"repeat":   strings.Repeat,
 
+		// message catalogs
+		"T": p.translateFunc,
+
 		// access to FileInfos (directory listings)
 		"fileInfoName": fileInfoNameFunc,
-		"fileInfoTime": fileInfoTimeFunc,
+		"fileInfoTime": p.fileInfoTimeFunc,
 
 		// access to search result information
 		"infoKind_html":    infoKind_htmlFunc,
@@ -128,11 +147,50 @@ func fileInfoNameFunc(fi os.FileInfo) string {
 	return name
 }
 
-func fileInfoTimeFunc(fi os.FileInfo) string {
-	if t := fi.ModTime(); t.Unix() != 0 {
-		return t.Local().String()
+// timeLayout is the message of the date format in the template
+// catalogs, e.g. "2006年01月02日 15:04:05" for zh_CN.
+const timeLayout = "2006-01-02 15:04:05 -0700 MST"
+
+func (p *Presentation) fileInfoTimeFunc(fi os.FileInfo) string {
+	return p.fileInfoTime(p.Lang, fi)
+}
+
+func (p *Presentation) fileInfoTime(lang string, fi os.FileInfo) string {
+	t := fi.ModTime()
+	if t.Unix() == 0 {
+		return "" // don't return epoch if time is obviously not set
+	}
+	if layout := p.message(lang, timeLayout); layout != timeLayout {
+		return t.Local().Format(layout)
+	}
+	return t.Local().String()
+}
+
+// translateFunc returns the translation of the template message msg.
+// If args are present, the translation is used as format string:
+//
+//	{{T "Package"}}
+//	{{T "%d packages" $n}}
+//
+func (p *Presentation) translateFunc(msg string, args ...interface{}) string {
+	return p.Translate(p.Lang, msg, args...)
+}
+
+// Translate returns the translation of the message msg into lang,
+// as the "T" template function of FuncMapFor(lang).
+func (p *Presentation) Translate(lang, msg string, args ...interface{}) string {
+	msg = p.message(lang, msg)
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+func (p *Presentation) message(lang, msg string) string {
+	if lang == "" || p.TranslateMessage == nil {
+		return msg
 	}
-	return "" // don't return epoch if time is obviously not set
+	return p.TranslateMessage(lang, msg)
 }
 
 // The strings in infoKinds must be properly html-escaped.
@@ -279,12 +337,15 @@ func sanitizeFunc(src string) string {
 }
 
 type PageInfo struct {
-	Dirname string // directory containing the package
-	Err     error  // error or nil
+	Dirname   string // directory containing the package
+	Lang      string // requested language of the documentation, if any
+	MinStatus string // requested minimum review status of the translation, if any
+	Err       error  // error or nil
 
 	// package info
 	FSet       *token.FileSet         // nil if no package documentation
 	PDoc       *doc.Package           // nil if no package documentation
+	OrigPDoc   *doc.Package           // untranslated PDoc, in JSON mode only
 	Examples   []*doc.Example         // nil if no example code
 	Notes      map[string][]*doc.Note // nil if no package Notes
 	PAst       map[string]*ast.File   // nil if no AST with package exports
@@ -116,3 +116,37 @@ func TestSanitizeFunc(t *testing.T) {
 		}
 	}
 }
+
+func TestTranslateFunc(t *testing.T) {
+	messages := map[string]string{
+		"Package":     "包",
+		"%d packages": "%d 个包",
+	}
+	p := &Presentation{
+		Lang: "zh_CN",
+		TranslateMessage: func(lang, msg string) string {
+			if s, ok := messages[msg]; ok && lang == "zh_CN" {
+				return s
+			}
+			return msg
+		},
+	}
+	for _, tc := range []struct {
+		msg  string
+		args []interface{}
+		want string
+	}{
+		{"Package", nil, "包"},
+		{"%d packages", []interface{}{3}, "3 个包"},
+		{"Overview", nil, "Overview"},
+	} {
+		if got := p.translateFunc(tc.msg, tc.args...); got != tc.want {
+			t.Errorf("translateFunc(%q) = %q; want %q", tc.msg, got, tc.want)
+		}
+	}
+
+	p.Lang = ""
+	if got := p.translateFunc("Package"); got != "Package" {
+		t.Errorf("translateFunc without Lang = %q; want %q", got, "Package")
+	}
+}
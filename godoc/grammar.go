@@ -0,0 +1,517 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the grammar index of the spec and the
+// railroad diagrams of its productions, built from the EBNF
+// sections parsed by ebnfParser.
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// EBNF expressions
+type (
+	ebnfExpr        interface{}
+	ebnfName        string // production name
+	ebnfToken       string // quoted terminal, as in the HTML source
+	ebnfSequence    []ebnfExpr
+	ebnfAlternative []ebnfExpr
+	ebnfRange       struct{ Begin, End string }
+	ebnfGroup       struct{ Body ebnfExpr }
+	ebnfOption      struct{ Body ebnfExpr }
+	ebnfRepetition  struct{ Body ebnfExpr }
+)
+
+// A Production is an EBNF production of the spec.
+type Production struct {
+	Name   string
+	Src    string   // definition, as in the HTML source
+	Uses   []string // productions used by the definition
+	UsedBy []string // productions using this one
+
+	expr ebnfExpr // nil for an empty definition
+}
+
+// ParseGrammar returns the productions of the EBNF sections
+// in the HTML source src, in source order.
+func ParseGrammar(src []byte) []*Production {
+	prods, _ := parseGrammar(src)
+	return prods
+}
+
+// parseGrammar returns the productions and the syntax errors of
+// the EBNF sections in the HTML source src.
+func parseGrammar(src []byte) (prods []*Production, errs []string) {
+	for len(src) > 0 {
+		i := bytes.Index(src, openTag)
+		if i < 0 {
+			break
+		}
+		src = src[i+len(openTag):]
+		j := bytes.Index(src, closeTag)
+		if j < 0 {
+			j = len(src)
+		}
+		p := ebnfParser{rawStrings: true}
+		p.parse(ioutil.Discard, src[:j])
+		prods = append(prods, p.prods...)
+		errs = append(errs, p.errs...)
+		src = src[j:]
+	}
+
+	index := make(map[string]*Production)
+	for _, prod := range prods {
+		index[prod.Name] = prod
+	}
+	for _, prod := range prods {
+		seen := make(map[string]bool)
+		walkEBNF(prod.expr, func(name string) {
+			if seen[name] {
+				return
+			}
+			seen[name] = true
+			prod.Uses = append(prod.Uses, name)
+			if x := index[name]; x != nil {
+				x.UsedBy = append(x.UsedBy, prod.Name)
+			}
+		})
+	}
+	return
+}
+
+// walkEBNF calls f for each production name used in x.
+func walkEBNF(x ebnfExpr, f func(name string)) {
+	switch x := x.(type) {
+	case ebnfName:
+		f(string(x))
+	case ebnfSequence:
+		for _, y := range x {
+			walkEBNF(y, f)
+		}
+	case ebnfAlternative:
+		for _, y := range x {
+			walkEBNF(y, f)
+		}
+	case *ebnfGroup:
+		walkEBNF(x.Body, f)
+	case *ebnfOption:
+		walkEBNF(x.Body, f)
+	case *ebnfRepetition:
+		walkEBNF(x.Body, f)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Grammar checks
+
+// startProduction is the start symbol of the grammar of the spec.
+const startProduction = "SourceFile"
+
+// GrammarIssue kinds.
+const (
+	GrammarSyntax    = "syntax"    // EBNF syntax error
+	GrammarUndefined = "undefined" // production used but not defined
+	GrammarUnused    = "unused"    // production defined but not used
+	GrammarMissing   = "missing"   // production of the english spec missing in the translation
+	GrammarExtra     = "extra"     // production of the translation missing in the english spec
+	GrammarChanged   = "changed"   // definition differs from the english spec
+)
+
+// A GrammarIssue is a problem of the EBNF productions of a spec.
+type GrammarIssue struct {
+	Kind   string
+	Name   string // production name
+	Detail string
+}
+
+func (x *GrammarIssue) String() string {
+	s := x.Kind
+	if x.Name != "" {
+		s += " " + x.Name
+	}
+	if x.Detail != "" {
+		s += ": " + x.Detail
+	}
+	return s
+}
+
+// CheckGrammar reports the syntax errors, the undefined and the
+// unused productions of the EBNF sections in the HTML source src.
+func CheckGrammar(src []byte) []*GrammarIssue {
+	prods, errs := parseGrammar(src)
+
+	var issues []*GrammarIssue
+	for _, err := range errs {
+		issues = append(issues, &GrammarIssue{Kind: GrammarSyntax, Detail: err})
+	}
+	defined := make(map[string]bool)
+	for _, prod := range prods {
+		if defined[prod.Name] {
+			issues = append(issues, &GrammarIssue{GrammarSyntax, prod.Name, "defined twice"})
+		}
+		defined[prod.Name] = true
+	}
+	for _, prod := range prods {
+		for _, name := range prod.Uses {
+			if !defined[name] {
+				issues = append(issues, &GrammarIssue{GrammarUndefined, name, "used by " + prod.Name})
+			}
+		}
+		if len(prod.UsedBy) == 0 && prod.Name != startProduction {
+			issues = append(issues, &GrammarIssue{Kind: GrammarUnused, Name: prod.Name})
+		}
+	}
+	return issues
+}
+
+// CompareGrammar reports the differences between the productions
+// of the translated spec and the english one, both HTML sources.
+func CompareGrammar(english, translation []byte) []*GrammarIssue {
+	defs := func(src []byte) (names []string, m map[string]string) {
+		m = make(map[string]string)
+		for _, prod := range ParseGrammar(src) {
+			names = append(names, prod.Name)
+			m[prod.Name] = strings.Join(strings.Fields(html.UnescapeString(prod.Src)), " ")
+		}
+		return
+	}
+	enNames, en := defs(english)
+	trNames, tr := defs(translation)
+
+	var issues []*GrammarIssue
+	for _, name := range enNames {
+		switch def, ok := tr[name]; {
+		case !ok:
+			issues = append(issues, &GrammarIssue{Kind: GrammarMissing, Name: name})
+		case def != en[name]:
+			issues = append(issues, &GrammarIssue{GrammarChanged, name, fmt.Sprintf("%q, want %q", def, en[name])})
+		}
+	}
+	for _, name := range trNames {
+		if _, ok := en[name]; !ok {
+			issues = append(issues, &GrammarIssue{Kind: GrammarExtra, Name: name})
+		}
+	}
+	return issues
+}
+
+// ----------------------------------------------------------------------------
+// Railroad diagrams
+
+const (
+	rrRadius   = 10 // radius of the curves
+	rrGap      = 10 // space between the boxes
+	rrHeight   = 24 // height of the boxes
+	rrCharSize = 8  // width of a character
+)
+
+// A rrBox is the layout of an expression: its width, its extent
+// above and below the rail, and how to draw it at the rail
+// position (x, y).
+type rrBox struct {
+	w, up, down int
+	draw        func(w io.Writer, x, y int)
+}
+
+func rrLine(w io.Writer, x0, y0, x1, y1 int) {
+	if x0 != x1 || y0 != y1 {
+		fmt.Fprintf(w, "<path d=\"M%d %dL%d %d\"/>\n", x0, y0, x1, y1)
+	}
+}
+
+// rrBranch draws a line from the rail (x, y) down (or up) to a
+// branch at y1, reaching it at x+2r.
+func rrBranch(w io.Writer, x, y, y1 int) {
+	r, d := rrRadius, rrRadius
+	if y1 < y {
+		d = -d
+	}
+	fmt.Fprintf(w, "<path d=\"M%d %dQ%d %d %d %dL%d %dQ%d %d %d %d\"/>\n",
+		x, y, x+r, y, x+r, y+d, x+r, y1-d, x+r, y1, x+2*r, y1)
+}
+
+// rrMerge draws a line from a branch at (x-2r, y1) back to the rail (x, y).
+func rrMerge(w io.Writer, x, y, y1 int) {
+	r, d := rrRadius, rrRadius
+	if y1 < y {
+		d = -d
+	}
+	fmt.Fprintf(w, "<path d=\"M%d %dQ%d %d %d %dL%d %dQ%d %d %d %d\"/>\n",
+		x-2*r, y1, x-r, y1, x-r, y1-d, x-r, y+d, x-r, y, x, y)
+}
+
+func rrText(text string, terminal bool, href string) *rrBox {
+	width := utf8.RuneCountInString(text)*rrCharSize + 2*rrGap
+	return &rrBox{
+		w: width, up: rrHeight / 2, down: rrHeight / 2,
+		draw: func(w io.Writer, x, y int) {
+			if href != "" {
+				fmt.Fprintf(w, "<a xlink:href=\"%s\">", html.EscapeString(href))
+			}
+			rx, class := 0, "nonterminal"
+			if terminal {
+				rx, class = rrHeight/2, "terminal"
+			}
+			fmt.Fprintf(w, "<rect class=\"%s\" x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"%d\"/>",
+				class, x, y-rrHeight/2, width, rrHeight, rx)
+			fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\">%s</text>", x+width/2, y+4, html.EscapeString(text))
+			if href != "" {
+				fmt.Fprintf(w, "</a>")
+			}
+			fmt.Fprintln(w)
+		},
+	}
+}
+
+func rrSequence(list []*rrBox) *rrBox {
+	b := &rrBox{}
+	for i, x := range list {
+		if i > 0 {
+			b.w += rrGap
+		}
+		b.w += x.w
+		b.up = rrMax(b.up, x.up)
+		b.down = rrMax(b.down, x.down)
+	}
+	b.draw = func(w io.Writer, x, y int) {
+		for i, item := range list {
+			if i > 0 {
+				rrLine(w, x, y, x+rrGap, y)
+				x += rrGap
+			}
+			item.draw(w, x, y)
+			x += item.w
+		}
+	}
+	return b
+}
+
+// rrChoice stacks the alternatives below the first one, on the rail.
+func rrChoice(list []*rrBox) *rrBox {
+	maxw := 0
+	for _, x := range list {
+		maxw = rrMax(maxw, x.w)
+	}
+	b := &rrBox{w: maxw + 4*rrRadius, up: list[0].up}
+	offsets := make([]int, len(list)) // of the rails below the main one
+	for i, x := range list {
+		if i > 0 {
+			offsets[i] = rrMax(offsets[i-1]+list[i-1].down+rrGap+x.up, 2*rrRadius)
+		}
+		b.down = offsets[i] + x.down
+	}
+	b.draw = func(w io.Writer, x, y int) {
+		for i, item := range list {
+			y1 := y + offsets[i]
+			if i == 0 {
+				rrLine(w, x, y, x+2*rrRadius, y)
+			} else {
+				rrBranch(w, x, y, y1)
+			}
+			item.draw(w, x+2*rrRadius, y1)
+			rrLine(w, x+2*rrRadius+item.w, y1, x+b.w-2*rrRadius, y1)
+			if i == 0 {
+				rrLine(w, x+b.w-2*rrRadius, y, x+b.w, y)
+			} else {
+				rrMerge(w, x+b.w, y, y1)
+			}
+		}
+	}
+	return b
+}
+
+// rrLoop draws the body on the rail, a loop back below it and
+// a line skipping it above.
+func rrLoop(body *rrBox) *rrBox {
+	up := rrMax(body.up+rrGap, 2*rrRadius)
+	down := rrMax(body.down+rrGap, 2*rrRadius)
+	b := &rrBox{w: body.w + 4*rrRadius, up: up, down: down}
+	b.draw = func(w io.Writer, x, y int) {
+		r := rrRadius
+		xs, xe := x+2*r, x+2*r+body.w
+
+		// skip
+		rrBranch(w, x, y, y-up)
+		rrLine(w, x+2*r, y-up, x+b.w-2*r, y-up)
+		rrMerge(w, x+b.w, y, y-up)
+
+		// body
+		rrLine(w, x, y, xs, y)
+		body.draw(w, xs, y)
+		rrLine(w, xe, y, x+b.w, y)
+
+		// loop
+		fmt.Fprintf(w, "<path d=\"M%d %dQ%d %d %d %dL%d %dQ%d %d %d %dL%d %dQ%d %d %d %dL%d %dQ%d %d %d %d\"/>\n",
+			xe, y, xe+r, y, xe+r, y+r, xe+r, y+down-r, xe+r, y+down, xe, y+down,
+			xs, y+down, xs-r, y+down, xs-r, y+down-r, xs-r, y+r, xs-r, y, xs, y)
+	}
+	return b
+}
+
+func rrLayout(x ebnfExpr, href func(name string) string) *rrBox {
+	switch x := x.(type) {
+	case ebnfName:
+		return rrText(string(x), false, href(string(x)))
+	case ebnfToken:
+		return rrText(html.UnescapeString(string(x)), true, "")
+	case *ebnfRange:
+		return rrText(html.UnescapeString(x.Begin+" … "+x.End), true, "")
+	case ebnfSequence:
+		var list []*rrBox
+		for _, y := range x {
+			list = append(list, rrLayout(y, href))
+		}
+		return rrSequence(list)
+	case ebnfAlternative:
+		var list []*rrBox
+		for _, y := range x {
+			list = append(list, rrLayout(y, href))
+		}
+		return rrChoice(list)
+	case *ebnfGroup:
+		return rrLayout(x.Body, href)
+	case *ebnfOption:
+		return rrChoice([]*rrBox{rrSequence(nil), rrLayout(x.Body, href)})
+	case *ebnfRepetition:
+		return rrLoop(rrLayout(x.Body, href))
+	}
+	return rrSequence(nil) // empty
+}
+
+const rrStyle = `<style>
+.railroad path { fill: none; stroke: #375eab; stroke-width: 1.5; }
+.railroad rect { fill: #e0ebf5; stroke: #375eab; stroke-width: 1.5; }
+.railroad rect.terminal { fill: #fff; }
+.railroad text { font: 13px monospace; text-anchor: middle; }
+.railroad a text { fill: #375eab; }
+</style>
+`
+
+// RailroadSVG writes the railroad diagram of prod as a SVG image.
+// The production names in the diagram link to href(name), if not empty.
+func RailroadSVG(w io.Writer, prod *Production, href func(name string) string) {
+	if href == nil {
+		href = func(string) string { return "" }
+	}
+	body := rrLayout(prod.expr, href)
+	const margin = 10
+	width := body.w + 4*margin
+	height := body.up + body.down + 2*margin
+	y := margin + body.up
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" class=\"railroad\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n",
+		width, height, width, height)
+	io.WriteString(w, rrStyle)
+	// start and end markers
+	fmt.Fprintf(w, "<path d=\"M%d %dL%d %dM%d %dL%d %d\"/>\n", margin, y-5, margin, y+5, width-margin, y-5, width-margin, y+5)
+	rrLine(w, margin, y, 2*margin, y)
+	body.draw(w, 2*margin, y)
+	rrLine(w, 2*margin+body.w, y, width-margin, y)
+	io.WriteString(w, "</svg>\n")
+}
+
+func rrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ----------------------------------------------------------------------------
+// Grammar index
+
+// specPath is the spec in the /doc tree of a language, see Corpus.DocumentFS.
+const specPath = "/doc/go_spec.html"
+
+// ServeGrammar serves the grammar index of the spec at /ref/spec/grammar,
+// and the railroad diagrams of its productions at
+// /ref/spec/grammar/$(name).svg, for the request language.
+func (p *Presentation) ServeGrammar(w http.ResponseWriter, r *http.Request) {
+	lang := r.FormValue("lang")
+	src, err := vfs.ReadFile(p.Corpus.fsFor(lang), specPath)
+	if err != nil {
+		p.ServeError(w, r, specPath, err)
+		return
+	}
+	prods := ParseGrammar(src)
+
+	query := ""
+	if lang != "" {
+		query = "?lang=" + url.QueryEscape(lang)
+	}
+
+	name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/ref/spec/grammar"), "/")
+	if name != "" {
+		name = strings.TrimSuffix(name, ".svg")
+		for _, prod := range prods {
+			if prod.Name == name {
+				w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+				RailroadSVG(w, prod, func(name string) string {
+					return "/ref/spec/grammar" + query + "#" + name
+				})
+				return
+			}
+		}
+		p.ServeError(w, r, r.URL.Path, fmt.Errorf("unknown production %q", name))
+		return
+	}
+
+	title := "The Go Programming Language Specification"
+	if m := p.Corpus.MetadataFor("/ref/spec", lang); m != nil && m.Title != "" {
+		title = m.Title
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<div id=\"grammar-index\">\n<p>")
+	for i, prod := range prods {
+		if i > 0 {
+			buf.WriteString(" &middot;\n")
+		}
+		fmt.Fprintf(&buf, `<a href="#%[1]s">%[1]s</a>`, template.HTMLEscapeString(prod.Name))
+	}
+	buf.WriteString("</p>\n</div>\n")
+	for _, prod := range prods {
+		name := template.HTMLEscapeString(prod.Name)
+		fmt.Fprintf(&buf, "<h3 id=\"%s\">%s <a href=\"/ref/spec%s#%s\" class=\"permalink\">&para;</a></h3>\n", name, name, query, name)
+		// the definition is HTML source, and links to the index
+		var def bytes.Buffer
+		Linkify(&def, []byte(string(openTag)+prod.Src+string(closeTag)))
+		buf.Write(bytes.Replace(def.Bytes(), []byte(`<a id="`+prod.Name+`">`), []byte("<a>"), 1))
+		buf.WriteString("\n")
+		RailroadSVG(&buf, prod, func(name string) string { return "#" + name })
+		for _, x := range []struct {
+			label string
+			names []string
+		}{{"Uses", prod.Uses}, {"Used by", prod.UsedBy}} {
+			if len(x.names) == 0 {
+				continue
+			}
+			fmt.Fprintf(&buf, "<p>%s:", template.HTMLEscapeString(p.Translate(lang, x.label)))
+			for _, name := range x.names {
+				fmt.Fprintf(&buf, ` <a href="#%[1]s">%[1]s</a>`, template.HTMLEscapeString(name))
+			}
+			buf.WriteString("</p>\n")
+		}
+	}
+
+	p.ServePage(w, Page{
+		Title:    title,
+		Subtitle: p.Translate(lang, "Grammar"),
+		Lang:     lang,
+		Body:     buf.Bytes(),
+	})
+}
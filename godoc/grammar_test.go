@@ -0,0 +1,130 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const grammarSrc = `<p>Productions:</p>
+<pre class="ebnf">
+Block = "{" StatementList "}" .
+StatementList = { Statement ";" } .
+</pre>
+<p>and</p>
+<pre class="ebnf">
+Statement = [ Label ] ( Block | identifier ) .
+identifier = letter { letter | "0" … "9" } .
+letter = "a" … "z" .
+Label = .
+</pre>
+`
+
+func TestParseGrammar(t *testing.T) {
+	prods := ParseGrammar([]byte(grammarSrc))
+	var names []string
+	for _, prod := range prods {
+		names = append(names, prod.Name)
+	}
+	if want := []string{"Block", "StatementList", "Statement", "identifier", "letter", "Label"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("names = %v, want %v", names, want)
+	}
+
+	stmt := prods[2]
+	if want := `Statement = [ Label ] ( Block | identifier ) .`; stmt.Src != want {
+		t.Errorf("Src = %q, want %q", stmt.Src, want)
+	}
+	if want := []string{"Label", "Block", "identifier"}; !reflect.DeepEqual(stmt.Uses, want) {
+		t.Errorf("Uses = %v, want %v", stmt.Uses, want)
+	}
+	if want := []string{"StatementList"}; !reflect.DeepEqual(stmt.UsedBy, want) {
+		t.Errorf("UsedBy = %v, want %v", stmt.UsedBy, want)
+	}
+	if want := []string{"identifier"}; !reflect.DeepEqual(prods[4].UsedBy, want) {
+		t.Errorf("letter UsedBy = %v, want %v", prods[4].UsedBy, want)
+	}
+	if prods[5].expr != nil {
+		t.Errorf("Label expr = %v, want nil", prods[5].expr)
+	}
+}
+
+func TestRailroadSVG(t *testing.T) {
+	for _, prod := range ParseGrammar([]byte(grammarSrc)) {
+		var buf bytes.Buffer
+		RailroadSVG(&buf, prod, func(name string) string { return "#" + name })
+		svg := buf.String()
+		if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") {
+			t.Errorf("%s: bad svg %q", prod.Name, svg)
+		}
+		for _, name := range prod.Uses {
+			if !strings.Contains(svg, `<a xlink:href="#`+name+`">`) {
+				t.Errorf("%s: missing link to %s", prod.Name, name)
+			}
+		}
+	}
+}
+
+func TestLinkify(t *testing.T) {
+	var buf bytes.Buffer
+	Linkify(&buf, []byte(`<pre class="ebnf">A = B | "x" … "y" .</pre>`))
+	want := `<pre class="ebnf"><a id="A">A</a> = <a href="#B" class="noline">B</a> | "x" … "y" .</pre>`
+	if got := buf.String(); got != want {
+		t.Errorf("Linkify = %q, want %q", got, want)
+	}
+
+	// raw strings are only accepted by CheckGrammar and CompareGrammar
+	buf.Reset()
+	Linkify(&buf, []byte("<pre class=\"ebnf\">A = `x` .</pre>"))
+	if got := buf.String(); !strings.Contains(got, "error: expected term") {
+		t.Errorf("Linkify(raw string) = %q, want syntax error", got)
+	}
+}
+
+func grammarIssues(issues []*GrammarIssue) []string {
+	var list []string
+	for _, x := range issues {
+		list = append(list, x.Kind+" "+x.Name)
+	}
+	return list
+}
+
+func TestCheckGrammar(t *testing.T) {
+	src := `<pre class="ebnf">
+SourceFile = Block { Block } .
+Block = "{" Statement "}" | ` + "`\\`" + ` .
+Unused = Block .
+</pre>
+<pre class="ebnf">
+Bad = "x" | .
+</pre>`
+	got := grammarIssues(CheckGrammar([]byte(src)))
+	want := []string{"syntax ", "undefined Statement", "unused Unused", "unused Bad"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckGrammar = %q, want %q", got, want)
+	}
+}
+
+func TestCompareGrammar(t *testing.T) {
+	english := `<pre class="ebnf">
+A = B | "x" .
+B = "&lt;" .
+C = A .
+</pre>`
+	translation := `<h2>翻译</h2>
+<pre class="ebnf">
+A = B |
+    "x" .
+B = "<" "=" .
+D = A .
+</pre>`
+	got := grammarIssues(CompareGrammar([]byte(english), []byte(translation)))
+	want := []string{"changed B", "missing C", "extra D"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareGrammar = %q, want %q", got, want)
+	}
+}
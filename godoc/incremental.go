@@ -0,0 +1,541 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the incremental update of the index: the
+// directories whose listing changed since the index was built are
+// re-indexed, and the result is merged into a copy of the index
+// without the old contents of these directories.
+//
+// A directory is identified with its import path in the identifier,
+// export and doc indices; the directories sharing an import path with
+// a changed directory are re-indexed as well.
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"hash/fnv"
+	"log"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+)
+
+// A dirIndex records what a directory contributed to an index, such
+// that it can be removed when the directory changes.
+type dirIndex struct {
+	Sig     uint64         // signature of the directory listing
+	Imports map[string]int // import path => number of imports
+	Stats   Statistics     // except Words
+}
+
+func (d *dirIndex) addImport(path string) {
+	if d == nil {
+		return
+	}
+	if d.Imports == nil {
+		d.Imports = make(map[string]int)
+	}
+	d.Imports[path]++
+}
+
+// dirSignature returns the signature of the files of a directory
+// listing: their names and sizes, and their modification times if
+// modTimes is set.
+func dirSignature(list []os.FileInfo, modTimes bool) uint64 {
+	h := fnv.New64a()
+	for _, fi := range list {
+		switch {
+		case fi.IsDir():
+		case modTimes:
+			fmt.Fprintf(h, "%s %d %d\n", fi.Name(), fi.Size(), fi.ModTime().UnixNano())
+		default:
+			fmt.Fprintf(h, "%s %d\n", fi.Name(), fi.Size())
+		}
+	}
+	return h.Sum64()
+}
+
+// dirImportPath returns the import path of the package in dirname.
+func dirImportPath(dirname string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(dirname, "/src/"), "pkg/")
+}
+
+// changedDirs returns the directories added, removed or changed since
+// the index x was built, and the directories sharing their import paths.
+func (c *Corpus) changedDirs(x *Index) map[string]bool {
+	changed := make(map[string]bool)
+	seen := make(map[string]bool)
+	for dirname := range c.fsDirnames() {
+		if c.IndexDirectory != nil && !c.IndexDirectory(dirname) {
+			continue
+		}
+		seen[dirname] = true
+		d := x.dirs[dirname]
+		list, err := c.fs.ReadDir(dirname)
+		if err != nil {
+			if d != nil {
+				changed[dirname] = true // not indexed anymore
+			}
+			continue
+		}
+		if d == nil || d.Sig != dirSignature(list, true) {
+			changed[dirname] = true
+		}
+	}
+	for dirname := range x.dirs {
+		if !seen[dirname] {
+			changed[dirname] = true
+		}
+	}
+
+	paths := make(map[string]bool)
+	for dirname := range changed {
+		paths[dirImportPath(dirname)] = true
+	}
+	for dirname := range x.dirs {
+		if paths[dirImportPath(dirname)] {
+			changed[dirname] = true
+		}
+	}
+	return changed
+}
+
+// updateIndex returns the index of the current file system: old
+// updated with the changed directories if possible, or a new index.
+// A new index is built if most directories changed, or if most
+// snippets of old are not used anymore.
+func (c *Corpus) updateIndex(old *Index) *Index {
+	if old == nil || old.dirs == nil || !old.CompatibleWith(c) {
+		return c.NewIndex()
+	}
+	changed := c.changedDirs(old)
+	if len(changed) == 0 {
+		return old
+	}
+	if 2*len(changed) > len(old.dirs) || 2*old.deadSnippets > len(old.snippets) {
+		return c.NewIndex()
+	}
+	if c.Verbose {
+		log.Printf("re-indexing %d changed directories", len(changed))
+	}
+	return old.merge(c.newIndex(changed), changed)
+}
+
+// merge returns a new index: x with the contents of the directories in
+// changed replaced by the index y of these directories. x is unchanged,
+// y must not be used anymore.
+func (x *Index) merge(y *Index, changed map[string]bool) *Index {
+	m := joinIndexes([]*Index{x.without(changed), y})
+	m.text = nil
+	if len(x.text) > 0 && len(y.text) > 0 {
+		m.text = mergeText(x.text, y.text, changed)
+	}
+	return m
+}
+
+// without returns a copy of x without the contents of the directories
+// in changed, except for the full text index which is shared. x is
+// unchanged.
+func (x *Index) without(changed map[string]bool) *Index {
+	paths := make(map[string]bool)
+	for dirname := range changed {
+		paths[dirImportPath(dirname)] = true
+	}
+	m := &Index{
+		text:         x.text,
+		snippets:     x.snippets,
+		importCount:  make(map[string]int, len(x.importCount)),
+		packagePath:  make(map[string]map[string]bool, len(x.packagePath)),
+		exports:      make(map[string]map[string]SpotKind, len(x.exports)),
+		idents:       make(map[SpotKind]map[string][]Ident, len(x.idents)),
+		docs:         make(map[string]*DocIndex, len(x.docs)),
+		selectors:    make(map[string]HitList, len(x.selectors)),
+		dirs:         make(map[string]*dirIndex, len(x.dirs)),
+		deadSnippets: x.deadSnippets,
+		opts:         x.opts,
+	}
+
+	// directories, statistics and import counts
+	m.stats = x.stats
+	for path, n := range x.importCount {
+		m.importCount[path] = n
+	}
+	for dirname, d := range x.dirs {
+		if !changed[dirname] {
+			m.dirs[dirname] = d
+			continue
+		}
+		m.stats.Bytes -= d.Stats.Bytes
+		m.stats.Files -= d.Stats.Files
+		m.stats.Lines -= d.Stats.Lines
+		m.stats.Spots -= d.Stats.Spots
+		for path, n := range d.Imports {
+			if m.importCount[path] -= n; m.importCount[path] <= 0 {
+				delete(m.importCount, path)
+			}
+		}
+	}
+
+	// words
+	m.words = make(map[string]*LookupResult, len(x.words))
+	for w, r := range x.words {
+		decls, dead := dropDirs(r.Decls, changed)
+		others, _ := dropDirs(r.Others, changed)
+		m.deadSnippets += dead
+		switch {
+		case len(decls) == len(r.Decls) && len(others) == len(r.Others):
+			m.words[w] = r
+		case len(decls) > 0 || len(others) > 0:
+			m.words[w] = &LookupResult{decls, others}
+		}
+	}
+	m.stats.Words = len(m.words)
+	for sel, h := range x.selectors {
+		if l, _ := dropDirs(h, changed); len(l) > 0 {
+			m.selectors[sel] = l
+		}
+	}
+
+	// packages, exports and identifiers
+	for name, pkgPaths := range x.packagePath {
+		for path := range pkgPaths {
+			if !paths[path] {
+				m.addPackagePath(name, path)
+			}
+		}
+	}
+	for path, exports := range x.exports {
+		if !paths[path] {
+			m.exports[path] = exports
+		}
+	}
+	for kind, names := range x.idents {
+		m.idents[kind] = make(map[string][]Ident)
+		for name, list := range names {
+			var l []Ident
+			for _, id := range list {
+				if !paths[id.Path] {
+					l = append(l, id)
+				}
+			}
+			if len(l) > 0 {
+				m.idents[kind][name] = l
+			}
+		}
+	}
+	for lang, d := range x.docs {
+		m.docs[lang] = d.without(paths)
+	}
+	for _, sig := range x.signatures {
+		if !paths[sig.Path] {
+			m.signatures = append(m.signatures, sig)
+		}
+	}
+	return m
+}
+
+// joinIndexes returns the index of the disjoint sets of directories
+// indexed by list. The indices in list must not be used anymore: the
+// snippet indices of all but the first one are changed.
+func joinIndexes(list []*Index) *Index {
+	m := &Index{
+		words:       make(map[string]*LookupResult),
+		importCount: make(map[string]int),
+		packagePath: make(map[string]map[string]bool),
+		exports:     make(map[string]map[string]SpotKind),
+		idents:      make(map[SpotKind]map[string][]Ident),
+		docs:        make(map[string]*DocIndex),
+		selectors:   make(map[string]HitList),
+		dirs:        make(map[string]*dirIndex),
+	}
+	joined := make(map[string]bool)    // words with hits of several indices
+	joinedSel := make(map[string]bool) // selectors with hits of several indices
+	for i, y := range list {
+		if i == 0 {
+			m.opts = y.opts
+		}
+		m.text = append(m.text, y.text...)
+		m.deadSnippets += y.deadSnippets
+
+		// directories, statistics and import counts
+		for dirname, d := range y.dirs {
+			m.dirs[dirname] = d
+		}
+		m.stats.Bytes += y.stats.Bytes
+		m.stats.Files += y.stats.Files
+		m.stats.Lines += y.stats.Lines
+		m.stats.Spots += y.stats.Spots
+		for path, n := range y.importCount {
+			m.importCount[path] += n
+		}
+
+		// words and snippets
+		off := len(m.snippets)
+		if i == 0 {
+			m.snippets = y.snippets
+		} else {
+			m.snippets = append(m.snippets[:off:off], y.snippets...)
+		}
+		for w, r := range y.words {
+			if off > 0 {
+				shiftSnippets(r.Decls, off)
+			}
+			if old := m.words[w]; old != nil {
+				r = &LookupResult{
+					append(old.Decls[:len(old.Decls):len(old.Decls)], r.Decls...),
+					append(old.Others[:len(old.Others):len(old.Others)], r.Others...),
+				}
+				joined[w] = true
+			}
+			m.words[w] = r
+		}
+		for sel, h := range y.selectors {
+			if old := m.selectors[sel]; old != nil {
+				h = append(old[:len(old):len(old)], h...)
+				joinedSel[sel] = true
+			}
+			m.selectors[sel] = h
+		}
+
+		// packages, exports and identifiers
+		for name, pkgPaths := range y.packagePath {
+			for path := range pkgPaths {
+				m.addPackagePath(name, path)
+			}
+		}
+		for path, exports := range y.exports {
+			m.exports[path] = exports
+		}
+		for kind, names := range y.idents {
+			if m.idents[kind] == nil {
+				m.idents[kind] = make(map[string][]Ident)
+			}
+			for name, list := range names {
+				l := m.idents[kind][name]
+				m.idents[kind][name] = append(l[:len(l):len(l)], list...)
+			}
+		}
+		for lang, d := range y.docs {
+			m.docs[lang] = m.docs[lang].join(d)
+		}
+		m.signatures = append(m.signatures, y.signatures...)
+	}
+
+	for w := range joined {
+		r := m.words[w]
+		sort.Sort(hitsByPak(r.Decls))
+		sort.Sort(hitsByPak(r.Others))
+	}
+	for sel := range joinedSel {
+		sort.Sort(hitsByPak(m.selectors[sel]))
+	}
+	m.stats.Words = len(m.words)
+	var wlist RunList
+	for w := range m.words {
+		wlist = append(wlist, &wordPair{canonical(w), w})
+	}
+	m.alts = newAlts(wlist)
+	if len(list) > 1 {
+		for _, names := range m.idents {
+			for _, l := range names {
+				sort.Sort(byImportCount{l, m.importCount})
+			}
+		}
+		sort.Sort(sigsByName(m.signatures))
+	}
+	return m
+}
+
+func (x *Index) addPackagePath(name, path string) {
+	if x.packagePath[name] == nil {
+		x.packagePath[name] = make(map[string]bool)
+	}
+	x.packagePath[name][path] = true
+}
+
+// dropDirs returns the hits of h outside of the directories in changed,
+// and the number of snippets of the dropped hits.
+func dropDirs(h HitList, changed map[string]bool) (list HitList, dead int) {
+	i := 0
+	for i < len(h) && !changed[h[i].Pak.Path] {
+		i++
+	}
+	if i == len(h) {
+		return h, 0 // common case: nothing to drop
+	}
+	list = append(list, h[:i]...)
+	for _, run := range h[i:] {
+		if !changed[run.Pak.Path] {
+			list = append(list, run)
+			continue
+		}
+		for _, f := range run.Files {
+			for _, group := range f.Groups {
+				for _, info := range group {
+					if info.IsIndex() {
+						dead++
+					}
+				}
+			}
+		}
+	}
+	return list, dead
+}
+
+// shiftSnippets adds off to the snippet indices of the hits in h.
+func shiftSnippets(h HitList, off int) {
+	for _, run := range h {
+		for _, f := range run.Files {
+			for _, group := range f.Groups {
+				for i, info := range group {
+					if info.IsIndex() {
+						group[i] = makeSpotInfo(info.Kind(), info.Lori()+off, true)
+					}
+				}
+			}
+		}
+	}
+}
+
+type hitsByPak HitList
+
+func (h hitsByPak) Len() int           { return len(h) }
+func (h hitsByPak) Less(i, j int) bool { return h[i].Pak.less(h[j].Pak) }
+func (h hitsByPak) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// without returns the doc index d without the identifiers of the
+// packages in paths.
+func (d *DocIndex) without(paths map[string]bool) *DocIndex {
+	m := &DocIndex{Terms: make(map[string][]int)}
+	index := make([]int, len(d.Idents)) // index of d.Idents[i] in m, or -1
+	for i, id := range d.Idents {
+		index[i] = -1
+		if !paths[id.Path] {
+			index[i] = len(m.Idents)
+			m.Idents = append(m.Idents, id)
+		}
+	}
+	for term, list := range d.Terms {
+		var l []int
+		for _, i := range list {
+			if j := index[i]; j >= 0 {
+				l = append(l, j)
+			}
+		}
+		if len(l) > 0 {
+			m.Terms[term] = l
+		}
+	}
+	return m
+}
+
+// join returns the doc index with the identifiers of d and e; d may be
+// nil. Neither d nor e is changed.
+func (d *DocIndex) join(e *DocIndex) *DocIndex {
+	m := &DocIndex{Terms: make(map[string][]int)}
+	if d != nil {
+		m.Idents = d.Idents[:len(d.Idents):len(d.Idents)]
+		for term, list := range d.Terms {
+			m.Terms[term] = list[:len(list):len(list)]
+		}
+	}
+	off := len(m.Idents)
+	m.Idents = append(m.Idents, e.Idents...)
+	for term, list := range e.Terms {
+		l := m.Terms[term]
+		for _, i := range list {
+			l = append(l, i+off)
+		}
+		m.Terms[term] = l
+	}
+	return m
+}
+
+// maxTextIndexes is the number of full text indices beyond which
+// mergeText copies them into one.
+const maxTextIndexes = 8
+
+// mergeText returns the full text indices of the files of x outside of
+// the directories in changed and of the files of y. The indices of x
+// known to contain no changed directory are kept; the kept files of the
+// others are copied into a new index, no file is read. The indices of y
+// are added as is, such that changing the same directories again only
+// rebuilds those.
+func mergeText(x, y []*textIndex, changed map[string]bool) []*textIndex {
+	var list, stale []*textIndex
+	for _, t := range x {
+		if t.unchanged(changed) {
+			list = append(list, t)
+		} else {
+			stale = append(stale, t)
+		}
+	}
+	if len(stale) > 0 {
+		if t := copyText(stale, func(f *token.File) bool { return !changed[pathpkg.Dir(f.Name())] }); t != nil {
+			list = append(list, t)
+		}
+	}
+	list = append(list, y...)
+	if len(list) > maxTextIndexes {
+		t := copyText(list, func(*token.File) bool { return true })
+		list = nil
+		if t != nil {
+			list = []*textIndex{t}
+		}
+	}
+	return list
+}
+
+// copyText returns a new full text index of the files of the indices
+// in list for which keep is true, nil if there are none.
+func copyText(list []*textIndex, keep func(f *token.File) bool) *textIndex {
+	fset := token.NewFileSet()
+	var sources bytes.Buffer
+	dirs := make(map[string]bool)
+	for _, t := range list {
+		if err := t.get(); err != nil {
+			log.Printf("loading full text index: %v", err)
+			continue
+		}
+		src := t.src
+		t.fset.Iterate(func(f *token.File) bool {
+			if keep(f) {
+				// as in Indexer.addFile, offsets and Pos values are in lock-step
+				sources.WriteByte(0)
+				data := src[f.Base() : f.Base()+f.Size()]
+				fset.AddFile(f.Name(), fset.Base(), f.Size()).SetLinesForContent(data)
+				sources.Write(data)
+				dirs[pathpkg.Dir(f.Name())] = true
+			}
+			return true
+		})
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	return &textIndex{
+		fset:     fset,
+		src:      sources.Bytes(),
+		trigrams: newTrigramIndex(fset, sources.Bytes(), nil),
+		dirs:     dirs,
+	}
+}
+
+// unchanged reports whether t is known to contain no file of the
+// directories in changed.
+func (t *textIndex) unchanged(changed map[string]bool) bool {
+	if t.dirs == nil {
+		return false
+	}
+	for dirname := range changed {
+		if t.dirs[dirname] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+// hitStrings returns the hits of h in a form independent of the
+// snippet indices.
+func hitStrings(x *Index, h HitList) []string {
+	var list []string
+	for _, run := range h {
+		for _, f := range run.Files {
+			for _, group := range f.Groups {
+				for _, info := range group {
+					s := fmt.Sprintf("%s %s %d", f.File.Path(), spotKindNames[info.Kind()], info.Lori())
+					if info.IsIndex() {
+						sn := x.Snippet(info.Lori())
+						s = fmt.Sprintf("%s %s %d %s", f.File.Path(), spotKindNames[info.Kind()], sn.Line, sn.Text)
+					}
+					list = append(list, s)
+				}
+			}
+		}
+	}
+	return list
+}
+
+// initTestCorpus initializes the directory tree of c. Unlike Init, it
+// does not start the metadata refresher, which would read the files of
+// the test concurrently with their changes.
+func initTestCorpus(t *testing.T, c *Corpus) {
+	if err := c.initFSTree(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type identsByPath []Ident
+
+func (s identsByPath) Len() int      { return len(s) }
+func (s identsByPath) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s identsByPath) Less(i, j int) bool {
+	return s[i].Path < s[j].Path || s[i].Path == s[j].Path && s[i].Name < s[j].Name
+}
+
+// compareIndex reports the differences of the incrementally updated
+// index x and the new index y.
+func compareIndex(t *testing.T, x, y *Index) {
+	if x.stats != y.stats {
+		t.Errorf("stats = %+v; want %+v", x.stats, y.stats)
+	}
+	if len(x.words) != len(y.words) {
+		t.Errorf("%d words; want %d", len(x.words), len(y.words))
+	}
+	for w, r := range y.words {
+		xr := x.words[w]
+		if xr == nil {
+			t.Errorf("word %s missing", w)
+			continue
+		}
+		if got, want := hitStrings(x, xr.Decls), hitStrings(y, r.Decls); !reflect.DeepEqual(got, want) {
+			t.Errorf("decls of %s = %v; want %v", w, got, want)
+		}
+		if got, want := hitStrings(x, xr.Others), hitStrings(y, r.Others); !reflect.DeepEqual(got, want) {
+			t.Errorf("uses of %s = %v; want %v", w, got, want)
+		}
+	}
+	if len(x.alts) != len(y.alts) {
+		t.Errorf("%d alternative spellings; want %d", len(x.alts), len(y.alts))
+	}
+	for _, test := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"importCount", x.importCount, y.importCount},
+		{"packagePath", x.packagePath, y.packagePath},
+		{"exports", x.exports, y.exports},
+		{"signatures", x.signatures, y.signatures},
+	} {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s = %v; want %v", test.name, test.got, test.want)
+		}
+	}
+	for kind, names := range y.idents {
+		for name, want := range names {
+			got := append([]Ident(nil), x.idents[kind][name]...)
+			want = append([]Ident(nil), want...)
+			sort.Sort(identsByPath(got))
+			sort.Sort(identsByPath(want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("idents %s %s = %v; want %v", spotKindNames[kind], name, got, want)
+			}
+		}
+	}
+	for _, rx := range []string{`func`, `Ba[rz]`, `import "foo"`} {
+		r := regexp.MustCompile(rx)
+		gotN, got := x.LookupRegexp(r, 100)
+		wantN, want := y.LookupRegexp(r, 100)
+		if gotN != wantN || !reflect.DeepEqual(got, want) {
+			t.Errorf("LookupRegexp(%s) = %d, %v; want %d, %v", rx, gotN, got, wantN, want)
+		}
+	}
+}
+
+func TestIncrementalIndex(t *testing.T) {
+	files := map[string]string{
+		"src/foo/foo.go":       "// Package foo is an example.\npackage foo\n\nimport \"bar\"\n\n// Foo is stuff.\ntype Foo struct{}\n\nfunc New() *Foo { return new(Foo) }\n",
+		"src/bar/bar.go":       "// Package bar is another example.\npackage bar\n\nfunc Bar() {}\n",
+		"src/other/bar/bar.go": "// Package bar is another bar package.\npackage bar\n\nimport \"foo\"\n\nfunc X() *foo.Foo { return nil }\n",
+		"src/a/a.go":           "package a\n\nimport \"bar\"\n\nfunc A() {}\n",
+		"src/b/b.go":           "package b\n\nimport \"bar\"\n\nfunc B() {}\n",
+		"src/b/readme.txt":     "Package b, text file.\n",
+	}
+	c := NewCorpus(mapfs.New(files))
+	c.IndexEnabled = true
+	initTestCorpus(t, c)
+	c.UpdateIndex()
+	ix1, _ := c.CurrentIndex()
+
+	// unchanged file system: the index is kept
+	c.UpdateIndex()
+	if ix, _ := c.CurrentIndex(); ix != ix1 {
+		t.Errorf("index rebuilt for unchanged file system")
+	}
+
+	// change, add and remove a package
+	files["src/foo/foo.go"] += "\n// Bar is new.\nfunc Bar(foo string) int { return 0 }\n"
+	files["src/baz/baz.go"] = "// Package baz is new.\npackage baz\n\nimport \"foo\"\n\nfunc Baz() {}\n"
+	delete(files, "src/other/bar/bar.go")
+	initTestCorpus(t, c)
+	c.UpdateIndex()
+	ix2, _ := c.CurrentIndex()
+	if ix2 == ix1 {
+		t.Fatal("index not updated")
+	}
+	if len(ix2.snippets) == 0 || ix2.snippets[0] != ix1.snippets[0] {
+		t.Errorf("index rebuilt instead of updated")
+	}
+	if ix1.words["Baz"] != nil || ix1.words["X"] == nil {
+		t.Errorf("old index changed by update")
+	}
+	compareIndex(t, ix2, c.NewIndex())
+
+	// change the same package again: only its text index is rebuilt
+	files["src/foo/foo.go"] += "\n// Qux is new.\nfunc Qux() {}\n"
+	initTestCorpus(t, c)
+	c.UpdateIndex()
+	ix3, _ := c.CurrentIndex()
+	kept := 0
+	for _, t3 := range ix3.text {
+		for _, t2 := range ix2.text {
+			if t3 == t2 {
+				kept++
+			}
+		}
+	}
+	if kept == 0 {
+		t.Errorf("full text index rebuilt instead of updated")
+	}
+	compareIndex(t, ix3, c.NewIndex())
+}
@@ -27,11 +27,14 @@
 // - add the files to a file set in lockstep as they are added to the byte
 //   buffer such that a byte buffer offset corresponds to the Pos value for
 //   that file location
-// - create a suffix array from the concatenated sources
+// - create a trigram index of the files (see trigram.go), and keep it
+//   with the concatenated sources
 //
-// String lookup in full text index:
-// - use the suffix array to lookup a string's offsets - the offsets
-//   correspond to the Pos values relative to the file set
+// Regexp lookup in full text index:
+// - use the trigram index to find the files that may contain a match
+// - match the regexp against the contents of these files in the
+//   concatenated sources - the offsets correspond to the Pos values
+//   relative to the file set
 // - translate the Pos values back into file and line information and
 //   sort the result
 
@@ -47,8 +50,8 @@ import (
 	"go/doc"
 	"go/parser"
 	"go/token"
-	"index/suffixarray"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	pathpkg "path"
@@ -336,6 +339,22 @@ func newAltWords(h RunList) interface{} {
 	return &AltWords{canon, alts}
 }
 
+// newAlts returns the map of alternative spellings of the word list
+// {canonical(w), w}.
+func newAlts(wlist RunList) map[string]*AltWords {
+	// reduce the word list {canonical(w), w} into
+	// a list of AltWords runs {canonical(w), {w}}
+	alist := wlist.reduce(lessWordPair, newAltWords)
+
+	// convert alist into a map of alternative spellings
+	alts := make(map[string]*AltWords)
+	for i := 0; i < len(alist); i++ {
+		a := alist[i].(*AltWords)
+		alts[a.Canon] = a
+	}
+	return alts
+}
+
 func (a *AltWords) filter(s string) *AltWords {
 	var alts []string
 	for _, w := range a.Alts {
@@ -456,6 +475,13 @@ type Indexer struct {
 	exports       map[string]map[string]SpotKind // "net/http" => "ListenAndServe" => FuncDecl
 	curPkgExports map[string]SpotKind
 	idents        map[SpotKind]map[string][]Ident // kind => name => list of Idents
+	docs          map[string]*DocIndex            // lang => translated docs
+	trPkgs        map[string]*ast.Package         // package path => files of the translated package being indexed, see addTranslatedFile
+	signatures    []FuncSig                       // exported funcs and methods
+	dirs          map[string]*dirIndex            // dirname => indexed directory
+	curDir        *dirIndex                       // directory of the current file
+	fileImports   map[string]string               // import name => path, of the current file
+	selectors     map[string]RunList              // "net/http.Handler" => uses of http.Handler
 }
 
 func (x *Indexer) intern(s string) string {
@@ -537,6 +563,7 @@ func (x *Indexer) visitSpec(kind SpotKind, spec ast.Spec) {
 		if n.Path != nil {
 			if imp, err := strconv.Unquote(n.Path.Value); err == nil {
 				x.importCount[x.intern(imp)]++
+				x.curDir.addImport(x.intern(imp))
 			}
 		}
 
@@ -574,6 +601,10 @@ func (x *Indexer) Visit(node ast.Node) ast.Visitor {
 	case *ast.Ident:
 		x.visitIdent(Use, n)
 
+	case *ast.SelectorExpr:
+		x.visitSelector(n)
+		return x
+
 	case *ast.FieldList:
 		x.visitFieldList(VarDecl, n)
 
@@ -619,6 +650,71 @@ func (x *Indexer) Visit(node ast.Node) ast.Visitor {
 	return nil
 }
 
+// visitSelector records the use of the exported identifier of an
+// imported package, such as http.Handler, by its import path and name.
+// The identifiers of the selector are visited as usual.
+func (x *Indexer) visitSelector(sel *ast.SelectorExpr) {
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Obj != nil || !x.c.IndexGoCode {
+		return // not a package name: declared in the file
+	}
+	path, ok := x.fileImports[pkg.Name]
+	if !ok {
+		return
+	}
+	key := x.intern(path + "." + sel.Sel.Name)
+	info := makeSpotInfo(Use, x.current.Line(sel.Sel.Pos()), false)
+	x.selectors[key] = append(x.selectors[key], Spot{x.file, info})
+}
+
+// fileImportNames returns the names of the packages imported by f,
+// mapped to their import paths.
+func fileImportNames(f *ast.File) map[string]string {
+	names := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := assumedPackageName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name != "_" && name != "." {
+			names[name] = path
+		}
+	}
+	return names
+}
+
+// assumedPackageName returns the name of the package path, if it
+// follows the conventions: the last element of the path, without
+// major version ("yaml" for "gopkg.in/yaml.v2" and
+// "github.com/user/yaml/v2") and "go-" prefix.
+func assumedPackageName(path string) string {
+	name := pathpkg.Base(path)
+	if isMajorVersion(name) && pathpkg.Dir(path) != "." {
+		name = pathpkg.Base(pathpkg.Dir(path))
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// isMajorVersion reports whether s is a major version element such as "v2".
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // addFile adds a file to the index if possible and returns the file set file
 // and the file's AST if it was successfully parsed as a Go file. If addFile
 // failed (that is, if the file was not added), it returns file == nil.
@@ -626,7 +722,7 @@ func (x *Indexer) addFile(f vfs.ReadSeekCloser, filename string, goFile bool) (f
 	defer f.Close()
 
 	// The file set's base offset and x.sources size must be in lock-step;
-	// this permits the direct mapping of suffix array lookup results to
+	// this permits the direct mapping of offsets of the sources to
 	// to corresponding Pos values.
 	//
 	// When a file is added to the file set, its offset base increases by
@@ -728,15 +824,20 @@ func (x *Indexer) indexDocs(dirname string, filename string, astFile *ast.File)
 	if pkgName == "main" {
 		return
 	}
-	pkgPath := x.intern(strings.TrimPrefix(strings.TrimPrefix(dirname, "/src/"), "pkg/"))
+	pkgPath := x.intern(dirImportPath(dirname))
 	astPkg := ast.Package{
 		Name: pkgName,
 		Files: map[string]*ast.File{
 			filename: astFile,
 		},
 	}
+	translate := x.c.TranslateDocPackage != nil && len(x.c.IndexLangs) > 0
 	var m doc.Mode
+	if translate {
+		m |= doc.PreserveAST // documented again by indexTranslatedDir
+	}
 	docPkg := doc.New(&astPkg, dirname, m)
+	x.indexSignatures(pkgPath, pkgName, astFile)
 	addIdent := func(sk SpotKind, name string, docstr string) {
 		if x.idents[sk] == nil {
 			x.idents[sk] = make(map[string][]Ident)
@@ -824,6 +925,10 @@ func (x *Indexer) indexDocs(dirname string, filename string, astFile *ast.File)
 	for _, f := range docPkg.Funcs {
 		addIdent(FuncDecl, f.Name, f.Doc)
 	}
+
+	if translate {
+		x.addTranslatedFile(pkgPath, pkgName, filename, astFile)
+	}
 }
 
 func (x *Indexer) indexGoFile(dirname string, filename string, file *token.File, astFile *ast.File) {
@@ -833,6 +938,7 @@ func (x *Indexer) indexGoFile(dirname string, filename string, file *token.File,
 		x.current = file
 		pak := x.lookupPackage(dirname, pkgName)
 		x.file = &File{filename, pak}
+		x.fileImports = fileImportNames(astFile)
 		ast.Walk(x, astFile)
 	}
 
@@ -850,7 +956,7 @@ func (x *Indexer) indexGoFile(dirname string, filename string, file *token.File,
 	if _, ok := x.packagePath[ppKey]; !ok {
 		x.packagePath[ppKey] = make(map[string]bool)
 	}
-	pkgPath := x.intern(strings.TrimPrefix(strings.TrimPrefix(dirname, "/src/"), "pkg/"))
+	pkgPath := x.intern(dirImportPath(dirname))
 	x.packagePath[ppKey][pkgPath] = true
 
 	// Merge in exported symbols found walking this file into
@@ -910,11 +1016,13 @@ func (x *Indexer) visitFile(dirname string, fi os.FileInfo) {
 	x.throttle.Throttle()
 
 	x.curPkgExports = make(map[string]SpotKind)
+	x.curDir = x.dirs[dirname]
 	file, fast := x.addFile(f, filename, goFile)
 	if file == nil {
 		return // addFile failed
 	}
 
+	spots := x.stats.Spots
 	if fast != nil {
 		x.indexGoFile(dirname, fi.Name(), file, fast)
 	}
@@ -923,6 +1031,12 @@ func (x *Indexer) visitFile(dirname string, fi os.FileInfo) {
 	x.stats.Bytes += file.Size()
 	x.stats.Files++
 	x.stats.Lines += file.LineCount()
+	if d := x.curDir; d != nil {
+		d.Stats.Bytes += file.Size()
+		d.Stats.Files++
+		d.Stats.Lines += file.LineCount()
+		d.Stats.Spots += x.stats.Spots - spots
+	}
 }
 
 // indexOptions contains information that affects the contents of an index.
@@ -945,6 +1059,27 @@ type indexOptions struct {
 	// MaxResults optionally specifies the maximum results for indexing.
 	// The default is 1000.
 	MaxResults int
+
+	// Langs and Translations are the comma-separated IndexLangs and
+	// the content signature of the files under the TranslationRoots,
+	// if translated docs are indexed.
+	Langs        string
+	Translations uint64
+}
+
+// indexOptions returns the indexing options set in c.
+func (c *Corpus) indexOptions() indexOptions {
+	opts := indexOptions{
+		Docs:       c.IndexDocs,
+		GoCode:     c.IndexGoCode,
+		FullText:   c.IndexFullText,
+		MaxResults: c.MaxResults,
+	}
+	if c.TranslateDocPackage != nil && len(c.IndexLangs) > 0 {
+		opts.Langs = strings.Join(c.IndexLangs, ",")
+		opts.Translations = c.translationsSignature()
+	}
+	return opts
 }
 
 // ----------------------------------------------------------------------------
@@ -955,9 +1090,34 @@ type LookupResult struct {
 	Others HitList // all other occurrences
 }
 
+// A textIndex is the full text index of a set of files.
+type textIndex struct {
+	fset     *token.FileSet // file set used during indexing
+	src      []byte         // concatenated sources, at the Pos offsets of fset
+	trigrams *trigramIndex  // trigram index of the files
+
+	// if not nil, the directories of the files
+	dirs map[string]bool
+
+	// if not nil, load reads the index on first use
+	load func(t *textIndex) error
+	once sync.Once
+	err  error
+}
+
+// get loads t if needed.
+func (t *textIndex) get() error {
+	t.once.Do(func() {
+		if t.load != nil {
+			t.err = t.load(t)
+			t.load = nil // release the reader
+		}
+	})
+	return t.err
+}
+
 type Index struct {
-	fset        *token.FileSet           // file set used during indexing; nil if no textindex
-	suffixes    *suffixarray.Index       // suffixes for concatenated sources; nil if no textindex
+	text        []*textIndex             // full text indices of disjoint sets of files; nil if no textindex
 	words       map[string]*LookupResult // maps words to hit lists
 	alts        map[string]*AltWords     // maps canonical(words) to lists of alternative spellings
 	snippets    []*Snippet               // all snippets, indexed by snippet index
@@ -966,7 +1126,16 @@ type Index struct {
 	packagePath map[string]map[string]bool     // "template" => "text/template" => true
 	exports     map[string]map[string]SpotKind // "net/http" => "ListenAndServe" => FuncDecl
 	idents      map[SpotKind]map[string][]Ident
+	docs        map[string]*DocIndex // lang => translated docs
+	signatures  []FuncSig            // sorted by package path and name
+	selectors   map[string]HitList   // "net/http.Handler" => uses of http.Handler, see Refs
+	suggest     suggestTable         // built on first use
+	symbols     symbolTable          // built on first use
 	opts        indexOptions
+
+	// for incremental updates; dirs is nil for an index read from a file
+	dirs         map[string]*dirIndex // dirname => indexed directory
+	deadSnippets int                  // number of snippets of re-indexed directories
 }
 
 func canonical(w string) string { return strings.ToLower(w) }
@@ -991,6 +1160,12 @@ func (c *Corpus) throttle() float64 {
 
 // NewIndex creates a new index for the .go files provided by the corpus.
 func (c *Corpus) NewIndex() *Index {
+	return c.newIndex(nil)
+}
+
+// newIndex creates a new index for the .go files in the directories
+// provided by the corpus; if dirs is not nil, only for those in dirs.
+func (c *Corpus) newIndex(dirs map[string]bool) *Index {
 	// initialize Indexer
 	// (use some reasonably sized maps to start)
 	x := &Indexer{
@@ -1005,15 +1180,22 @@ func (c *Corpus) NewIndex() *Index {
 		packagePath: make(map[string]map[string]bool),
 		exports:     make(map[string]map[string]SpotKind),
 		idents:      make(map[SpotKind]map[string][]Ident, 4),
+		docs:        make(map[string]*DocIndex),
+		trPkgs:      make(map[string]*ast.Package),
+		dirs:        make(map[string]*dirIndex),
+		selectors:   make(map[string]RunList),
 	}
 
 	// index all files in the directories given by dirnames
-	var wg sync.WaitGroup // outstanding ReadDir + visitFile
+	var wg sync.WaitGroup // outstanding directories
 	dirGate := make(chan bool, maxOpenDirs)
 	for dirname := range c.fsDirnames() {
 		if c.IndexDirectory != nil && !c.IndexDirectory(dirname) {
 			continue
 		}
+		if dirs != nil && !dirs[dirname] {
+			continue
+		}
 		dirGate <- true
 		wg.Add(1)
 		go func(dirname string) {
@@ -1025,13 +1207,19 @@ func (c *Corpus) NewIndex() *Index {
 				log.Printf("ReadDir(%q): %v; skipping directory", dirname, err)
 				return // ignore this directory
 			}
+			x.mu.Lock()
+			x.dirs[dirname] = &dirIndex{Sig: dirSignature(list, true)}
+			x.mu.Unlock()
+			var files sync.WaitGroup // outstanding visitFile of dirname
 			for _, fi := range list {
-				wg.Add(1)
+				files.Add(1)
 				go func(fi os.FileInfo) {
-					defer wg.Done()
+					defer files.Done()
 					x.visitFile(dirname, fi)
 				}(fi)
 			}
+			files.Wait()
+			x.indexTranslatedDir(dirname)
 		}(dirname)
 	}
 	wg.Wait()
@@ -1061,22 +1249,21 @@ func (c *Corpus) NewIndex() *Index {
 		x.throttle.Throttle()
 	}
 	x.stats.Words = len(words)
-
-	// reduce the word list {canonical(w), w} into
-	// a list of AltWords runs {canonical(w), {w}}
-	alist := wlist.reduce(lessWordPair, newAltWords)
-
-	// convert alist into a map of alternative spellings
-	alts := make(map[string]*AltWords)
-	for i := 0; i < len(alist); i++ {
-		a := alist[i].(*AltWords)
-		alts[a.Canon] = a
+	alts := newAlts(wlist)
+	selectors := make(map[string]HitList, len(x.selectors))
+	for sel, h := range x.selectors {
+		selectors[sel] = reduce(h)
 	}
 
 	// create text index
-	var suffixes *suffixarray.Index
+	var text []*textIndex
 	if c.IndexFullText {
-		suffixes = suffixarray.New(x.sources.Bytes())
+		text = append(text, &textIndex{
+			fset:     x.fset,
+			src:      x.sources.Bytes(),
+			trigrams: newTrigramIndex(x.fset, x.sources.Bytes(), x.throttle),
+			dirs:     dirSet(x.dirs),
+		})
 	}
 
 	// sort idents by the number of imports of their respective packages
@@ -1085,10 +1272,10 @@ func (c *Corpus) NewIndex() *Index {
 			sort.Sort(byImportCount{ir, x.importCount})
 		}
 	}
+	sort.Sort(sigsByName(x.signatures))
 
 	return &Index{
-		fset:        x.fset,
-		suffixes:    suffixes,
+		text:        text,
 		words:       words,
 		alts:        alts,
 		snippets:    x.snippets,
@@ -1097,18 +1284,17 @@ func (c *Corpus) NewIndex() *Index {
 		packagePath: x.packagePath,
 		exports:     x.exports,
 		idents:      x.idents,
-		opts: indexOptions{
-			Docs:       x.c.IndexDocs,
-			GoCode:     x.c.IndexGoCode,
-			FullText:   x.c.IndexFullText,
-			MaxResults: x.c.MaxResults,
-		},
+		docs:        x.docs,
+		signatures:  x.signatures,
+		selectors:   selectors,
+		dirs:        x.dirs,
+		opts:        c.indexOptions(),
 	}
 }
 
 var ErrFileIndexVersion = errors.New("file index version out of date")
 
-const fileIndexVersion = 3
+const fileIndexVersion = 9
 
 // fileIndex is the subset of Index that's gob-encoded for use by
 // Index.Write and Index.Read.
@@ -1123,6 +1309,10 @@ type fileIndex struct {
 	PackagePath map[string]map[string]bool
 	Exports     map[string]map[string]SpotKind
 	Idents      map[SpotKind]map[string][]Ident
+	Docs        map[string]*DocIndex
+	Signatures  []FuncSig
+	Selectors   map[string]HitList
+	Dirs        map[string]*dirIndex
 	Opts        indexOptions
 }
 
@@ -1137,38 +1327,59 @@ func (x *fileIndex) Read(r io.Reader) error {
 // WriteTo writes the index x to w.
 func (x *Index) WriteTo(w io.Writer) (n int64, err error) {
 	w = countingWriter{&n, w}
-	fulltext := false
-	if x.suffixes != nil {
-		fulltext = true
+	if err := x.writeIdents(w); err != nil {
+		return 0, err
 	}
+	if len(x.text) > 0 {
+		if err := gob.NewEncoder(w).Encode(len(x.text)); err != nil {
+			return 0, err
+		}
+		for _, t := range x.text {
+			if err := t.writeTo(w); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// writeIdents writes the index x without the full text index to w.
+func (x *Index) writeIdents(w io.Writer) error {
 	fx := fileIndex{
 		Version:     fileIndexVersion,
 		Words:       x.words,
 		Alts:        x.alts,
 		Snippets:    x.snippets,
-		Fulltext:    fulltext,
+		Fulltext:    len(x.text) > 0,
 		Stats:       x.stats,
 		ImportCount: x.importCount,
 		PackagePath: x.packagePath,
 		Exports:     x.exports,
 		Idents:      x.idents,
+		Docs:        x.docs,
+		Signatures:  x.signatures,
+		Selectors:   x.selectors,
+		Dirs:        x.dirs,
 		Opts:        x.opts,
 	}
-	if err := fx.Write(w); err != nil {
-		return 0, err
+	return fx.Write(w)
+}
+
+// writeTo writes the full text index t to w.
+func (t *textIndex) writeTo(w io.Writer) error {
+	if err := t.get(); err != nil {
+		return err
 	}
-	if fulltext {
-		encode := func(x interface{}) error {
-			return gob.NewEncoder(w).Encode(x)
-		}
-		if err := x.fset.Write(encode); err != nil {
-			return 0, err
-		}
-		if err := x.suffixes.Write(w); err != nil {
-			return 0, err
-		}
+	encode := func(x interface{}) error {
+		return gob.NewEncoder(w).Encode(x)
 	}
-	return n, nil
+	if err := t.fset.Write(encode); err != nil {
+		return err
+	}
+	if err := encode(t.src); err != nil {
+		return err
+	}
+	return encode(t.trigrams)
 }
 
 // ReadFrom reads the index from r into x; x must not be nil.
@@ -1180,12 +1391,48 @@ func (x *Index) ReadFrom(r io.Reader) (n int64, err error) {
 		r = bufio.NewReader(r)
 	}
 	r = countingReader{&n, r.(byteReader)}
+	fulltext, err := x.readIdents(r)
+	if err != nil {
+		return n, err
+	}
+	if fulltext {
+		var count int
+		if err := gob.NewDecoder(r).Decode(&count); err != nil {
+			return n, err
+		}
+		for i := 0; i < count; i++ {
+			t := new(textIndex)
+			if err := t.readFrom(r); err != nil {
+				return n, err
+			}
+			x.text = append(x.text, t)
+		}
+		if count == 1 {
+			// the text index of all indexed directories
+			x.text[0].dirs = dirSet(x.dirs)
+		}
+	}
+	return n, nil
+}
+
+// dirSet returns the set of the directories of dirs.
+func dirSet(dirs map[string]*dirIndex) map[string]bool {
+	set := make(map[string]bool, len(dirs))
+	for dirname := range dirs {
+		set[dirname] = true
+	}
+	return set
+}
+
+// readIdents reads the index without the full text index from r into
+// x, and reports whether a full text index follows.
+func (x *Index) readIdents(r io.Reader) (fulltext bool, err error) {
 	var fx fileIndex
 	if err := fx.Read(r); err != nil {
-		return n, err
+		return false, err
 	}
 	if fx.Version != fileIndexVersion {
-		return 0, ErrFileIndexVersion
+		return false, ErrFileIndexVersion
 	}
 	x.words = fx.Words
 	x.alts = fx.Alts
@@ -1195,21 +1442,29 @@ func (x *Index) ReadFrom(r io.Reader) (n int64, err error) {
 	x.packagePath = fx.PackagePath
 	x.exports = fx.Exports
 	x.idents = fx.Idents
+	x.docs = fx.Docs
+	x.signatures = fx.Signatures
+	x.selectors = fx.Selectors
+	x.dirs = fx.Dirs
 	x.opts = fx.Opts
-	if fx.Fulltext {
-		x.fset = token.NewFileSet()
-		decode := func(x interface{}) error {
-			return gob.NewDecoder(r).Decode(x)
-		}
-		if err := x.fset.Read(decode); err != nil {
-			return n, err
-		}
-		x.suffixes = new(suffixarray.Index)
-		if err := x.suffixes.Read(r); err != nil {
-			return n, err
-		}
+	return fx.Fulltext, nil
+}
+
+// readFrom reads the full text index from r into t. r must implement
+// io.ByteReader, such that no more than the index is read.
+func (t *textIndex) readFrom(r io.Reader) error {
+	t.fset = token.NewFileSet()
+	decode := func(x interface{}) error {
+		return gob.NewDecoder(r).Decode(x)
 	}
-	return n, nil
+	if err := t.fset.Read(decode); err != nil {
+		return err
+	}
+	if err := decode(&t.src); err != nil {
+		return err
+	}
+	t.trigrams = new(trigramIndex)
+	return decode(t.trigrams)
 }
 
 // Stats returns index statistics.
@@ -1358,45 +1613,27 @@ type FileLines struct {
 
 // LookupRegexp returns the number of matches and the matches where a regular
 // expression r is found in the full text index. At most n matches are
-// returned (thus found <= n).
+// returned (thus found <= n). Only the files which may contain matches
+// according to the trigram index are searched.
 //
 func (x *Index) LookupRegexp(r *regexp.Regexp, n int) (found int, result []FileLines) {
-	if x.suffixes == nil || n <= 0 {
+	if len(x.text) == 0 || n <= 0 {
 		return
 	}
 	// n > 0
 
 	var list positionList
-	// FindAllIndex may returns matches that span across file boundaries.
-	// Such matches are unlikely, buf after eliminating them we may end up
-	// with fewer than n matches. If we don't have enough at the end, redo
-	// the search with an increased value n1, but only if FindAllIndex
-	// returned all the requested matches in the first place (if it
-	// returned fewer than that there cannot be more).
-	for n1 := n; found < n; n1 += n - found {
-		found = 0
-		matches := x.suffixes.FindAllIndex(r, n1)
-		// compute files, exclude matches that span file boundaries,
-		// and map offsets to file-local offsets
-		list = make(positionList, len(matches))
-		for _, m := range matches {
-			// by construction, an offset corresponds to the Pos value
-			// for the file set - use it to get the file and line
-			p := token.Pos(m[0])
-			if file := x.fset.File(p); file != nil {
-				if base := file.Base(); base <= m[1] && m[1] <= base+file.Size() {
-					// match [m[0], m[1]) is within the file boundaries
-					list[found].filename = file.Name()
-					list[found].line = file.Line(p)
-					found++
-				}
-			}
-		}
-		if found == n || len(matches) < n1 {
-			// found all matches or there's no chance to find more
+	for _, t := range x.text {
+		if len(list) == n {
 			break
 		}
+		if err := t.get(); err != nil {
+			log.Printf("loading full text index: %v", err)
+			continue
+		}
+		list = t.lookupRegexp(r, n-len(list), list)
 	}
+	found = len(list)
 	list = list[0:found]
 	sort.Sort(list) // sort by filename
 
@@ -1422,6 +1659,37 @@ func (x *Index) LookupRegexp(r *regexp.Regexp, n int) (found int, result []FileL
 	return
 }
 
+// lookupRegexp appends at most n matches of r in t to list.
+func (t *textIndex) lookupRegexp(r *regexp.Regexp, n int, list positionList) positionList {
+	var bases []int
+	if t.trigrams != nil {
+		bases = t.trigrams.candidates(r)
+	} else {
+		t.fset.Iterate(func(f *token.File) bool {
+			bases = append(bases, f.Base())
+			return true
+		})
+	}
+
+	// by construction, an offset of the sources corresponds to the
+	// Pos value for the file set - use it to get the file and line
+	src := t.src
+	for _, base := range bases {
+		file := t.fset.File(token.Pos(base))
+		if file == nil {
+			continue
+		}
+		for _, m := range r.FindAllIndex(src[base:base+file.Size()], n) {
+			list = append(list, positionList{{file.Name(), file.Line(token.Pos(base + m[0]))}}...)
+			n--
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return list
+}
+
 // InvalidateIndex should be called whenever any of the file systems
 // under godoc's observation change so that the indexer is kicked on.
 func (c *Corpus) invalidateIndex() {
@@ -1462,14 +1730,17 @@ func (c *Corpus) fsDirnames() <-chan string {
 }
 
 // CompatibleWith reports whether the Index x is compatible with the corpus
-// indexing options set in c.
+// indexing options set in c, and with its translations if translated
+// docs are indexed. An index read from a sharded index file was also
+// checked to index the directories of c when read; the later changes
+// are those of the updates of the index.
 func (x *Index) CompatibleWith(c *Corpus) bool {
-	return x.opts.Docs == c.IndexDocs &&
-		x.opts.GoCode == c.IndexGoCode &&
-		x.opts.FullText == c.IndexFullText &&
-		x.opts.MaxResults == c.MaxResults
+	return x.opts == c.indexOptions()
 }
 
+// readIndex sets the current index from the files matching filenames.
+// The files of a sharded index are kept open for the lazily loaded full
+// text indices; they are closed when no longer referenced.
 func (c *Corpus) readIndex(filenames string) error {
 	matches, err := filepath.Glob(filenames)
 	if err != nil {
@@ -1478,22 +1749,55 @@ func (c *Corpus) readIndex(filenames string) error {
 		return fmt.Errorf("no index files match %q", filenames)
 	}
 	sort.Strings(matches) // make sure files are in the right order
-	files := make([]io.Reader, 0, len(matches))
+	files := make([]*os.File, 0, len(matches))
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
 	for _, filename := range matches {
 		f, err := os.Open(filename)
 		if err != nil {
+			closeAll()
 			return err
 		}
-		defer f.Close()
 		files = append(files, f)
 	}
-	return c.ReadIndexFrom(io.MultiReader(files...))
+	m, err := newMultiReaderAt(files)
+	if err == nil {
+		err = c.ReadIndexFrom(io.NewSectionReader(m, 0, m.Size()))
+	}
+	if err != nil {
+		closeAll()
+	}
+	return err
 }
 
-// ReadIndexFrom sets the current index from the serialized version found in r.
+// ReadIndexFrom sets the current index from the serialized version found in r,
+// written by Index.WriteTo or Corpus.WriteIndex. The full text index of a
+// sharded index is read on first use if r implements io.ReaderAt; r must
+// then remain readable.
 func (c *Corpus) ReadIndexFrom(r io.Reader) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(indexMagic)); string(magic) == indexMagic {
+		ra, ok := r.(io.ReaderAt)
+		if !ok {
+			data, err := ioutil.ReadAll(br)
+			if err != nil {
+				return err
+			}
+			ra = bytes.NewReader(data)
+		}
+		x, err := c.readShardedIndex(ra)
+		if err != nil {
+			return err
+		}
+		c.searchIndex.Set(x)
+		return nil
+	}
+
 	x := new(Index)
-	if _, err := x.ReadFrom(r); err != nil {
+	if _, err := x.ReadFrom(br); err != nil {
 		return err
 	}
 	if !x.CompatibleWith(c) {
@@ -1508,7 +1812,8 @@ func (c *Corpus) UpdateIndex() {
 		log.Printf("updating index...")
 	}
 	start := time.Now()
-	index := c.NewIndex()
+	old, _ := c.CurrentIndex()
+	index := c.updateIndex(old)
 	stop := time.Now()
 	c.searchIndex.Set(index)
 	if c.Verbose {
@@ -1540,19 +1845,28 @@ func (c *Corpus) RunIndexer() {
 		return
 	}
 
-	// Repeatedly update the package directory tree and index.
-	// TODO(bgarcia): Use fsnotify to only update when notified of a filesystem change.
+	// Repeatedly update the package directory tree and index, and
+	// update the index when notified of a file system change by
+	// RunWatcher, which updates the directory tree.
+	fsTree := true
 	for {
-		c.initFSTree()
+		if fsTree {
+			c.initFSTree()
+		}
 		c.UpdateIndex()
-		if c.IndexInterval < 0 {
-			return
+		var timer <-chan time.Time // nil if not reindexing periodically
+		switch {
+		case c.IndexInterval == 0:
+			timer = time.After(5 * time.Minute) // by default, reindex every 5 minutes
+		case c.IndexInterval > 0:
+			timer = time.After(c.IndexInterval)
 		}
-		delay := 5 * time.Minute // by default, reindex every 5 minutes
-		if c.IndexInterval > 0 {
-			delay = c.IndexInterval
+		select {
+		case <-timer:
+			fsTree = true
+		case <-c.indexSignal:
+			fsTree = false
 		}
-		time.Sleep(delay)
 	}
 }
 
@@ -59,10 +59,21 @@ func extractMetadata(b []byte) (meta Metadata, tail []byte, err error) {
 // UpdateMetadata scans $GOROOT/doc for HTML files, reads their metadata,
 // and updates the DocMetadata map.
 func (c *Corpus) updateMetadata() {
+	c.docMetadata.Set(scanMetadata(c.fs))
+
+	// rescan the /doc trees of other languages on next access
+	c.langMu.Lock()
+	c.langMetadata = nil
+	c.langMu.Unlock()
+}
+
+// scanMetadata scans /doc in fs for HTML files, and returns the
+// mapping from paths to their metadata.
+func scanMetadata(fs vfs.FileSystem) map[string]*Metadata {
 	metadata := make(map[string]*Metadata)
 	var scan func(string) // scan is recursive
 	scan = func(dir string) {
-		fis, err := c.fs.ReadDir(dir)
+		fis, err := fs.ReadDir(dir)
 		if err != nil {
 			log.Println("updateMetadata:", err)
 			return
@@ -77,7 +88,7 @@ func (c *Corpus) updateMetadata() {
 				continue
 			}
 			// Extract metadata from the file.
-			b, err := vfs.ReadFile(c.fs, name)
+			b, err := vfs.ReadFile(fs, name)
 			if err != nil {
 				log.Printf("updateMetadata %s: %v", name, err)
 				continue
@@ -99,15 +110,24 @@ func (c *Corpus) updateMetadata() {
 		}
 	}
 	scan("/doc")
-	c.docMetadata.Set(metadata)
+	return metadata
 }
 
 // MetadataFor returns the *Metadata for a given relative path or nil if none
-// exists.
+// exists. The optional lang argument is the request language.
 //
-func (c *Corpus) MetadataFor(relpath string) *Metadata {
+func (c *Corpus) MetadataFor(relpath string, lang ...string) *Metadata {
+	if len(lang) > 0 && c.langDocFS(lang[0]) != nil {
+		return lookupMetadata(c.metadataFor(lang[0]), relpath)
+	}
 	if m, _ := c.docMetadata.Get(); m != nil {
-		meta := m.(map[string]*Metadata)
+		return lookupMetadata(m.(map[string]*Metadata), relpath)
+	}
+	return nil
+}
+
+func lookupMetadata(meta map[string]*Metadata, relpath string) *Metadata {
+	if meta != nil {
 		// If metadata for this relpath exists, return it.
 		if p := meta[relpath]; p != nil {
 			return p
@@ -123,6 +143,63 @@ func (c *Corpus) MetadataFor(relpath string) *Metadata {
 	return nil
 }
 
+// metadataFor returns the metadata of the /doc tree of lang, which
+// must have a /doc tree, see langDocFS.
+func (c *Corpus) metadataFor(lang string) map[string]*Metadata {
+	fs := c.fsFor(lang)
+
+	c.langMu.Lock()
+	defer c.langMu.Unlock()
+
+	meta, ok := c.langMetadata[lang]
+	if !ok {
+		meta = scanMetadata(fs)
+		if c.langMetadata == nil {
+			c.langMetadata = make(map[string]map[string]*Metadata)
+		}
+		c.langMetadata[lang] = meta
+	}
+	return meta
+}
+
+// fsFor returns the file system with the /doc tree of lang,
+// see DocumentFS.
+func (c *Corpus) fsFor(lang string) vfs.FileSystem {
+	if fs := c.langDocFS(lang); fs != nil {
+		return fs
+	}
+	return c.fs
+}
+
+// langDocFS returns the file system with the /doc tree of lang,
+// or nil for the default /doc tree. Only the languages with a /doc
+// tree are cached: lang comes from the request.
+func (c *Corpus) langDocFS(lang string) vfs.FileSystem {
+	if lang == "" || c.DocumentFS == nil {
+		return nil
+	}
+
+	c.langMu.Lock()
+	defer c.langMu.Unlock()
+
+	fs, ok := c.langFS[lang]
+	if !ok {
+		docfs := c.DocumentFS(lang)
+		if docfs == nil {
+			return nil
+		}
+		ns := make(vfs.NameSpace)
+		ns.Bind("/", c.fs, "/", vfs.BindReplace)
+		ns.Bind("/doc", docfs, "/", vfs.BindBefore) // fallback to the default /doc tree
+		fs = ns
+		if c.langFS == nil {
+			c.langFS = make(map[string]vfs.FileSystem)
+		}
+		c.langFS[lang] = fs
+	}
+	return fs
+}
+
 // refreshMetadata sends a signal to update DocMetadata. If a refresh is in
 // progress the metadata will be refreshed again afterward.
 //
@@ -0,0 +1,81 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"golang.org/x/tools/godoc/vfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestLangDocument(t *testing.T) {
+	c := NewCorpus(mapfs.New(map[string]string{
+		"doc/install.html": `<!--{"Title": "Getting Started", "Path": "/doc/install"}-->` + "\n<p>Install Go</p>",
+		"doc/faq.html":     `<!--{"Title": "FAQ"}-->` + "\n<p>FAQ</p>",
+	}))
+	c.DocumentFS = func(lang string) vfs.FileSystem {
+		if lang != "zh_CN" {
+			return nil
+		}
+		return mapfs.New(map[string]string{
+			"install.html": `<!--{"Title": "安装", "Path": "/doc/install"}-->` + "\n<p>安装 Go</p>",
+		})
+	}
+	c.updateMetadata()
+
+	for _, tc := range []struct {
+		path, lang, title string
+	}{
+		{"/doc/install", "", "Getting Started"},
+		{"/doc/install", "zh_CN", "安装"},
+		{"/doc/install", "ja_JP", "Getting Started"},
+		{"/doc/faq.html", "zh_CN", "FAQ"}, // fallback to the default /doc tree
+	} {
+		m := c.MetadataFor(tc.path, tc.lang)
+		if m == nil || m.Title != tc.title {
+			t.Errorf("MetadataFor(%q, %q) = %v; want title %q", tc.path, tc.lang, m, tc.title)
+		}
+	}
+
+	p := NewPresentation(c)
+	p.GodocHTML = template.Must(template.New("godoc").Parse("{{.Title}}|{{printf \"%s\" .Body}}"))
+	p.TemplatesFor = func(lang string) *Templates {
+		if lang != "zh_CN" {
+			return nil
+		}
+		return &Templates{
+			GodocHTML: template.Must(template.New("godoc").Parse("[zh] {{.Title}}|{{printf \"%s\" .Body}}")),
+		}
+	}
+	for _, tc := range []struct {
+		url, want string
+	}{
+		{"/doc/install", "Getting Started|\n<p>Install Go</p>"},
+		{"/doc/install?lang=zh_CN", "[zh] 安装|\n<p>安装 Go</p>"},
+		{"/doc/install?lang=ja_JP", "Getting Started|\n<p>Install Go</p>"},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", tc.url, nil)
+		p.ServeFile(w, r)
+		if got := w.Body.String(); !strings.Contains(got, tc.want) {
+			t.Errorf("GET %s = %q; want %q", tc.url, got, tc.want)
+		}
+	}
+
+	// the languages without translation are not cached
+	if n := len(c.langFS); n != 1 {
+		t.Errorf("%d /doc trees cached; want 1", n)
+	}
+	if n := len(c.langMetadata); n != 1 {
+		t.Errorf("%d metadata maps cached; want 1", n)
+	}
+	if n := len(p.langTemplates); n != 1 {
+		t.Errorf("%d templates cached; want 1", n)
+	}
+}
@@ -15,6 +15,7 @@ type Page struct {
 	Tabtitle string
 	Subtitle string
 	Query    string
+	Lang     string // request language, if any
 	Body     []byte
 
 	// filled in by servePage
@@ -30,14 +31,16 @@ func (p *Presentation) ServePage(w http.ResponseWriter, page Page) {
 	page.SearchBox = p.Corpus.IndexEnabled
 	page.Playground = p.ShowPlayground
 	page.Version = runtime.Version()
-	applyTemplateToResponseWriter(w, p.GodocHTML, page)
+	applyTemplateToResponseWriter(w, p.templates(page.Lang).GodocHTML, page)
 }
 
 func (p *Presentation) ServeError(w http.ResponseWriter, r *http.Request, relpath string, err error) {
+	lang := r.FormValue("lang")
 	w.WriteHeader(http.StatusNotFound)
 	p.ServePage(w, Page{
 		Title:    "File " + relpath,
 		Subtitle: relpath,
-		Body:     applyTemplate(p.ErrorHTML, "errorHTML", err), // err may contain an absolute path!
+		Lang:     lang,
+		Body:     applyTemplate(p.templates(lang).ErrorHTML, "errorHTML", err), // err may contain an absolute path!
 	})
 }
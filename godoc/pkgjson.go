@@ -0,0 +1,337 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the JSON form of the package documentation,
+// served at /api/pkg/ and for m=json.
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A PackageJSON is the documentation of a package, as served in JSON.
+// The docs are translated to Lang, if any; their original texts are
+// in the English fields, if different.
+type PackageJSON struct {
+	ImportPath string                `json:"importPath"`
+	Name       string                `json:"name,omitempty"`
+	Lang       string                `json:"lang,omitempty"`
+	IsMain     bool                  `json:"isMain,omitempty"`
+	Doc        string                `json:"doc,omitempty"`
+	English    string                `json:"english,omitempty"`
+	Consts     []*ValueJSON          `json:"consts,omitempty"`
+	Vars       []*ValueJSON          `json:"vars,omitempty"`
+	Types      []*TypeJSON           `json:"types,omitempty"`
+	Funcs      []*FuncJSON           `json:"funcs,omitempty"`
+	Examples   []*ExampleJSON        `json:"examples,omitempty"`
+	Notes      map[string][]NoteJSON `json:"notes,omitempty"`
+	Dirs       []DirJSON             `json:"dirs,omitempty"`
+}
+
+// A PosJSON is a source position.
+type PosJSON struct {
+	Filename string `json:"filename"` // e.g. "/src/fmt/print.go"
+	Line     int    `json:"line"`
+}
+
+// A ValueJSON is a const or var declaration.
+type ValueJSON struct {
+	Names   []string `json:"names"`
+	Doc     string   `json:"doc,omitempty"`
+	English string   `json:"english,omitempty"`
+	Decl    string   `json:"decl"`
+	Pos     PosJSON  `json:"pos"`
+}
+
+// A FuncJSON is a func or method declaration.
+type FuncJSON struct {
+	Name    string  `json:"name"`
+	Recv    string  `json:"recv,omitempty"`
+	Doc     string  `json:"doc,omitempty"`
+	English string  `json:"english,omitempty"`
+	Decl    string  `json:"decl"`
+	Pos     PosJSON `json:"pos"`
+}
+
+// A FieldJSON is a struct field or an interface method.
+type FieldJSON struct {
+	Names []string `json:"names,omitempty"` // empty for embedded fields
+	Type  string   `json:"type"`
+	Tag   string   `json:"tag,omitempty"`
+	Doc   string   `json:"doc,omitempty"` // original comment
+}
+
+// A TypeJSON is a type declaration, with its associated declarations.
+type TypeJSON struct {
+	Name    string       `json:"name"`
+	Doc     string       `json:"doc,omitempty"`
+	English string       `json:"english,omitempty"`
+	Decl    string       `json:"decl"`
+	Pos     PosJSON      `json:"pos"`
+	Fields  []*FieldJSON `json:"fields,omitempty"`
+	Consts  []*ValueJSON `json:"consts,omitempty"`
+	Vars    []*ValueJSON `json:"vars,omitempty"`
+	Funcs   []*FuncJSON  `json:"funcs,omitempty"`
+	Methods []*FuncJSON  `json:"methods,omitempty"`
+}
+
+// An ExampleJSON is an example of the package.
+type ExampleJSON struct {
+	Name   string `json:"name"`
+	Doc    string `json:"doc,omitempty"`
+	Code   string `json:"code"`
+	Output string `json:"output,omitempty"`
+}
+
+// A NoteJSON is a marked comment, e.g. BUG(uid): body.
+type NoteJSON struct {
+	UID  string  `json:"uid"`
+	Body string  `json:"body"`
+	Pos  PosJSON `json:"pos"`
+}
+
+// A DirJSON is a sub-directory of the package directory.
+type DirJSON struct {
+	Path     string `json:"path"`
+	Synopsis string `json:"synopsis,omitempty"`
+}
+
+// packageJSON returns the JSON form of info.
+func (p *Presentation) packageJSON(info *PageInfo, importPath string) *PackageJSON {
+	pkg := &PackageJSON{ImportPath: importPath, IsMain: info.IsMain}
+	if info.Dirs != nil {
+		for _, d := range info.Dirs.List {
+			if d.HasPkg {
+				pkg.Dirs = append(pkg.Dirs, DirJSON{pathpkgJoin(importPath, d.Path), d.Synopsis})
+			}
+		}
+	}
+	pdoc := info.PDoc
+	if pdoc == nil {
+		return pkg
+	}
+
+	english := make(map[string]string) // original docs by id
+	if orig := info.OrigPDoc; orig != nil && orig != pdoc {
+		docIds(orig, func(id, text string) { english[id] = text })
+		// the translated package is a copy, even without translation
+		docIds(pdoc, func(id, text string) {
+			if s, ok := english[id]; ok && s != text {
+				pkg.Lang = info.Lang
+			}
+		})
+	}
+	englishOf := func(id, text string) string {
+		if s, ok := english[id]; ok && s != text {
+			return s
+		}
+		return ""
+	}
+
+	pos := func(n ast.Node) PosJSON {
+		if n == nil || info.FSet == nil {
+			return PosJSON{}
+		}
+		position := info.FSet.Position(n.Pos())
+		return PosJSON{position.Filename, position.Line}
+	}
+	decl := func(n ast.Node) string {
+		if n == nil {
+			return ""
+		}
+		return p.nodeFunc(info, n)
+	}
+	values := func(list []*doc.Value) []*ValueJSON {
+		var values []*ValueJSON
+		for _, v := range list {
+			values = append(values, &ValueJSON{
+				Names:   v.Names,
+				Doc:     v.Doc,
+				English: englishOf(v.Names[0], v.Doc),
+				Decl:    decl(v.Decl),
+				Pos:     pos(v.Decl),
+			})
+		}
+		return values
+	}
+	funcs := func(list []*doc.Func, typeName string) []*FuncJSON {
+		var funcs []*FuncJSON
+		for _, f := range list {
+			id := f.Name
+			if f.Recv != "" && typeName != "" {
+				id = typeName + "." + f.Name
+			}
+			funcs = append(funcs, &FuncJSON{
+				Name:    f.Name,
+				Recv:    f.Recv,
+				Doc:     f.Doc,
+				English: englishOf(id, f.Doc),
+				Decl:    decl(f.Decl),
+				Pos:     pos(f.Decl),
+			})
+		}
+		return funcs
+	}
+
+	pkg.Name = pdoc.Name
+	pkg.Doc = pdoc.Doc
+	pkg.English = englishOf("", pdoc.Doc)
+	pkg.Consts = values(pdoc.Consts)
+	pkg.Vars = values(pdoc.Vars)
+	pkg.Funcs = funcs(pdoc.Funcs, "")
+	for _, t := range pdoc.Types {
+		pkg.Types = append(pkg.Types, &TypeJSON{
+			Name:    t.Name,
+			Doc:     t.Doc,
+			English: englishOf(t.Name, t.Doc),
+			Decl:    decl(t.Decl),
+			Pos:     pos(t.Decl),
+			Fields:  p.fieldsJSON(info, t.Decl),
+			Consts:  values(t.Consts),
+			Vars:    values(t.Vars),
+			Funcs:   funcs(t.Funcs, ""),
+			Methods: funcs(t.Methods, t.Name),
+		})
+	}
+
+	for _, eg := range info.Examples {
+		code := p.nodeFunc(info, &printer.CommentedNode{Node: eg.Code, Comments: eg.Comments})
+		if n := len(code); n >= 2 && code[0] == '{' && code[n-1] == '}' {
+			// function body, as in example_textFunc
+			code = strings.Replace(code[1:n-1], "\n    ", "\n", -1)
+			if loc := exampleOutputRx.FindStringIndex(code); loc != nil {
+				code = code[:loc[0]]
+			}
+			code = strings.TrimSpace(code)
+		}
+		pkg.Examples = append(pkg.Examples, &ExampleJSON{eg.Name, eg.Doc, code, eg.Output})
+	}
+
+	if len(info.Notes) > 0 {
+		pkg.Notes = make(map[string][]NoteJSON)
+		for marker, notes := range info.Notes {
+			for _, n := range notes {
+				position := token.Position{}
+				if info.FSet != nil {
+					position = info.FSet.Position(n.Pos)
+				}
+				pkg.Notes[marker] = append(pkg.Notes[marker], NoteJSON{n.UID, n.Body, PosJSON{position.Filename, position.Line}})
+			}
+		}
+	}
+	return pkg
+}
+
+// fieldsJSON returns the fields of the struct, or the methods of the
+// interface, declared by decl.
+func (p *Presentation) fieldsJSON(info *PageInfo, decl *ast.GenDecl) []*FieldJSON {
+	if decl == nil {
+		return nil
+	}
+	var list *ast.FieldList
+	for _, spec := range decl.Specs {
+		if ts, ok := spec.(*ast.TypeSpec); ok {
+			switch t := ts.Type.(type) {
+			case *ast.StructType:
+				list = t.Fields
+			case *ast.InterfaceType:
+				list = t.Methods
+			}
+		}
+	}
+	if list == nil {
+		return nil
+	}
+	var fields []*FieldJSON
+	for _, f := range list.List {
+		field := &FieldJSON{Type: p.nodeFunc(info, f.Type)}
+		for _, name := range f.Names {
+			field.Names = append(field.Names, name.Name)
+		}
+		if f.Tag != nil {
+			field.Tag = f.Tag.Value
+		}
+		if f.Doc != nil {
+			field.Doc = f.Doc.Text()
+		} else if f.Comment != nil {
+			field.Doc = f.Comment.Text()
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// docIds calls f for each documented identifier of pkg, with its
+// id as used by the translations ("" for the package, "T.M" for
+// the methods) and its doc.
+func docIds(pkg *doc.Package, f func(id, text string)) {
+	f("", pkg.Doc)
+	values := func(list []*doc.Value) {
+		for _, v := range list {
+			f(v.Names[0], v.Doc)
+		}
+	}
+	values(pkg.Consts)
+	values(pkg.Vars)
+	for _, fn := range pkg.Funcs {
+		f(fn.Name, fn.Doc)
+	}
+	for _, t := range pkg.Types {
+		f(t.Name, t.Doc)
+		values(t.Consts)
+		values(t.Vars)
+		for _, fn := range t.Funcs {
+			f(fn.Name, fn.Doc)
+		}
+		for _, fn := range t.Methods {
+			f(t.Name+"."+fn.Name, fn.Doc)
+		}
+	}
+}
+
+func pathpkgJoin(importPath, dir string) string {
+	if importPath == "" || importPath == "." {
+		return dir
+	}
+	return importPath + "/" + dir
+}
+
+// servePackageJSON serves info as JSON.
+func (p *Presentation) servePackageJSON(w http.ResponseWriter, info *PageInfo, importPath string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if info.Err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": info.Err.Error()})
+		return
+	}
+	pkg := p.packageJSON(info, importPath)
+	for _, list := range pkg.Notes {
+		sort.Sort(notesByPos(list))
+	}
+	data, err := json.MarshalIndent(pkg, "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+type notesByPos []NoteJSON
+
+func (s notesByPos) Len() int      { return len(s) }
+func (s notesByPos) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s notesByPos) Less(i, j int) bool {
+	if s[i].Pos.Filename != s[j].Pos.Filename {
+		return s[i].Pos.Filename < s[j].Pos.Filename
+	}
+	return s[i].Pos.Line < s[j].Pos.Line
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const pkgJSONSrc = `// Package foo is an example.
+package foo
+
+const Pi = 3.1415
+
+// Foo is stuff.
+type Foo struct {
+	// Name is the name.
+	Name string ` + "`json:\"name\"`" + `
+	Bar
+}
+
+// New returns a Foo.
+func New() *Foo {
+	return new(Foo)
+}
+
+// Len returns the length.
+func (f *Foo) Len() int { return 0 }
+`
+
+func TestPackageJSON(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "/src/foo/foo.go", pkgJSONSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, _ := ast.NewPackage(fset, map[string]*ast.File{"foo.go": file}, poorMansImporter, nil)
+	orig := doc.New(pkg, "foo", 0)
+	tr := copyDocPackage(orig)
+	tr.Doc = "包 foo 是一个例子.\n"
+	tr.Types[0].Doc = "Foo 是一个类型.\n"
+
+	p := NewPresentation(newCorpus(t))
+	info := &PageInfo{Lang: "zh_CN", FSet: fset, PDoc: tr, OrigPDoc: orig}
+	got := p.packageJSON(info, "foo")
+
+	if got.ImportPath != "foo" || got.Name != "foo" || got.Lang != "zh_CN" {
+		t.Errorf("got package %q (%q, lang %q)", got.ImportPath, got.Name, got.Lang)
+	}
+	if got.Doc != "包 foo 是一个例子.\n" || got.English != "Package foo is an example.\n" {
+		t.Errorf("got doc %q, english %q", got.Doc, got.English)
+	}
+	if len(got.Consts) != 1 || got.Consts[0].Names[0] != "Pi" || got.Consts[0].Decl != "const Pi = 3.1415" {
+		t.Errorf("got consts %+v", got.Consts)
+	}
+	if len(got.Types) != 1 {
+		t.Fatalf("got types %+v", got.Types)
+	}
+	typ := got.Types[0]
+	if typ.Name != "Foo" || typ.Doc != "Foo 是一个类型.\n" || typ.English != "Foo is stuff.\n" {
+		t.Errorf("got type %+v", typ)
+	}
+	if typ.Pos.Filename != "/src/foo/foo.go" || typ.Pos.Line != 7 {
+		t.Errorf("got type position %+v", typ.Pos)
+	}
+	if len(typ.Fields) != 2 ||
+		typ.Fields[0].Names[0] != "Name" || typ.Fields[0].Type != "string" ||
+		typ.Fields[0].Tag != "`json:\"name\"`" || typ.Fields[0].Doc != "Name is the name.\n" ||
+		len(typ.Fields[1].Names) != 0 || typ.Fields[1].Type != "Bar" {
+		t.Errorf("got fields %+v", typ.Fields)
+	}
+	if len(typ.Funcs) != 1 || typ.Funcs[0].Decl != "func New() *Foo" || typ.Funcs[0].English != "" {
+		t.Errorf("got type funcs %+v", typ.Funcs)
+	}
+	if len(typ.Methods) != 1 || typ.Methods[0].Name != "Len" || typ.Methods[0].Recv != "*Foo" {
+		t.Errorf("got methods %+v", typ.Methods)
+	}
+
+	// without translation
+	got = p.packageJSON(&PageInfo{FSet: fset, PDoc: orig}, "foo")
+	if got.Lang != "" || got.Doc != "Package foo is an example.\n" || got.English != "" {
+		t.Errorf("got lang %q, doc %q, english %q", got.Lang, got.Doc, got.English)
+	}
+	got = p.packageJSON(&PageInfo{Lang: "zh_CN", FSet: fset, PDoc: copyDocPackage(orig), OrigPDoc: orig}, "foo")
+	if got.Lang != "" || got.English != "" {
+		t.Errorf("untranslated copy: got lang %q, english %q", got.Lang, got.English)
+	}
+
+	// errors are served as JSON
+	req, _ := http.NewRequest("GET", "/api/pkg/nonexistent/", nil)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+		t.Errorf("/api/pkg/nonexistent/: status %d, %s", w.Code, w.Body)
+	}
+}
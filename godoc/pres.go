@@ -16,15 +16,8 @@ import (
 // SearchResultFunc functions return an HTML body for displaying search results.
 type SearchResultFunc func(p *Presentation, result SearchResult) []byte
 
-// Presentation generates output from a corpus.
-type Presentation struct {
-	Corpus *Corpus
-
-	mux        *http.ServeMux
-	fileServer http.Handler
-	cmdHandler handlerServer
-	pkgHandler handlerServer
-
+// Templates is the set of templates of a presentation.
+type Templates struct {
 	CallGraphHTML,
 	DirlistHTML,
 	ErrorHTML,
@@ -40,6 +33,19 @@ type Presentation struct {
 	SearchTxtHTML,
 	SearchText,
 	SearchDescXML *template.Template
+}
+
+// Presentation generates output from a corpus.
+type Presentation struct {
+	Corpus *Corpus
+
+	mux        *http.ServeMux
+	fileServer http.Handler
+	cmdHandler handlerServer
+	pkgHandler handlerServer
+	apiHandler handlerServer
+
+	Templates // templates of the default language
 
 	// TabWidth optionally specifies the tab width.
 	TabWidth int
@@ -89,9 +95,36 @@ type Presentation struct {
 	// body for displaying search results.
 	SearchResults []SearchResultFunc
 
+	// Lang optionally specifies the language of the template messages
+	// and dates, e.g. "zh_CN".
+	Lang string
+
+	// TranslateMessage optionally specifies a function to translate the
+	// template message msg into lang, used by the "T" template function.
+	// It returns msg if there is no translation.
+	TranslateMessage func(lang, msg string) string
+
+	// TemplatesFor optionally specifies a function returning the templates
+	// of the request language lang (the "lang" form value), or nil to use
+	// the default templates. The non-nil results are cached per
+	// language until ResetTemplates, so it must return nil for the
+	// languages without translation.
+	TemplatesFor func(lang string) *Templates
+
+	// PackageHeader optionally specifies a function returning an HTML
+	// fragment inserted before the package documentation page.
+	PackageHeader func(info *PageInfo) []byte
+
+	// PackageFooter optionally specifies a function returning an HTML
+	// fragment appended to the package documentation page.
+	PackageFooter func(info *PageInfo) []byte
+
 	initFuncMapOnce sync.Once
 	funcMap         template.FuncMap
 	templateFuncs   template.FuncMap
+
+	langTemplatesMu sync.Mutex
+	langTemplates   map[string]*Templates // cached results of TemplatesFor
 }
 
 // NewPresentation returns a new Presentation from a corpus.
@@ -128,14 +161,56 @@ func NewPresentation(c *Corpus) *Presentation {
 		fsRoot:  "/src",
 		exclude: []string{"/src/cmd"},
 	}
+	p.apiHandler = handlerServer{
+		p:       p,
+		c:       c,
+		pattern: "/api/pkg/",
+		fsRoot:  "/src",
+		mode:    JSON,
+	}
 	p.cmdHandler.registerWithMux(p.mux)
 	p.pkgHandler.registerWithMux(p.mux)
+	p.apiHandler.registerWithMux(p.mux)
 	p.mux.HandleFunc("/", p.ServeFile)
 	p.mux.HandleFunc("/search", p.HandleSearch)
+	p.mux.HandleFunc("/search/suggest", p.HandleSuggest)
+	p.mux.HandleFunc("/refs/", p.HandleRefs)
+	p.mux.HandleFunc("/symbols/", p.HandleSymbols)
 	p.mux.HandleFunc("/opensearch.xml", p.serveSearchDesc)
 	return p
 }
 
+// templates returns the templates of the request language lang.
+func (p *Presentation) templates(lang string) *Templates {
+	if lang == "" || p.TemplatesFor == nil {
+		return &p.Templates
+	}
+
+	p.langTemplatesMu.Lock()
+	defer p.langTemplatesMu.Unlock()
+
+	t, ok := p.langTemplates[lang]
+	if !ok {
+		t = p.TemplatesFor(lang)
+		if t == nil {
+			return &p.Templates // not cached: lang comes from the request
+		}
+		if p.langTemplates == nil {
+			p.langTemplates = make(map[string]*Templates)
+		}
+		p.langTemplates[lang] = t
+	}
+	return t
+}
+
+// ResetTemplates drops the cached results of TemplatesFor. It must be
+// called when the translated templates have changed.
+func (p *Presentation) ResetTemplates() {
+	p.langTemplatesMu.Lock()
+	p.langTemplates = nil
+	p.langTemplatesMu.Unlock()
+}
+
 func (p *Presentation) FileServer() http.Handler {
 	return p.fileServer
 }
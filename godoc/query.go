@@ -0,0 +1,356 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the structured search query syntax:
+//
+//	Close pkg:net/http kind:func|method	Close, in net/http only
+//	Close pkg:net/...			Close, in net and below
+//	Reader file:*_test.go			Reader, in test files
+//	Close -pkg:os -kind:method		negated filters
+//	"read deadline"				phrase, for full text and doc search
+//	格式化 lang:zh_CN			translated docs of zh_CN
+//
+// Only the well-formed filters with a known key are taken out of the
+// query; the rest is kept verbatim, since it may be a regular
+// expression. A query without filters or phrases is used as is.
+
+import (
+	pathpkg "path"
+	"regexp"
+	"strings"
+)
+
+// A Query is a parsed search query.
+type Query struct {
+	Text    string   // identifier, words or regular expression
+	Phrases []string // quoted phrases
+	Lang    string   // language of the docs to search, if set
+
+	Pkgs, NotPkgs   []string   // import paths; "net/..." matches net and below
+	Kinds, NotKinds []SpotKind // kinds of identifiers and hits
+	Files, NotFiles []string   // file name patterns, as for path.Match
+}
+
+var (
+	pkgPattern  = regexp.MustCompile(`^[\w.~+-]+(/[\w.~+-]+)*$`)
+	langPattern = regexp.MustCompile(`^[a-zA-Z]+(_[a-zA-Z0-9]+)?$`)
+)
+
+// ParseQuery parses the search query s.
+func ParseQuery(s string) *Query {
+	q := new(Query)
+	query := s
+	var text []string // the rest of s: text fields and the space before them
+	structured := false
+	for {
+		field := strings.TrimLeft(s, " \t\n")
+		if field == "" {
+			break
+		}
+		space := s[:len(s)-len(field)]
+
+		// a quoted phrase ends at a quote followed by space
+		n := strings.IndexAny(field, " \t\n")
+		if field[0] == '"' {
+			if i := strings.IndexByte(field[1:], '"'); i >= 0 && (i+2 == len(field) || strings.IndexByte(" \t\n", field[i+2]) >= 0) {
+				n = i + 2
+			}
+		}
+		if n < 0 {
+			n = len(field)
+		}
+		field, s = field[:n], field[n:]
+
+		if q.addFilter(field) {
+			structured = true
+			continue
+		}
+		if len(text) > 0 {
+			text = append(text, space)
+		}
+		text = append(text, field)
+	}
+	if !structured {
+		return &Query{Text: query}
+	}
+	q.Text = strings.Join(text, "")
+	return q
+}
+
+// addFilter adds the filter or phrase field to q, and reports whether
+// field is a well-formed filter or phrase.
+func (q *Query) addFilter(field string) bool {
+	if n := len(field); n >= 2 && field[0] == '"' && strings.IndexByte(field[1:], '"') == n-2 {
+		if phrase := strings.TrimSpace(field[1 : len(field)-1]); phrase != "" {
+			q.Phrases = append(q.Phrases, phrase)
+		}
+		return true
+	}
+
+	not := strings.HasPrefix(field, "-")
+	if not {
+		field = field[1:]
+	}
+	i := strings.IndexByte(field, ':')
+	if i < 0 {
+		return false
+	}
+	key, value := field[:i], field[i+1:]
+	switch key {
+	case "pkg":
+		if !pkgPattern.MatchString(value) {
+			return false
+		}
+		if not {
+			q.NotPkgs = append(q.NotPkgs, value)
+		} else {
+			q.Pkgs = append(q.Pkgs, value)
+		}
+	case "kind":
+		var kinds []SpotKind
+		for _, name := range strings.Split(value, "|") {
+			kind, ok := spotKindOf(name)
+			if !ok {
+				return false
+			}
+			kinds = append(kinds, kind)
+		}
+		if not {
+			q.NotKinds = append(q.NotKinds, kinds...)
+		} else {
+			q.Kinds = append(q.Kinds, kinds...)
+		}
+	case "file":
+		if _, err := pathpkg.Match(value, ""); value == "" || err != nil {
+			return false
+		}
+		if not {
+			q.NotFiles = append(q.NotFiles, value)
+		} else {
+			q.Files = append(q.Files, value)
+		}
+	case "lang":
+		if not || !langPattern.MatchString(value) {
+			return false
+		}
+		q.Lang = value
+	default:
+		return false
+	}
+	return true
+}
+
+// spotKindOf returns the SpotKind of a short kind name, as used in JSON.
+func spotKindOf(name string) (SpotKind, bool) {
+	for i, s := range spotKindNames {
+		if s == name {
+			return SpotKind(i), true
+		}
+	}
+	return 0, false
+}
+
+// filtered reports whether q restricts the results.
+func (q *Query) filtered() bool {
+	return len(q.Pkgs) > 0 || len(q.NotPkgs) > 0 ||
+		len(q.Kinds) > 0 || len(q.NotKinds) > 0 ||
+		len(q.Files) > 0 || len(q.NotFiles) > 0
+}
+
+// Regexp returns the regular expression for the full text search:
+// the quoted phrases, or else Text.
+func (q *Query) Regexp() (*regexp.Regexp, error) {
+	if len(q.Phrases) == 0 {
+		return regexp.Compile(q.Text)
+	}
+	list := make([]string, len(q.Phrases))
+	for i, phrase := range q.Phrases {
+		list[i] = regexp.QuoteMeta(phrase)
+	}
+	return regexp.Compile(strings.Join(list, "|"))
+}
+
+// Words returns the words and phrases of q, for the doc search.
+func (q *Query) Words() string {
+	return strings.Join(append([]string{q.Text}, q.Phrases...), " ")
+}
+
+// matchPkg reports whether pattern matches the import path.
+func matchPkg(pattern, importPath string) bool {
+	if strings.HasSuffix(pattern, "/...") {
+		prefix := strings.TrimSuffix(pattern, "/...")
+		return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+	}
+	return importPath == pattern || strings.HasSuffix(importPath, "/"+pattern)
+}
+
+// matchPath reports whether the file or package path matches pattern.
+func matchPath(pattern, name string) bool {
+	if ok, _ := pathpkg.Match(pattern, name); ok {
+		return true
+	}
+	ok, _ := pathpkg.Match(pattern, pathpkg.Base(name))
+	return ok
+}
+
+func (q *Query) matchPkg(importPath string) bool {
+	if len(q.Pkgs) > 0 {
+		found := false
+		for _, p := range q.Pkgs {
+			if matchPkg(p, importPath) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, p := range q.NotPkgs {
+		if matchPkg(p, importPath) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) matchFile(filename string) bool {
+	if len(q.Files) > 0 {
+		found := false
+		for _, p := range q.Files {
+			if matchPath(p, filename) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, p := range q.NotFiles {
+		if matchPath(p, filename) {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) matchKind(kind SpotKind) bool {
+	if len(q.Kinds) > 0 {
+		found := false
+		for _, k := range q.Kinds {
+			if k == kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, k := range q.NotKinds {
+		if k == kind {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *Query) matchIdent(kind SpotKind, id Ident) bool {
+	return q.matchKind(kind) && q.matchPkg(id.Path)
+}
+
+// filterHits returns the hits of h matching q. The hit lists may be
+// shared with the index and are not changed.
+func (q *Query) filterHits(h HitList) HitList {
+	var list HitList
+	for _, run := range h {
+		if !q.matchPkg(dirImportPath(run.Pak.Path)) {
+			continue
+		}
+		var files []*FileRun
+		for _, f := range run.Files {
+			if !q.matchFile(f.File.Path()) {
+				continue
+			}
+			var groups []KindRun
+			for _, group := range f.Groups {
+				// the spots of a KindRun are of the same kind
+				if len(group) > 0 && q.matchKind(group[0].Kind()) {
+					groups = append(groups, group)
+				}
+			}
+			if len(groups) > 0 {
+				files = append(files, &FileRun{f.File, groups})
+			}
+		}
+		if len(files) > 0 {
+			list = append(list, &PakRun{run.Pak, files})
+		}
+	}
+	return list
+}
+
+// filter removes the results not matching q from result.
+func (q *Query) filter(result *SearchResult) {
+	result.Pak = q.filterHits(result.Pak)
+	if result.Hit != nil {
+		result.Hit = &LookupResult{
+			Decls:  q.filterHits(result.Hit.Decls),
+			Others: q.filterHits(result.Hit.Others),
+		}
+	}
+	idents := make(map[SpotKind][]Ident)
+	for kind, list := range result.Idents {
+		for _, id := range list {
+			if q.matchIdent(kind, id) {
+				idents[kind] = append(idents[kind], id)
+			}
+		}
+	}
+	result.Idents = idents
+
+	var fuzzy []FuzzyMatch
+	for _, m := range result.Fuzzy {
+		if q.matchIdent(m.Kind, Ident{Path: m.Path, Name: m.Name}) {
+			fuzzy = append(fuzzy, m)
+		}
+	}
+	result.Fuzzy = fuzzy
+
+	var textual []FileLines
+	found := 0
+	for _, f := range result.Textual {
+		if q.matchPkg(dirImportPath(pathpkg.Dir(f.Filename))) && q.matchFile(f.Filename) {
+			textual = append(textual, f)
+			found += len(f.Lines)
+		}
+	}
+	if len(textual) < len(result.Textual) {
+		result.Textual = textual
+		result.Found = found
+	}
+}
+
+// lookupIdents returns all exported identifiers matching the simple
+// or qualified identifier query, by kind. Unlike Lookup, the number
+// of identifiers is not limited.
+func (x *Index) lookupIdents(query string) map[SpotKind][]Ident {
+	ss := strings.Split(query, ".")
+	ident, pakname := ss[len(ss)-1], ""
+	if len(ss) == 2 {
+		pakname = ss[0]
+	}
+	idents := make(map[SpotKind][]Ident)
+	for k, v := range x.idents {
+		for _, id := range v[ident] {
+			if pakname == "" || id.Package == pakname {
+				idents[k] = append(idents[k], id)
+			}
+		}
+	}
+	return idents
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  *Query
+	}{
+		{"Close", &Query{Text: "Close"}},
+		{"a  b*", &Query{Text: "a  b*"}},
+		{"Close pkg:net/http kind:func|method", &Query{
+			Text:  "Close",
+			Pkgs:  []string{"net/http"},
+			Kinds: []SpotKind{FuncDecl, MethodDecl},
+		}},
+		{`Reader -pkg:io -kind:var file:*_test.go lang:zh_CN`, &Query{
+			Text:     "Reader",
+			Lang:     "zh_CN",
+			NotPkgs:  []string{"io"},
+			NotKinds: []SpotKind{VarDecl},
+			Files:    []string{"*_test.go"},
+		}},
+		{`"read deadline" -file:*.s`, &Query{
+			Phrases:  []string{"read deadline"},
+			NotFiles: []string{"*.s"},
+		}},
+		{"a  b pkg:net c", &Query{Text: "a  b c", Pkgs: []string{"net"}}},
+		{`"a b" "a"b" c`, &Query{Text: `"a"b" c`, Phrases: []string{"a b"}}},
+
+		// not filters: kept verbatim for the regular expression search
+		{"x -= 1", &Query{Text: "x -= 1"}},
+		{`"open`, &Query{Text: `"open`}},
+		{`"a"b"`, &Query{Text: `"a"b"`}},
+		{"x|pkg:y", &Query{Text: "x|pkg:y"}},
+		{"pkg:(a|b)", &Query{Text: "pkg:(a|b)"}},
+		{"x kind:struct", &Query{Text: "x kind:struct"}},
+		{"x file:[a", &Query{Text: "x file:[a"}},
+		{"x pkg:", &Query{Text: "x pkg:"}},
+	} {
+		if got := ParseQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPkg(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"net/http", "net/http", true},
+		{"http", "net/http", true},
+		{"net/http", "net/http/httptest", false},
+		{"net/...", "net/http/httptest", true},
+		{"net/...", "net", true},
+		{"net/...", "netchan", false},
+		{"ttp", "net/http", false},
+	} {
+		if got := matchPkg(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchPkg(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestQueryMatchDir(t *testing.T) {
+	q := ParseQuery("x pkg:net/...")
+	for _, dir := range []string{"/src/net/http", "/src/pkg/net/http"} {
+		if !q.matchPkg(dirImportPath(dir)) {
+			t.Errorf("pkg:net/... does not match %s", dir)
+		}
+	}
+}
+
+func TestLookupQuery(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+
+	paks := func(h HitList) []string {
+		var list []string
+		for _, run := range h {
+			list = append(list, run.Pak.Path)
+		}
+		return list
+	}
+	names := func(result SearchResult, kind SpotKind) []string {
+		var list []string
+		for _, id := range result.Idents[kind] {
+			list = append(list, id.Path+"."+id.Name)
+		}
+		return list
+	}
+
+	result := c.Lookup("Foo")
+	if got := names(result, TypeDecl); !reflect.DeepEqual(got, []string{"foo.Foo"}) {
+		t.Errorf("Foo: got types %v", got)
+	}
+	if got := names(result, VarDecl); len(got) != 0 {
+		t.Errorf("Foo: got vars %v", got)
+	}
+
+	result = c.Lookup("Foo kind:type")
+	if result.Alert != "" {
+		t.Fatalf("Foo kind:type: %s", result.Alert)
+	}
+	if got := paks(result.Hit.Decls); !reflect.DeepEqual(got, []string{"/src/foo"}) {
+		t.Errorf("Foo kind:type: got decls in %v", got)
+	}
+	if got := paks(result.Hit.Others); len(got) != 0 {
+		t.Errorf("Foo kind:type: got uses in %v", got)
+	}
+
+	result = c.Lookup("Foo -pkg:foo")
+	if got := paks(result.Hit.Decls); len(got) != 0 {
+		t.Errorf("Foo -pkg:foo: got decls in %v", got)
+	}
+	if got := names(result, TypeDecl); len(got) != 0 {
+		t.Errorf("Foo -pkg:foo: got types %v", got)
+	}
+
+	result = c.Lookup("X pkg:other/...")
+	if got := names(result, FuncDecl); !reflect.DeepEqual(got, []string{"other/bar.X"}) {
+		t.Errorf("X pkg:other/...: got funcs %v", got)
+	}
+	result = c.Lookup("X pkg:foo")
+	if got := names(result, FuncDecl); len(got) != 0 {
+		t.Errorf("X pkg:foo: got funcs %v", got)
+	}
+
+	// an unknown kind is not a filter
+	result = c.Lookup("X kind:blah")
+	if got := names(result, FuncDecl); len(got) != 0 {
+		t.Errorf("X kind:blah: got funcs %v", got)
+	}
+}
@@ -0,0 +1,304 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the references view of a declaration, served at
+// /refs/$(path).$(name), e.g. /refs/net/http.Handler. The identifier
+// is resolved with the export and identifier indices; its uses are
+// the uses of the name in the declaring package and the qualified
+// uses (http.Handler) in the packages importing it.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+const maxRefs = 1000 // maximum number of uses reported
+
+// A RefsResult is the list of references of an exported declaration.
+type RefsResult struct {
+	Kind     string       `json:"kind"`
+	Path     string       `json:"path"` // import path of the declaring package
+	Package  string       `json:"package"`
+	Name     string       `json:"name"`
+	Synopsis string       `json:"synopsis,omitempty"`
+	URL      string       `json:"url"` // documentation
+	Decls    []RefFile    `json:"decls"`
+	Packages []RefPackage `json:"packages"` // uses by package
+	Found    int          `json:"found"`    // number of uses
+	Complete bool         `json:"complete"` // all uses are reported
+}
+
+// A RefPackage is the list of uses in a package.
+type RefPackage struct {
+	Path  string    `json:"path"` // import path
+	Name  string    `json:"name"`
+	Files []RefFile `json:"files"`
+}
+
+// A RefFile is the list of references in a file.
+type RefFile struct {
+	File  string    `json:"file"`
+	URL   string    `json:"url"`
+	Lines []RefLine `json:"lines"`
+}
+
+// A RefLine is a line with a reference.
+type RefLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"` // source line, trimmed
+	URL  string `json:"url"`
+}
+
+// Refs returns the declarations of the exported identifier name of the
+// package path, and at most max of its uses. The source lines and URLs
+// are not set. Refs returns nil if there is no such identifier.
+//
+// In the declaring package, the uses are found by name; in the
+// packages importing path, only the selectors naming the package
+// through its import name are counted.
+func (x *Index) Refs(path, name string, max int) *RefsResult {
+	kind, ok := x.exports[path][name]
+	if !ok {
+		return nil
+	}
+	res := &RefsResult{
+		Kind:     spotKindNames[kind],
+		Path:     path,
+		Package:  pathpkg.Base(path),
+		Name:     name,
+		Complete: true,
+	}
+	for _, id := range x.idents[kind][name] {
+		if id.Path == path {
+			res.Package = id.Package
+			res.Synopsis = id.Doc
+			break
+		}
+	}
+
+	var uses HitList
+	if lookup := x.words[name]; lookup != nil {
+		for _, run := range lookup.Decls {
+			if dirImportPath(run.Pak.Path) != path {
+				continue
+			}
+			for _, f := range run.Files {
+				var lines []int
+				for _, group := range f.Groups {
+					for _, info := range group {
+						if info.Kind() == kind && info.IsIndex() {
+							if sn := x.Snippet(info.Lori()); sn != nil {
+								lines = append(lines, sn.Line)
+							}
+						}
+					}
+				}
+				if len(lines) > 0 {
+					res.Decls = append(res.Decls, newRefFile(f.File.Path(), lines))
+				}
+			}
+		}
+		for _, run := range lookup.Others {
+			if dirImportPath(run.Pak.Path) == path {
+				uses = append(uses, run)
+			}
+		}
+	}
+	uses = append(uses, x.selectors[path+"."+name]...)
+	sort.Sort(hitsByPak(uses))
+
+	for _, run := range uses {
+		pkg := RefPackage{Path: dirImportPath(run.Pak.Path), Name: run.Pak.Name}
+		for _, f := range run.Files {
+			var lines []int
+			for _, group := range f.Groups {
+				for _, info := range group {
+					if info.Kind() != Use {
+						continue
+					}
+					if res.Found == max {
+						res.Complete = false
+						break
+					}
+					res.Found++
+					lines = append(lines, info.Lori())
+				}
+			}
+			if len(lines) > 0 {
+				pkg.Files = append(pkg.Files, newRefFile(f.File.Path(), lines))
+			}
+		}
+		if len(pkg.Files) > 0 {
+			res.Packages = append(res.Packages, pkg)
+		}
+	}
+	return res
+}
+
+func newRefFile(file string, lines []int) RefFile {
+	f := RefFile{File: file}
+	for _, line := range unique(lines) {
+		f.Lines = append(f.Lines, RefLine{Line: line})
+	}
+	return f
+}
+
+// refsPath returns the import path and the identifier name of the
+// references URL path. The name follows the last dot, since the import
+// path may contain dots (gopkg.in/yaml.v2) but the identifier cannot.
+func refsPath(urlPath string) (path, name string, err error) {
+	s := strings.TrimPrefix(urlPath, "/refs/")
+	i := strings.LastIndex(s, ".")
+	if i <= 0 || i == len(s)-1 || strings.Contains(s[i+1:], "/") {
+		return "", "", errors.New("want /refs/$(path).$(name)")
+	}
+	return s[:i], s[i+1:], nil
+}
+
+// srcURL returns the URL of the source file file.
+func (p *Presentation) srcURL(file string) string {
+	if p.URLForSrc != nil {
+		return p.URLForSrc(file)
+	}
+	return srcLinkFunc(file)
+}
+
+// srcLineURL returns the URL of a line of the source file file.
+func (p *Presentation) srcLineURL(file string, line int) string {
+	if p.URLForSrcPos != nil {
+		return p.URLForSrcPos(file, line, 0, 0)
+	}
+	return fmt.Sprintf("%s#L%d", srcLinkFunc(file), line)
+}
+
+// fillRefFile sets the source lines and URLs of f.
+func (p *Presentation) fillRefFile(f *RefFile) {
+	f.URL = p.srcURL(f.File)
+	var src [][]byte
+	if data, err := vfs.ReadFile(p.Corpus.fs, f.File); err == nil {
+		src = bytes.Split(data, []byte("\n"))
+	}
+	for i := range f.Lines {
+		l := &f.Lines[i]
+		l.URL = p.srcLineURL(f.File, l.Line)
+		if 0 < l.Line && l.Line <= len(src) {
+			l.Text = string(bytes.TrimSpace(src[l.Line-1]))
+		}
+	}
+}
+
+// HandleRefs serves the references of the declaration given by the URL
+// path, as HTML or, with m=json, as JSON.
+func (p *Presentation) HandleRefs(w http.ResponseWriter, r *http.Request) {
+	lang := r.FormValue("lang")
+	jsonMode := p.GetPageInfoMode(r)&JSON != 0
+	fail := func(err error) {
+		if jsonMode {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		p.ServeError(w, r, r.URL.Path, err)
+	}
+
+	path, name, err := refsPath(r.URL.Path)
+	if err != nil {
+		fail(err)
+		return
+	}
+	index, _ := p.Corpus.CurrentIndex()
+	if index == nil {
+		fail(errors.New("search index is not available"))
+		return
+	}
+	res := index.Refs(path, name, maxRefs)
+	if res == nil {
+		fail(fmt.Errorf("no exported identifier %s.%s", path, name))
+		return
+	}
+
+	docLang := lang
+	if docLang == "" {
+		docLang = p.Lang
+	}
+	if text, ok := p.Corpus.translateDoc(docLang, path, name); ok {
+		res.Synopsis = text
+	}
+	res.URL = "/pkg/" + path + "/"
+	if lang != "" {
+		res.URL += "?lang=" + url.QueryEscape(lang)
+	}
+	res.URL += "#" + name
+	for i := range res.Decls {
+		p.fillRefFile(&res.Decls[i])
+	}
+	for i := range res.Packages {
+		for j := range res.Packages[i].Files {
+			p.fillRefFile(&res.Packages[i].Files[j])
+		}
+	}
+
+	if jsonMode {
+		data, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(data)
+		return
+	}
+
+	p.ServePage(w, Page{
+		Title:    p.Translate(lang, "References to %s.%s", path, name),
+		Tabtitle: name,
+		Lang:     lang,
+		Body:     p.refsHTML(lang, res),
+	})
+}
+
+// refsHTML returns the HTML body of the references page of res.
+func (p *Presentation) refsHTML(lang string, res *RefsResult) []byte {
+	esc := template.HTMLEscapeString
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<p>%s <a href=\"%s\">%s.%s</a>", esc(res.Kind), esc(res.URL), esc(res.Package), esc(res.Name))
+	if res.Synopsis != "" {
+		fmt.Fprintf(&buf, " &mdash; %s", esc(res.Synopsis))
+	}
+	buf.WriteString("</p>\n")
+
+	files := func(list []RefFile) {
+		for _, f := range list {
+			fmt.Fprintf(&buf, "<p><a href=\"%s\">%s</a></p>\n<pre>\n", esc(f.URL), esc(f.File))
+			for _, l := range f.Lines {
+				fmt.Fprintf(&buf, "<a href=\"%s\">%6d</a>\t%s\n", esc(l.URL), l.Line, esc(l.Text))
+			}
+			buf.WriteString("</pre>\n")
+		}
+	}
+	fmt.Fprintf(&buf, "<h2 id=\"Declarations\">%s</h2>\n", esc(p.Translate(lang, "Declarations")))
+	files(res.Decls)
+
+	fmt.Fprintf(&buf, "<h2 id=\"Uses\">%s</h2>\n", esc(p.Translate(lang, "Uses")))
+	fmt.Fprintf(&buf, "<p>%s</p>\n", esc(p.Translate(lang, "%d uses in %d packages", res.Found, len(res.Packages))))
+	if !res.Complete {
+		fmt.Fprintf(&buf, "<p class=\"alert\">%s</p>\n", esc(p.Translate(lang, "Only the first %d uses are shown.", maxRefs)))
+	}
+	for _, pkg := range res.Packages {
+		fmt.Fprintf(&buf, "<h3 id=\"%s\">%s <a href=\"/pkg/%s/\">%s</a></h3>\n", esc(pkg.Path), esc(pkg.Name), esc(pkg.Path), esc(pkg.Path))
+		files(pkg.Files)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,144 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestRefsPath(t *testing.T) {
+	for _, test := range []struct{ url, path, name string }{
+		{"/refs/net/http.Handler", "net/http", "Handler"},
+		{"/refs/fmt.Println", "fmt", "Println"},
+		{"/refs/gopkg.in/yaml.v2.Marshal", "gopkg.in/yaml.v2", "Marshal"},
+		{"/refs/net/http", "", ""},
+		{"/refs/gopkg.in/yaml", "", ""},
+		{"/refs/fmt.", "", ""},
+		{"/refs/.Println", "", ""},
+	} {
+		path, name, _ := refsPath(test.url)
+		if path != test.path || name != test.name {
+			t.Errorf("refsPath(%q) = %q, %q; want %q, %q", test.url, path, name, test.path, test.name)
+		}
+	}
+}
+
+func newRefsCorpus(t *testing.T) *Corpus {
+	c := NewCorpus(mapfs.New(map[string]string{
+		"src/foo/foo.go":     "// Package foo is an example.\npackage foo\n\n// Foo is stuff.\ntype Foo struct{}\n\nfunc New() *Foo { return new(Foo) }\n",
+		"src/bar/bar.go":     "package bar\n\nimport \"foo\"\n\nvar x foo.Foo\n\nfunc Bar() *foo.Foo {\n\treturn foo.New()\n}\n",
+		"src/other/other.go": "package other\n\ntype Foo int\n\nvar y Foo\n",
+		"src/baz/baz.go":     "package baz\n\nimport f \"foo\"\n\ntype Foo int\n\nvar a Foo\n\nvar b f.Foo\n",
+	}))
+	c.IndexEnabled = true
+	if err := c.Init(); err != nil {
+		t.Fatal(err)
+	}
+	c.UpdateIndex()
+	return c
+}
+
+func TestRefs(t *testing.T) {
+	c := newRefsCorpus(t)
+	x, _ := c.CurrentIndex()
+	if res := x.Refs("foo", "Bar", maxRefs); res != nil {
+		t.Errorf("Refs(foo, Bar) = %+v; want nil", res)
+	}
+	res := x.Refs("foo", "Foo", maxRefs)
+	if res == nil {
+		t.Fatal("Refs(foo, Foo) = nil")
+	}
+	if res.Kind != "type" || res.Package != "foo" || res.Synopsis != "Foo is stuff." {
+		t.Errorf("Refs(foo, Foo) = %+v", res)
+	}
+	if want := []RefFile{{File: "/src/foo/foo.go", Lines: []RefLine{{Line: 5}}}}; !reflect.DeepEqual(res.Decls, want) {
+		t.Errorf("decls = %+v; want %+v", res.Decls, want)
+	}
+	// the uses in other and the local Foo of baz do not refer to foo.Foo
+	want := []RefPackage{
+		{"bar", "bar", []RefFile{{File: "/src/bar/bar.go", Lines: []RefLine{{Line: 5}, {Line: 7}}}}},
+		{"baz", "baz", []RefFile{{File: "/src/baz/baz.go", Lines: []RefLine{{Line: 9}}}}},
+		{"foo", "foo", []RefFile{{File: "/src/foo/foo.go", Lines: []RefLine{{Line: 7}}}}},
+	}
+	if !reflect.DeepEqual(res.Packages, want) {
+		t.Errorf("packages = %+v; want %+v", res.Packages, want)
+	}
+	if res.Found != 4 || !res.Complete {
+		t.Errorf("found %d, complete %v; want 4, true", res.Found, res.Complete)
+	}
+	if res := x.Refs("foo", "Foo", 2); res.Found != 2 || res.Complete {
+		t.Errorf("found %d, complete %v; want 2, false", res.Found, res.Complete)
+	}
+}
+
+func TestHandleRefs(t *testing.T) {
+	c := newRefsCorpus(t)
+	c.TranslateDoc = func(lang, importPath, id string) (string, bool) {
+		if lang == "zh_CN" && importPath == "foo" && id == "Foo" {
+			return "Foo 是一个类型.", true
+		}
+		return "", false
+	}
+	p := NewPresentation(c)
+	p.URLForSrcPos = func(src string, line, low, high int) string {
+		return fmt.Sprintf("https://example.com%s#L%d", src, line)
+	}
+	p.GodocHTML = template.Must(template.New("godoc").Parse("{{.Title}}|{{printf \"%s\" .Body}}"))
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, req)
+		return w
+	}
+
+	var res RefsResult
+	w := get("/refs/foo.Foo?m=json&lang=zh_CN")
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%v\n%s", err, w.Body)
+	}
+	if res.Synopsis != "Foo 是一个类型." || res.URL != "/pkg/foo/?lang=zh_CN#Foo" {
+		t.Errorf("synopsis %q, URL %q", res.Synopsis, res.URL)
+	}
+	if len(res.Packages) == 0 {
+		t.Fatalf("no uses: %s", w.Body)
+	}
+	want := RefLine{Line: 5, Text: "var x foo.Foo", URL: "https://example.com/src/bar/bar.go#L5"}
+	if f := res.Packages[0].Files[0]; f.URL != "/src/bar/bar.go" || f.Lines[0] != want {
+		t.Errorf("first file = %+v; want line %+v", f, want)
+	}
+
+	w = get("/refs/foo.Foo")
+	if s := w.Body.String(); !strings.HasPrefix(s, "References to foo.Foo|") ||
+		!strings.Contains(s, `<a href="https://example.com/src/bar/bar.go#L7">     7</a>	func Bar() *foo.Foo {`) {
+		t.Errorf("got %s", s)
+	}
+
+	if w := get("/refs/foo.Nope?m=json"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown identifier: code %d; want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	for path, want := range map[string]string{
+		"net/http":                    "http",
+		"gopkg.in/yaml.v2":            "yaml",
+		"github.com/user/yaml/v2":     "yaml",
+		"github.com/mattn/go-sqlite3": "sqlite3",
+		"v2":                          "v2",
+	} {
+		if got := assumedPackageName(path); got != want {
+			t.Errorf("assumedPackageName(%q) = %q; want %q", path, got, want)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 )
 
@@ -26,26 +25,77 @@ type SearchResult struct {
 	Textual  []FileLines // textual matches of Query
 	Complete bool        // true if all textual occurrences of Query are reported
 	Idents   map[SpotKind][]Ident
+
+	// approximate identifier matches, if there is no exact match
+	Fuzzy []FuzzyMatch
 }
 
-func (c *Corpus) Lookup(query string) SearchResult {
+// Lookup returns the search results for query. The optional lang
+// argument is the request language, whose translated docs are searched.
+// The query may contain filters, see ParseQuery, or be a function
+// signature, see IsSignatureQuery.
+func (c *Corpus) Lookup(query string, lang ...string) SearchResult {
 	result := &SearchResult{Query: query}
 
 	index, timestamp := c.CurrentIndex()
-	if index != nil {
+	if IsSignatureQuery(query) {
+		// search by signature
+		if index != nil {
+			idents, err := index.LookupSignature(query)
+			if err != nil {
+				result.Alert = "Error in signature: " + err.Error()
+				return *result
+			}
+			result.Idents = idents
+		}
+	} else if index != nil {
+		q := ParseQuery(query)
+		if q.Lang != "" {
+			lang = []string{q.Lang}
+		}
+
+		// translated doc search, and the identifiers of the
+		// native-language synonyms of query
+		var docs map[SpotKind][]Ident
+		var synonyms []string
+		if len(lang) > 0 && lang[0] != "" {
+			docs = index.LookupDoc(lang[0], q.Words())
+			if c.Synonyms != nil && q.Text != "" {
+				synonyms = c.Synonyms(lang[0], q.Text)
+			}
+		}
+
 		// identifier search
-		if r, err := index.Lookup(query); err == nil {
+		if r, err := index.Lookup(q.Text); err == nil {
 			result = r
+			result.Query = query
+			if q.filtered() {
+				// filter all identifiers, not only the top ones
+				result.Idents = index.lookupIdents(q.Text)
+			}
+			if r.Hit == nil {
+				result.addFuzzy(index, q.Text, index.LookupFuzzy(q.Text, maxFuzzy))
+			}
+		} else if len(docs) > 0 || len(synonyms) > 0 || len(q.Phrases) > 0 {
+			// the query is text of the translated docs, a synonym,
+			// or a phrase
 		} else if err != nil && !c.IndexFullText {
 			// ignore the error if full text search is enabled
 			// since the query may be a valid regular expression
 			result.Alert = "Error in query string: " + err.Error()
 			return *result
 		}
+		result.addIdents(docs)
+		for _, id := range synonyms {
+			if r, err := index.Lookup(id); err == nil {
+				result.addIdents(r.Idents)
+			}
+		}
+		result.addAlts(q.Text, synonyms)
 
 		// full text search
-		if c.IndexFullText && query != "" {
-			rx, err := regexp.Compile(query)
+		if c.IndexFullText && (q.Text != "" || len(q.Phrases) > 0) {
+			rx, err := q.Regexp()
 			if err != nil {
 				result.Alert = "Error in query regular expression: " + err.Error()
 				return *result
@@ -60,6 +110,14 @@ func (c *Corpus) Lookup(query string) SearchResult {
 				result.Found-- // since we looked for maxResults+1
 			}
 		}
+
+		if q.filtered() {
+			q.filter(result)
+			for kind, list := range result.Idents {
+				const rsltLimit = 50
+				result.Idents[kind] = byImportCount{list, index.importCount}.top(rsltLimit)
+			}
+		}
 	}
 
 	// is the result accurate?
@@ -75,6 +133,53 @@ func (c *Corpus) Lookup(query string) SearchResult {
 	return *result
 }
 
+// addIdents adds the identifiers not yet in result.Idents.
+func (result *SearchResult) addIdents(idents map[SpotKind][]Ident) {
+	if len(idents) == 0 {
+		return
+	}
+	if result.Idents == nil {
+		result.Idents = make(map[SpotKind][]Ident)
+	}
+	for kind, list := range idents {
+		have := make(map[string]bool)
+		for _, id := range result.Idents[kind] {
+			have[id.Path+"."+id.Name] = true
+		}
+		for _, id := range list {
+			if !have[id.Path+"."+id.Name] {
+				result.Idents[kind] = append(result.Idents[kind], id)
+			}
+		}
+	}
+}
+
+// addAlts adds the alternative words not yet in result.Alt.
+func (result *SearchResult) addAlts(query string, words []string) {
+	if len(words) == 0 {
+		return
+	}
+	alt := &AltWords{Canon: canonical(query)}
+	if result.Alt != nil {
+		// copy, result.Alt may be shared with the index
+		alt.Canon = result.Alt.Canon
+		alt.Alts = append(alt.Alts, result.Alt.Alts...)
+	}
+	for _, w := range words {
+		found := false
+		for _, a := range alt.Alts {
+			if a == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			alt.Alts = append(alt.Alts, w)
+		}
+	}
+	result.Alt = alt
+}
+
 // SearchResultDoc optionally specifies a function returning an HTML body
 // displaying search results matching godoc documentation.
 func (p *Presentation) SearchResultDoc(result SearchResult) []byte {
@@ -97,10 +202,21 @@ func (p *Presentation) SearchResultTxt(result SearchResult) []byte {
 // to display them.
 func (p *Presentation) HandleSearch(w http.ResponseWriter, r *http.Request) {
 	query := strings.TrimSpace(r.FormValue("q"))
-	result := p.Corpus.Lookup(query)
+	lang := r.FormValue("lang")
+	docLang := lang
+	if docLang == "" {
+		docLang = p.Lang
+	}
+	result := p.Corpus.Lookup(query, docLang)
 
-	if p.GetPageInfoMode(r)&NoHTML != 0 {
-		p.ServeText(w, applyTemplate(p.SearchText, "searchText", result))
+	tmpl := p.templates(lang)
+	mode := p.GetPageInfoMode(r)
+	if mode&JSON != 0 {
+		p.serveSearchJSON(w, result)
+		return
+	}
+	if mode&NoHTML != 0 {
+		p.ServeText(w, applyTemplate(tmpl.SearchText, "searchText", result))
 		return
 	}
 	contents := bytes.Buffer{}
@@ -118,21 +234,34 @@ func (p *Presentation) HandleSearch(w http.ResponseWriter, r *http.Request) {
 		title = fmt.Sprintf(`No results found for query %q`, query)
 	}
 
-	body := bytes.NewBuffer(applyTemplate(p.SearchHTML, "searchHTML", result))
+	body := bytes.NewBuffer(applyTemplate(tmpl.SearchHTML, "searchHTML", result))
 	body.Write(contents.Bytes())
 
 	p.ServePage(w, Page{
 		Title:    title,
 		Tabtitle: query,
 		Query:    query,
+		Lang:     lang,
 		Body:     body.Bytes(),
 	})
 }
 
 func (p *Presentation) serveSearchDesc(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/opensearchdescription+xml")
+	base := baseURL(r)
 	data := map[string]interface{}{
-		"BaseURL": fmt.Sprintf("http://%s", r.Host),
+		"BaseURL": base,
+	}
+	w.Write(addSearchSuggest(applyTemplate(p.SearchDescXML, "searchDescXML", &data), base))
+}
+
+// baseURL returns the scheme and host of the request r, as seen by the
+// client: the scheme is https if r is over TLS, or forwarded from it by
+// a proxy.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		scheme = "https"
 	}
-	applyTemplateToResponseWriter(w, p.SearchDescXML, &data)
+	return scheme + "://" + r.Host
 }
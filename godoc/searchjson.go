@@ -0,0 +1,159 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the JSON form of the search results,
+// served for /search?m=json.
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// spotKindNames are the short names of the SpotKinds, as used in JSON.
+// They must match the SpotKind values.
+var spotKindNames = []string{
+	"package",
+	"import",
+	"const",
+	"type",
+	"var",
+	"func",
+	"method",
+	"use",
+}
+
+// A SearchJSON is the result of a search, as served in JSON.
+type SearchJSON struct {
+	Query    string          `json:"query"`
+	Alert    string          `json:"alert,omitempty"`
+	Stale    bool            `json:"stale,omitempty"` // the index is older than the file system
+	Packages []string        `json:"packages,omitempty"`
+	Decls    []PakHitsJSON   `json:"decls,omitempty"`
+	Others   []PakHitsJSON   `json:"others,omitempty"`
+	Idents   []IdentJSON     `json:"idents,omitempty"`
+	Alts     []string        `json:"alts,omitempty"`
+	Fuzzy    []FuzzyJSON     `json:"fuzzy,omitempty"` // approximate matches, best first
+	Found    int             `json:"found,omitempty"`
+	Textual  []FileLinesJSON `json:"textual,omitempty"`
+	Complete bool            `json:"complete"` // all textual matches are reported
+}
+
+// A PakHitsJSON is the list of hits in a package.
+type PakHitsJSON struct {
+	Path string    `json:"path"`
+	Name string    `json:"name"`
+	Hits []HitJSON `json:"hits"`
+}
+
+// A HitJSON is an occurrence of the query.
+type HitJSON struct {
+	Kind    string `json:"kind"`
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Snippet string `json:"snippet,omitempty"` // declaration, in plain text
+}
+
+// An IdentJSON is an exported identifier matching the query.
+type IdentJSON struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path"`
+	Package string `json:"package"`
+	Name    string `json:"name"`
+	Doc     string `json:"doc,omitempty"`
+}
+
+// A FuzzyJSON is a declaration matching the query approximately.
+type FuzzyJSON struct {
+	Kind    string  `json:"kind"`
+	Path    string  `json:"path"`
+	Package string  `json:"package"`
+	Name    string  `json:"name"`
+	Score   float64 `json:"score"`
+}
+
+// A FileLinesJSON is a file with textual matches.
+type FileLinesJSON struct {
+	File  string `json:"file"`
+	Lines []int  `json:"lines"`
+}
+
+var htmlTagRx = regexp.MustCompile(`<[^>]*>`)
+
+// searchJSON returns the JSON form of result. The snippets of the
+// hits are looked up in index, if any.
+func searchJSON(result SearchResult, index *Index) *SearchJSON {
+	s := &SearchJSON{
+		Query:    result.Query,
+		Alert:    result.Alert,
+		Found:    result.Found,
+		Complete: result.Complete,
+	}
+	for _, run := range result.Pak {
+		s.Packages = append(s.Packages, run.Pak.Path)
+	}
+	hits := func(list HitList) []PakHitsJSON {
+		var paks []PakHitsJSON
+		for _, run := range list {
+			pak := PakHitsJSON{Path: run.Pak.Path, Name: run.Pak.Name}
+			for _, f := range run.Files {
+				for _, group := range f.Groups {
+					for _, info := range group {
+						hit := HitJSON{Kind: spotKindNames[info.Kind()], File: f.File.Path()}
+						if !info.IsIndex() {
+							hit.Line = info.Lori()
+						} else if index != nil {
+							if snippet := index.Snippet(info.Lori()); snippet != nil {
+								hit.Line = snippet.Line
+								hit.Snippet = strings.TrimSpace(html.UnescapeString(htmlTagRx.ReplaceAllString(snippet.Text, "")))
+							}
+						}
+						pak.Hits = append(pak.Hits, hit)
+					}
+				}
+			}
+			paks = append(paks, pak)
+		}
+		return paks
+	}
+	if result.Hit != nil {
+		s.Decls = hits(result.Hit.Decls)
+		s.Others = hits(result.Hit.Others)
+	}
+	for kind := SpotKind(0); kind < nKinds; kind++ {
+		for _, id := range result.Idents[kind] {
+			s.Idents = append(s.Idents, IdentJSON{spotKindNames[kind], id.Path, id.Package, id.Name, id.Doc})
+		}
+	}
+	if result.Alt != nil {
+		s.Alts = result.Alt.Alts
+	}
+	for _, m := range result.Fuzzy {
+		s.Fuzzy = append(s.Fuzzy, FuzzyJSON{spotKindNames[m.Kind], m.Path, m.Package, m.Name, m.Score})
+	}
+	for _, f := range result.Textual {
+		s.Textual = append(s.Textual, FileLinesJSON{f.Filename, f.Lines})
+	}
+	return s
+}
+
+// serveSearchJSON serves result as JSON.
+func (p *Presentation) serveSearchJSON(w http.ResponseWriter, result SearchResult) {
+	index, timestamp := p.Corpus.CurrentIndex()
+	s := searchJSON(result, index)
+	if p.Corpus.IndexEnabled && timestamp.Before(p.Corpus.FSModifiedTime()) {
+		s.Stale = true
+	}
+	data, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
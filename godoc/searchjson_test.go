@@ -0,0 +1,60 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchJSON(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+	p := NewPresentation(c)
+
+	req, _ := http.NewRequest("GET", "/search?m=json&q=Foo", nil)
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d\n%s", w.Code, w.Body)
+	}
+	var s SearchJSON
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+		t.Fatalf("%v\n%s", err, w.Body)
+	}
+	if s.Query != "Foo" || s.Stale {
+		t.Errorf("got query %q, stale %v", s.Query, s.Stale)
+	}
+
+	var decl *HitJSON
+	for _, pak := range s.Decls {
+		for i, hit := range pak.Hits {
+			if pak.Path == "/src/foo" && hit.Kind == "type" {
+				decl = &pak.Hits[i]
+			}
+		}
+	}
+	if decl == nil {
+		t.Fatalf("no type declaration of Foo in %+v", s.Decls)
+	}
+	if decl.File != "/src/foo/foo.go" || decl.Line != 11 || decl.Snippet != "// Foo is stuff.\ntype Foo struct{}" {
+		t.Errorf("got declaration %+v", decl)
+	}
+	if len(s.Others) == 0 {
+		t.Errorf("no uses of Foo")
+	}
+
+	found := false
+	for _, id := range s.Idents {
+		if id.Kind == "type" && id.Path == "foo" && id.Name == "Foo" && id.Doc == "Foo is stuff." {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no identifier foo.Foo in %+v", s.Idents)
+	}
+}
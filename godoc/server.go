@@ -36,10 +36,11 @@ import (
 // This should probably merge into something else.
 type handlerServer struct {
 	p       *Presentation
-	c       *Corpus  // copy of p.Corpus
-	pattern string   // url pattern; e.g. "/pkg/"
-	fsRoot  string   // file system root to which the pattern is mapped; e.g. "/src"
-	exclude []string // file system paths to exclude; e.g. "/src/cmd"
+	c       *Corpus      // copy of p.Corpus
+	pattern string       // url pattern; e.g. "/pkg/"
+	fsRoot  string       // file system root to which the pattern is mapped; e.g. "/src"
+	exclude []string     // file system paths to exclude; e.g. "/src/cmd"
+	mode    PageInfoMode // mode flags always set; e.g. JSON
 }
 
 func (s *handlerServer) registerWithMux(mux *http.ServeMux) {
@@ -59,6 +60,12 @@ func (h *handlerServer) GetPageInfo(abspath, relpath string, mode PageInfoMode,
 		relpath = "cmd/" + relpath
 	}
 	info := &PageInfo{Dirname: abspath}
+	if len(lang) > 0 {
+		info.Lang = lang[0]
+	}
+	if len(lang) > 1 {
+		info.MinStatus = lang[1]
+	}
 
 	// Restrict to the package files that would be used when building
 	// the package on this system.  This makes sure that if there are
@@ -133,7 +140,14 @@ func (h *handlerServer) GetPageInfo(abspath, relpath string, mode PageInfoMode,
 			}
 			info.PDoc = doc.New(pkg, pathpkg.Clean(relpath), m) // no trailing '/' in importpath
 			if h.c.TranslateDocPackage != nil {
-				info.PDoc = h.c.TranslateDocPackage(info.PDoc, lang...)
+				if mode&JSON != 0 {
+					// keep the english docs
+					info.OrigPDoc = info.PDoc
+					info.PDoc = copyDocPackage(info.PDoc)
+				}
+				if pdoc := h.c.TranslateDocPackage(info.PDoc, lang...); pdoc != nil {
+					info.PDoc = pdoc
+				}
 			}
 
 			if mode&NoTypeAssoc != 0 {
@@ -245,19 +259,25 @@ func (h *handlerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	relpath := pathpkg.Clean(r.URL.Path[len(h.pattern):])
 	abspath := pathpkg.Join(h.fsRoot, relpath)
-	mode := h.p.GetPageInfoMode(r)
+	mode := h.p.GetPageInfoMode(r) | h.mode
 	if relpath == builtinPkgPath {
-		mode = NoFiltering | NoTypeAssoc
+		mode = NoFiltering | NoTypeAssoc | mode&JSON
+	}
+	lang := r.FormValue("lang")
+	info := h.GetPageInfo(abspath, relpath, mode, lang, r.FormValue("minstatus"))
+	if mode&JSON != 0 {
+		h.p.servePackageJSON(w, info, relpath)
+		return
 	}
-	info := h.GetPageInfo(abspath, relpath, mode, r.FormValue("lang"))
 	if info.Err != nil {
 		log.Print(info.Err)
 		h.p.ServeError(w, r, relpath, info.Err)
 		return
 	}
 
+	tmpl := h.p.templates(lang)
 	if mode&NoHTML != 0 {
-		h.p.ServeText(w, applyTemplate(h.p.PackageText, "packageText", info))
+		h.p.ServeText(w, applyTemplate(tmpl.PackageText, "packageText", info))
 		return
 	}
 
@@ -308,11 +328,20 @@ func (h *handlerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		info.TypeInfoIndex[ti.Name] = i
 	}
 
+	body := applyTemplate(tmpl.PackageHTML, "packageHTML", info)
+	if h.p.PackageHeader != nil && info.PDoc != nil {
+		body = append(h.p.PackageHeader(info), body...)
+	}
+	if h.p.PackageFooter != nil && info.PDoc != nil {
+		body = append(body, h.p.PackageFooter(info)...)
+	}
+
 	h.p.ServePage(w, Page{
 		Title:    title,
 		Tabtitle: tabtitle,
 		Subtitle: subtitle,
-		Body:     applyTemplate(h.p.PackageHTML, "packageHTML", info),
+		Lang:     lang,
+		Body:     body,
 	})
 }
 
@@ -325,6 +354,7 @@ const (
 	NoHTML                               // show result in textual form, do not generate HTML
 	FlatDir                              // show directory in a flat (non-indented) manner
 	NoTypeAssoc                          // don't associate consts, vars, and factory functions with types
+	JSON                                 // show result as JSON, with the english docs of the translation
 )
 
 // modeNames defines names for each PageInfoMode flag.
@@ -334,6 +364,7 @@ var modeNames = map[string]PageInfoMode{
 	"src":     ShowSource,
 	"text":    NoHTML,
 	"flat":    FlatDir,
+	"json":    JSON,
 }
 
 // GetPageInfoMode computes the PageInfoMode flags by analyzing the request
@@ -520,7 +551,8 @@ func redirectFile(w http.ResponseWriter, r *http.Request) (redirected bool) {
 }
 
 func (p *Presentation) serveTextFile(w http.ResponseWriter, r *http.Request, abspath, relpath, title string) {
-	src, err := vfs.ReadFile(p.Corpus.fs, abspath)
+	lang := r.FormValue("lang")
+	src, err := vfs.ReadFile(p.Corpus.fsFor(lang), abspath)
 	if err != nil {
 		log.Printf("ReadFile: %s", err)
 		p.ServeError(w, r, relpath, err)
@@ -562,6 +594,7 @@ func (p *Presentation) serveTextFile(w http.ResponseWriter, r *http.Request, abs
 	p.ServePage(w, Page{
 		Title:    title + " " + relpath,
 		Tabtitle: relpath,
+		Lang:     lang,
 		Body:     buf.Bytes(),
 	})
 }
@@ -619,16 +652,19 @@ func (p *Presentation) serveDirectory(w http.ResponseWriter, r *http.Request, ab
 		return
 	}
 
+	lang := r.FormValue("lang")
 	p.ServePage(w, Page{
 		Title:    "Directory " + relpath,
 		Tabtitle: relpath,
-		Body:     applyTemplate(p.DirlistHTML, "dirlistHTML", list),
+		Lang:     lang,
+		Body:     applyTemplate(p.templates(lang).DirlistHTML, "dirlistHTML", list),
 	})
 }
 
 func (p *Presentation) ServeHTMLDoc(w http.ResponseWriter, r *http.Request, abspath, relpath string) {
-	// get HTML body contents
-	src, err := vfs.ReadFile(p.Corpus.fs, abspath)
+	// get HTML body contents, in the request language if any
+	lang := r.FormValue("lang")
+	src, err := vfs.ReadFile(p.Corpus.fsFor(lang), abspath)
 	if err != nil {
 		log.Printf("ReadFile: %s", err)
 		p.ServeError(w, r, relpath, err)
@@ -675,6 +711,7 @@ func (p *Presentation) ServeHTMLDoc(w http.ResponseWriter, r *http.Request, absp
 	p.ServePage(w, Page{
 		Title:    meta.Title,
 		Subtitle: meta.Subtitle,
+		Lang:     lang,
 		Body:     src,
 	})
 }
@@ -685,12 +722,17 @@ func (p *Presentation) ServeFile(w http.ResponseWriter, r *http.Request) {
 
 func (p *Presentation) serveFile(w http.ResponseWriter, r *http.Request) {
 	relpath := r.URL.Path
+	fs := p.Corpus.fsFor(r.FormValue("lang"))
 
 	// Check to see if we need to redirect or serve another file.
-	if m := p.Corpus.MetadataFor(relpath); m != nil {
+	if m := p.Corpus.MetadataFor(relpath, r.FormValue("lang")); m != nil {
 		if m.Path != relpath {
-			// Redirect to canonical path.
-			http.Redirect(w, r, m.Path, http.StatusMovedPermanently)
+			// Redirect to canonical path, keeping the query (e.g. lang).
+			target := m.Path
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
 			return
 		}
 		// Serve from the actual filesystem path.
@@ -716,7 +758,7 @@ func (p *Presentation) serveFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dir, err := p.Corpus.fs.Lstat(abspath)
+	dir, err := fs.Lstat(abspath)
 	if err != nil {
 		log.Print(err)
 		p.ServeError(w, r, relpath, err)
@@ -727,7 +769,7 @@ func (p *Presentation) serveFile(w http.ResponseWriter, r *http.Request) {
 		if redirect(w, r) {
 			return
 		}
-		if index := pathpkg.Join(abspath, "index.html"); util.IsTextFile(p.Corpus.fs, index) {
+		if index := pathpkg.Join(abspath, "index.html"); util.IsTextFile(fs, index) {
 			p.ServeHTMLDoc(w, r, index, index)
 			return
 		}
@@ -735,7 +777,7 @@ func (p *Presentation) serveFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if util.IsTextFile(p.Corpus.fs, abspath) {
+	if util.IsTextFile(fs, abspath) {
 		if redirectFile(w, r) {
 			return
 		}
@@ -0,0 +1,366 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the sharded index format. An index file starts
+// with a header carrying the format version, the fingerprint of the
+// indexed corpus, the index options and the table of shards; the
+// shards follow. A shard is the index of the directories of one
+// top-level tree, such as "net" or "github.com/user/repo"; it consists
+// of the identifier index and the full text index, which are stored
+// separately such that the full text index is only loaded on first use.
+//
+// The header is checked against the corpus once, before any shard is
+// loaded: the index options and the fingerprint of the indexed
+// directories, made of the names and sizes of their files, must match
+// those of the corpus. The identifier
+// indices of the shards are loaded in parallel and joined. When an
+// index file is written again, the shards whose directories did not
+// change are copied from the old file, such that only the changed
+// shards are rebuilt.
+//
+// Layout:
+//
+//	"godocidx"             magic
+//	uint32                 length of the header, big-endian
+//	indexHeader            gob-encoded
+//	shards                 at the offsets of the header, relative to
+//	                       the end of the header
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	indexMagic         = "godocidx"
+	indexFormatVersion = 1
+)
+
+// An indexHeader describes a sharded index file.
+type indexHeader struct {
+	Version      int    // indexFormatVersion
+	IndexVersion int    // fileIndexVersion
+	Fingerprint  string // of the indexed corpus
+	Opts         indexOptions
+	Shards       []shardHeader
+}
+
+// A shardHeader describes a shard of an index file. The offsets are
+// relative to the end of the header.
+type shardHeader struct {
+	Name        string
+	Fingerprint string // of the indexed directories
+	Offset      int64  // of the identifier index
+	Size        int64
+	TextOffset  int64 // of the full text index
+	TextSize    int64 // 0 if no full text index
+}
+
+// shardName returns the name of the shard of the directory dirname:
+// the first element of its import path, or the first three if the
+// first one is a host name. It is "" for directories without import
+// path.
+func shardName(dirname string) string {
+	path := dirImportPath(dirname)
+	if path == dirname {
+		return "" // not under /src
+	}
+	elems := strings.SplitN(path, "/", 4)
+	if !strings.Contains(elems[0], ".") || len(elems) == 1 {
+		return elems[0]
+	}
+	if len(elems) == 4 {
+		elems = elems[:3]
+	}
+	return strings.Join(elems, "/")
+}
+
+// fingerprint returns the fingerprint of the directories dirs indexed
+// with the options opts: the translated docs of the directories depend
+// on the indexed languages and the translations.
+func fingerprint(dirs map[string]uint64, opts indexOptions) string {
+	names := make([]string, 0, len(dirs))
+	for dirname := range dirs {
+		names = append(names, dirname)
+	}
+	sort.Strings(names)
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s %x\n", opts.Langs, opts.Translations)
+	for _, dirname := range names {
+		fmt.Fprintf(h, "%s %x\n", dirname, dirs[dirname])
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// shardDirs returns the signatures of the directories to index,
+// grouped by shard. The signatures do not depend on the modification
+// times, such that an index file may be copied or deployed elsewhere.
+func (c *Corpus) shardDirs() map[string]map[string]uint64 {
+	shards := make(map[string]map[string]uint64)
+	for dirname := range c.fsDirnames() {
+		if c.IndexDirectory != nil && !c.IndexDirectory(dirname) {
+			continue
+		}
+		list, err := c.fs.ReadDir(dirname)
+		if err != nil {
+			continue // not indexed
+		}
+		name := shardName(dirname)
+		if shards[name] == nil {
+			shards[name] = make(map[string]uint64)
+		}
+		shards[name][dirname] = dirSignature(list, false)
+	}
+	return shards
+}
+
+// shards returns the headers of the shards of the corpus, sorted by
+// name, with their fingerprint, and the signatures of their directories.
+func (c *Corpus) shards() ([]shardHeader, map[string]map[string]uint64) {
+	dirs := c.shardDirs()
+	opts := c.indexOptions()
+	shards := make([]shardHeader, 0, len(dirs))
+	for name := range dirs {
+		shards = append(shards, shardHeader{Name: name, Fingerprint: fingerprint(dirs[name], opts)})
+	}
+	sort.Sort(shardsByName(shards))
+	return shards, dirs
+}
+
+type shardsByName []shardHeader
+
+func (s shardsByName) Len() int           { return len(s) }
+func (s shardsByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s shardsByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+
+// corpusFingerprint returns the fingerprint of the corpus made of the
+// shards.
+func corpusFingerprint(shards []shardHeader) string {
+	h := fnv.New64a()
+	for _, s := range shards {
+		fmt.Fprintf(h, "%s %s\n", s.Name, s.Fingerprint)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// check returns an error if the index file of the header h was not
+// written with the index options of c, or for other directories.
+func (h *indexHeader) check(c *Corpus) error {
+	if h.Opts != c.indexOptions() {
+		return fmt.Errorf("index file options are incompatible: %v", h.Opts)
+	}
+	if shards, _ := c.shards(); h.Fingerprint != corpusFingerprint(shards) {
+		return errors.New("index file is out of date: the indexed directories changed")
+	}
+	return nil
+}
+
+// readIndexHeader reads the header of the sharded index file r. It
+// returns the header and the offset of the shards.
+func readIndexHeader(r io.ReaderAt) (*indexHeader, int64, error) {
+	var buf [len(indexMagic) + 4]byte
+	if _, err := r.ReadAt(buf[:], 0); err != nil {
+		return nil, 0, err
+	}
+	if string(buf[:len(indexMagic)]) != indexMagic {
+		return nil, 0, errors.New("not a sharded index file")
+	}
+	size := int64(binary.BigEndian.Uint32(buf[len(indexMagic):]))
+	base := int64(len(buf)) + size
+	h := new(indexHeader)
+	if err := gob.NewDecoder(io.NewSectionReader(r, int64(len(buf)), size)).Decode(h); err != nil {
+		return nil, 0, err
+	}
+	if h.Version != indexFormatVersion || h.IndexVersion != fileIndexVersion {
+		return nil, 0, ErrFileIndexVersion
+	}
+	return h, base, nil
+}
+
+// WriteIndex writes the index of the corpus to w in the sharded index
+// format. If old is not nil, it is a sharded index file written before:
+// its shards whose directories did not change are copied instead of
+// being rebuilt.
+func (c *Corpus) WriteIndex(w io.Writer, old io.ReaderAt) error {
+	oldShards := make(map[string]shardHeader)
+	var oldBase int64
+	if old != nil {
+		if h, base, err := readIndexHeader(old); err == nil && h.Opts == c.indexOptions() {
+			for _, s := range h.Shards {
+				oldShards[s.Name] = s
+			}
+			oldBase = base
+		}
+	}
+
+	shards, dirs := c.shards()
+	h := &indexHeader{
+		Version:      indexFormatVersion,
+		IndexVersion: fileIndexVersion,
+		Fingerprint:  corpusFingerprint(shards),
+		Opts:         c.indexOptions(),
+		Shards:       shards,
+	}
+	var data bytes.Buffer
+	copyShard := func(off, size int64) error {
+		_, err := io.Copy(&data, io.NewSectionReader(old, oldBase+off, size))
+		return err
+	}
+	for i := range h.Shards {
+		s := &h.Shards[i]
+		if o, ok := oldShards[s.Name]; ok && o.Fingerprint == s.Fingerprint {
+			if c.Verbose {
+				log.Printf("copying unchanged index shard %q", s.Name)
+			}
+			s.Offset = int64(data.Len())
+			if err := copyShard(o.Offset, o.Size); err != nil {
+				return err
+			}
+			s.Size = int64(data.Len()) - s.Offset
+			s.TextOffset = int64(data.Len())
+			if err := copyShard(o.TextOffset, o.TextSize); err != nil {
+				return err
+			}
+			s.TextSize = int64(data.Len()) - s.TextOffset
+			continue
+		}
+
+		if c.Verbose {
+			log.Printf("building index shard %q", s.Name)
+		}
+		set := make(map[string]bool, len(dirs[s.Name]))
+		for dirname := range dirs[s.Name] {
+			set[dirname] = true
+		}
+		x := c.newIndex(set)
+		s.Offset = int64(data.Len())
+		if err := x.writeIdents(&data); err != nil {
+			return err
+		}
+		s.Size = int64(data.Len()) - s.Offset
+		s.TextOffset = int64(data.Len())
+		for _, t := range x.text {
+			if err := t.writeTo(&data); err != nil {
+				return err
+			}
+		}
+		s.TextSize = int64(data.Len()) - s.TextOffset
+	}
+
+	var header bytes.Buffer
+	if err := gob.NewEncoder(&header).Encode(h); err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(header.Len()))
+	for _, b := range [][]byte{[]byte(indexMagic), size[:], header.Bytes(), data.Bytes()} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readShardedIndex reads the sharded index file r. The identifier
+// indices of the shards are read and joined; the full text indices are
+// read from r on first use.
+func (c *Corpus) readShardedIndex(r io.ReaderAt) (*Index, error) {
+	h, base, err := readIndexHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.check(c); err != nil {
+		return nil, err
+	}
+
+	shards := make([]*Index, len(h.Shards))
+	errs := make([]error, len(h.Shards))
+	var wg sync.WaitGroup
+	for i, s := range h.Shards {
+		wg.Add(1)
+		go func(i int, s shardHeader) {
+			defer wg.Done()
+			x := new(Index)
+			sr := bufio.NewReader(io.NewSectionReader(r, base+s.Offset, s.Size))
+			if _, err := x.readIdents(sr); err != nil {
+				errs[i] = fmt.Errorf("index shard %q: %v", s.Name, err)
+				return
+			}
+			if s.TextSize > 0 {
+				x.text = []*textIndex{{
+					dirs: dirSet(x.dirs),
+					load: func(t *textIndex) error {
+						return t.readFrom(bufio.NewReader(io.NewSectionReader(r, base+s.TextOffset, s.TextSize)))
+					},
+				}}
+			}
+			shards[i] = x
+		}(i, s)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	x := joinIndexes(shards)
+	x.opts = h.Opts
+	return x, nil
+}
+
+// A multiReaderAt is the concatenation of files.
+type multiReaderAt struct {
+	files []*os.File
+	offs  []int64 // offset of files[i]; offs[len(files)] is the total size
+}
+
+func newMultiReaderAt(files []*os.File) (*multiReaderAt, error) {
+	m := &multiReaderAt{files: files, offs: []int64{0}}
+	for _, f := range files {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		m.offs = append(m.offs, m.offs[len(m.offs)-1]+fi.Size())
+	}
+	return m, nil
+}
+
+func (m *multiReaderAt) Size() int64 { return m.offs[len(m.files)] }
+
+func (m *multiReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	for n < len(p) {
+		if off >= m.Size() {
+			return n, io.EOF
+		}
+		i := sort.Search(len(m.files), func(i int) bool { return m.offs[i+1] > off }) // off in files[i]
+		k, err := m.files[i].ReadAt(p[n:min64(int64(len(p)), int64(n)+m.offs[i+1]-off)], off-m.offs[i])
+		n += k
+		off += int64(k)
+		if err != nil && err != io.EOF {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
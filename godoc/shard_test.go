@@ -0,0 +1,141 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"bytes"
+	"go/doc"
+	"io"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestShardName(t *testing.T) {
+	for _, test := range []struct{ dirname, want string }{
+		{"/src", ""},
+		{"/doc", ""},
+		{"/src/fmt", "fmt"},
+		{"/src/net/http/httptest", "net"},
+		{"/src/pkg/net/http", "net"},
+		{"/src/github.com", "github.com"},
+		{"/src/github.com/user/repo", "github.com/user/repo"},
+		{"/src/github.com/user/repo/sub/pkg", "github.com/user/repo"},
+	} {
+		if got := shardName(test.dirname); got != test.want {
+			t.Errorf("shardName(%q) = %q; want %q", test.dirname, got, test.want)
+		}
+	}
+}
+
+// readShards returns the raw shards of the sharded index file data.
+func readShards(t *testing.T, data []byte) map[string]string {
+	h, base, err := readIndexHeader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make(map[string]string)
+	for _, s := range h.Shards {
+		shards[s.Name] = string(data[base+s.Offset:base+s.Offset+s.Size]) +
+			string(data[base+s.TextOffset:base+s.TextOffset+s.TextSize])
+	}
+	return shards
+}
+
+func TestShardedIndex(t *testing.T) {
+	files := map[string]string{
+		"src/foo/foo.go":       "// Package foo is an example.\npackage foo\n\nimport \"bar\"\n\n// Foo is stuff.\ntype Foo struct{}\n\nfunc New() *Foo { return new(Foo) }\n",
+		"src/bar/bar.go":       "// Package bar is another example.\npackage bar\n\nfunc Bar() {}\n",
+		"src/other/bar/bar.go": "// Package bar is another bar package.\npackage bar\n\nimport \"foo\"\n\nfunc X() *foo.Foo { return nil }\n",
+		"src/other/baz/baz.go": "package baz\n\nimport \"bar\"\n\nfunc Bar() {}\n",
+	}
+	c := NewCorpus(mapfs.New(files))
+	c.IndexEnabled = true
+	initTestCorpus(t, c)
+
+	var buf bytes.Buffer
+	if err := c.WriteIndex(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte(nil), buf.Bytes()...)
+	if err := c.ReadIndexFrom(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	x, _ := c.CurrentIndex()
+	if len(x.text) != 4 { // "", bar, foo and other
+		t.Fatalf("%d full text indices; want 4", len(x.text))
+	}
+	for _, text := range x.text {
+		if text.fset != nil {
+			t.Errorf("full text index loaded before use")
+		}
+	}
+	compareIndex(t, x, c.NewIndex())
+
+	// a reader without ReadAt is read into memory
+	if err := c.ReadIndexFrom(struct{ io.Reader }{bytes.NewReader(data)}); err != nil {
+		t.Fatal(err)
+	}
+	x, _ = c.CurrentIndex()
+	compareIndex(t, x, c.NewIndex())
+
+	// only the changed shard is rebuilt
+	files["src/other/baz/baz.go"] += "\nfunc Baz() {}\n"
+	initTestCorpus(t, c)
+	buf.Reset()
+	if err := c.WriteIndex(&buf, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	old, shards := readShards(t, data), readShards(t, buf.Bytes())
+	for name, s := range shards {
+		if changed := name == "other"; (s != old[name]) != changed {
+			t.Errorf("shard %q changed = %v; want %v", name, !changed, changed)
+		}
+	}
+	if err := c.ReadIndexFrom(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	x, _ = c.CurrentIndex()
+	compareIndex(t, x, c.NewIndex())
+	if !x.CompatibleWith(c) {
+		t.Errorf("index not compatible with its corpus")
+	}
+
+	// the index of other directories is not read
+	if err := c.ReadIndexFrom(bytes.NewReader(data)); err == nil {
+		t.Errorf("out of date index read")
+	}
+	// the later changes are those of the updates
+	files["src/bar/bar.go"] += "\nfunc Qux() {}\n"
+	initTestCorpus(t, c)
+	c.UpdateIndex()
+	if x, _ = c.CurrentIndex(); x.words["Qux"] == nil {
+		t.Errorf("read index not updated after a change")
+	}
+
+	// the shards of other indexed languages are rebuilt
+	data = append([]byte(nil), buf.Bytes()...)
+	c.TranslateDocPackage = func(pkg *doc.Package, lang ...string) *doc.Package { return pkg }
+	c.IndexLangs = []string{"zh_CN"}
+	buf.Reset()
+	if err := c.WriteIndex(&buf, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	old, shards = readShards(t, data), readShards(t, buf.Bytes())
+	for name, s := range shards {
+		if s == old[name] {
+			t.Errorf("shard %q of another language copied", name)
+		}
+	}
+	if err := c.ReadIndexFrom(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+
+	// the header is checked before loading shards
+	c.IndexFullText = false
+	if err := c.ReadIndexFrom(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Errorf("incompatible index read")
+	}
+}
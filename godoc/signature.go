@@ -0,0 +1,396 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the search by function signature. The exported
+// functions and methods are indexed with their normalized signature:
+// the receiver and parameter types (the inputs) and the result types,
+// without names and with package-qualified types. Queries have the form
+//
+//	func(io.Reader) ([]byte, error)
+//	(*bytes.Buffer) -> string
+//
+// where the receiver of a method is its first input. A _ matches any
+// type or part of a type, and a ... list element any number of types.
+// Unqualified names of types other than the predeclared ones match the
+// type in any package.
+
+import (
+	"errors"
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A FuncSig is the normalized signature of a function or method.
+type FuncSig struct {
+	Kind    SpotKind // FuncDecl or MethodDecl
+	Ident            // Name is "T.M" for methods
+	Inputs  []string // receiver and parameter types, e.g. "*bytes.Buffer"
+	Results []string // result types
+}
+
+// String returns the signature in query form.
+func (s *FuncSig) String() string {
+	return "(" + strings.Join(s.Inputs, ", ") + ") -> (" + strings.Join(s.Results, ", ") + ")"
+}
+
+var predeclaredTypes = map[string]bool{
+	"bool":       true,
+	"byte":       true,
+	"complex64":  true,
+	"complex128": true,
+	"error":      true,
+	"float32":    true,
+	"float64":    true,
+	"int":        true,
+	"int8":       true,
+	"int16":      true,
+	"int32":      true,
+	"int64":      true,
+	"rune":       true,
+	"string":     true,
+	"uint":       true,
+	"uint8":      true,
+	"uint16":     true,
+	"uint32":     true,
+	"uint64":     true,
+	"uintptr":    true,
+}
+
+// typeString returns the normalized form of the type x declared in
+// package pkg. Unqualified names of other than the predeclared types
+// are qualified with pkg.
+func typeString(x ast.Expr, pkg string) string {
+	switch t := x.(type) {
+	case *ast.Ident:
+		if predeclaredTypes[t.Name] || t.Name == "_" {
+			return t.Name
+		}
+		return pkg + "." + t.Name
+	case *ast.SelectorExpr:
+		if id, ok := t.X.(*ast.Ident); ok {
+			return id.Name + "." + t.Sel.Name
+		}
+	case *ast.StarExpr:
+		return "*" + typeString(t.X, pkg)
+	case *ast.ParenExpr:
+		return typeString(t.X, pkg)
+	case *ast.Ellipsis:
+		return "..." + typeString(t.Elt, pkg)
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "[]" + typeString(t.Elt, pkg)
+		}
+		if lit, ok := t.Len.(*ast.BasicLit); ok {
+			return "[" + lit.Value + "]" + typeString(t.Elt, pkg)
+		}
+		return "[...]" + typeString(t.Elt, pkg)
+	case *ast.MapType:
+		return "map[" + typeString(t.Key, pkg) + "]" + typeString(t.Value, pkg)
+	case *ast.ChanType:
+		switch t.Dir {
+		case ast.SEND:
+			return "chan<- " + typeString(t.Value, pkg)
+		case ast.RECV:
+			return "<-chan " + typeString(t.Value, pkg)
+		}
+		return "chan " + typeString(t.Value, pkg)
+	case *ast.FuncType:
+		s := "func(" + strings.Join(fieldTypes(t.Params, pkg), ", ") + ")"
+		switch results := fieldTypes(t.Results, pkg); len(results) {
+		case 0:
+		case 1:
+			s += " " + results[0]
+		default:
+			s += " (" + strings.Join(results, ", ") + ")"
+		}
+		return s
+	case *ast.InterfaceType:
+		if t.Methods == nil || len(t.Methods.List) == 0 {
+			return "interface{}"
+		}
+		return "interface{...}"
+	case *ast.StructType:
+		if t.Fields == nil || len(t.Fields.List) == 0 {
+			return "struct{}"
+		}
+		return "struct{...}"
+	}
+	return "?"
+}
+
+// fieldTypes returns the normalized types of the fields, one per name.
+func fieldTypes(list *ast.FieldList, pkg string) []string {
+	if list == nil {
+		return nil
+	}
+	var types []string
+	for _, f := range list.List {
+		t := typeString(f.Type, pkg)
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// indexSignatures adds the signatures of the exported functions and
+// methods of file to the index.
+func (x *Indexer) indexSignatures(pkgPath, pkgName string, file *ast.File) {
+	for _, decl := range file.Decls {
+		f, ok := decl.(*ast.FuncDecl)
+		if !ok || !f.Name.IsExported() {
+			continue
+		}
+		sig := FuncSig{
+			Kind: FuncDecl,
+			Ident: Ident{
+				Path:    pkgPath,
+				Package: pkgName,
+				Name:    x.intern(f.Name.Name),
+				Doc:     doc.Synopsis(f.Doc.Text()),
+			},
+		}
+		if f.Recv != nil && len(f.Recv.List) > 0 {
+			recv := f.Recv.List[0].Type
+			base := recv
+			if star, ok := base.(*ast.StarExpr); ok {
+				base = star.X
+			}
+			id, ok := base.(*ast.Ident)
+			if !ok || !id.IsExported() {
+				continue // method of an unexported or generic type
+			}
+			sig.Kind = MethodDecl
+			sig.Name = x.intern(id.Name + "." + f.Name.Name)
+			sig.Inputs = append(sig.Inputs, x.intern(typeString(recv, pkgName)))
+		}
+		for _, t := range fieldTypes(f.Type.Params, pkgName) {
+			sig.Inputs = append(sig.Inputs, x.intern(t))
+		}
+		for _, t := range fieldTypes(f.Type.Results, pkgName) {
+			sig.Results = append(sig.Results, x.intern(t))
+		}
+		x.signatures = append(x.signatures, sig)
+	}
+}
+
+type sigsByName []FuncSig
+
+func (s sigsByName) Len() int      { return len(s) }
+func (s sigsByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s sigsByName) Less(i, j int) bool {
+	if s[i].Path != s[j].Path {
+		return s[i].Path < s[j].Path
+	}
+	return s[i].Name < s[j].Name
+}
+
+// IsSignatureQuery reports whether query is a function signature.
+func IsSignatureQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	return strings.HasPrefix(query, "func(") || strings.HasPrefix(query, "func (") ||
+		strings.HasPrefix(query, "(") && strings.Contains(query, "->")
+}
+
+// A typePattern matches a list element of a signature.
+type typePattern struct {
+	any bool           // ... matches any number of types
+	rx  *regexp.Regexp // else, the type
+}
+
+// A sigPattern is a parsed signature query.
+type sigPattern struct {
+	inputs, results []typePattern
+}
+
+// splitList splits the comma-separated list s at the top level.
+func splitList(s string) []string {
+	var list []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				list = append(list, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" || len(list) > 0 {
+		list = append(list, s[start:])
+	}
+	return list
+}
+
+// parenList returns the parenthesized list at the start of s, and the
+// rest of s.
+func parenList(s string) (list, rest string, err error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") {
+		return "", "", errors.New("expected (")
+	}
+	depth := 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s[1:i], s[i+1:], nil
+			}
+		}
+	}
+	return "", "", errors.New("missing )")
+}
+
+// identRx matches the identifiers of a normalized type.
+var identRx = regexp.MustCompile(`[\pL_][\pL\pN_]*`)
+
+// parseTypePattern parses a list element of a signature query.
+func parseTypePattern(s string) (typePattern, error) {
+	s = strings.TrimSpace(s)
+	if s == "..." {
+		return typePattern{any: true}, nil
+	}
+	prefix := ""
+	if strings.HasPrefix(s, "...") {
+		prefix, s = "...", s[3:]
+	}
+	x, err := parser.ParseExpr(s)
+	if err != nil {
+		// the parameter may be named, e.g. "r io.Reader"
+		if i := strings.IndexAny(s, " \t"); i > 0 {
+			return parseTypePattern(prefix + s[i+1:])
+		}
+		return typePattern{}, errors.New("invalid type " + s)
+	}
+	t := prefix + typeString(x, "_")
+
+	// each identifier _ matches any part of a type
+	var buf []string
+	last := 0
+	for _, loc := range identRx.FindAllStringIndex(t, -1) {
+		buf = append(buf, regexp.QuoteMeta(t[last:loc[0]]))
+		if id := t[loc[0]:loc[1]]; id == "_" {
+			buf = append(buf, `.+`)
+		} else {
+			buf = append(buf, regexp.QuoteMeta(id))
+		}
+		last = loc[1]
+	}
+	buf = append(buf, regexp.QuoteMeta(t[last:]))
+	rx, err := regexp.Compile("^" + strings.Join(buf, "") + "$")
+	if err != nil {
+		return typePattern{}, err
+	}
+	return typePattern{rx: rx}, nil
+}
+
+func parseTypePatterns(s string) ([]typePattern, error) {
+	var list []typePattern
+	for _, elem := range splitList(s) {
+		p, err := parseTypePattern(elem)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// parseSignature parses the signature query.
+func parseSignature(query string) (*sigPattern, error) {
+	s := strings.TrimSpace(query)
+	arrow := false
+	if strings.HasPrefix(s, "func") {
+		s = s[len("func"):]
+	} else {
+		arrow = true
+	}
+	inputs, rest, err := parenList(s)
+	if err != nil {
+		return nil, err
+	}
+	rest = strings.TrimSpace(rest)
+	if arrow {
+		if !strings.HasPrefix(rest, "->") {
+			return nil, errors.New("expected ->")
+		}
+		rest = strings.TrimSpace(rest[len("->"):])
+	}
+	results := rest
+	if strings.HasPrefix(rest, "(") {
+		var tail string
+		if results, tail, err = parenList(rest); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(tail) != "" {
+			return nil, errors.New("unexpected " + tail)
+		}
+	}
+
+	p := new(sigPattern)
+	if p.inputs, err = parseTypePatterns(inputs); err != nil {
+		return nil, err
+	}
+	if p.results, err = parseTypePatterns(results); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// matchTypes reports whether the patterns match the list of types.
+func matchTypes(patterns []typePattern, types []string) bool {
+	if len(patterns) == 0 {
+		return len(types) == 0
+	}
+	if patterns[0].any {
+		for i := 0; i <= len(types); i++ {
+			if matchTypes(patterns[1:], types[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	return len(types) > 0 && patterns[0].rx.MatchString(types[0]) &&
+		matchTypes(patterns[1:], types[1:])
+}
+
+// LookupSignature returns the exported functions and methods whose
+// signatures match query, by kind.
+func (x *Index) LookupSignature(query string) (map[SpotKind][]Ident, error) {
+	p, err := parseSignature(query)
+	if err != nil {
+		return nil, err
+	}
+	idents := make(map[SpotKind][]Ident)
+	for i := range x.signatures {
+		sig := &x.signatures[i]
+		if matchTypes(p.inputs, sig.Inputs) && matchTypes(p.results, sig.Results) {
+			idents[sig.Kind] = append(idents[sig.Kind], sig.Ident)
+		}
+	}
+	for kind, list := range idents {
+		const rsltLimit = 50
+		ids := byImportCount{list, x.importCount}
+		sort.Stable(ids)
+		idents[kind] = ids.top(rsltLimit)
+	}
+	return idents, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const sigSrc = `package bytes
+
+import "io"
+
+type Buffer struct{}
+
+func NewBuffer(buf []byte) *Buffer { return nil }
+func NewBufferString(s string) *Buffer { return nil }
+func (b *Buffer) String() string { return "" }
+func (b *Buffer) Len() int { return 0 }
+func (b *Buffer) ReadFrom(r io.Reader) (n int64, err error) { return }
+func (b *Buffer) WriteString(s string) (n int, err error) { return }
+func ReadAll(r io.Reader) ([]byte, error) { return nil, nil }
+func Join(s [][]byte, sep []byte) []byte { return nil }
+func Map(mapping func(r rune) rune, s []byte) []byte { return nil }
+func Printf(format string, a ...interface{}) (int, error) { return 0, nil }
+func (b *Buffer) grow(n int) int { return 0 }
+func helper(r io.Reader) {}
+`
+
+func TestLookupSignature(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "bytes.go", sigSrc, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := &Indexer{strings: make(map[string]string)}
+	x.indexSignatures("bytes", "bytes", file)
+	sort.Sort(sigsByName(x.signatures))
+	ix := &Index{signatures: x.signatures}
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"func(io.Reader) ([]byte, error)", []string{"ReadAll"}},
+		{"func(r io.Reader) ([]byte, error)", []string{"ReadAll"}},
+		{"(*bytes.Buffer) -> string", []string{"Buffer.String"}},
+		{"(*Buffer) -> int", []string{"Buffer.Len"}},
+		{"(*Buffer, _) -> (int, error)", []string{"Buffer.WriteString"}},
+		{"(*Buffer, ...) -> (_, error)", []string{"Buffer.ReadFrom", "Buffer.WriteString"}},
+		{"func(string) *Buffer", []string{"NewBufferString"}},
+		{"func([]_) *Buffer", []string{"NewBuffer"}},
+		{"func(func(rune) rune, []byte) []byte", []string{"Map"}},
+		{"func(string, ...interface{}) (int, error)", []string{"Printf"}},
+		{"(..., []byte) -> []byte", []string{"Join", "Map"}},
+		{"(io.Reader) -> ()", nil},
+		{"(int) -> int", nil},
+	} {
+		idents, err := ix.LookupSignature(tt.query)
+		if err != nil {
+			t.Errorf("LookupSignature(%q): %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, kind := range []SpotKind{FuncDecl, MethodDecl} {
+			for _, id := range idents[kind] {
+				got = append(got, id.Name)
+			}
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LookupSignature(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	for _, query := range []string{"func(io.Reader", "(int) string", "func(int) (string"} {
+		if _, err := ix.LookupSignature(query); err == nil {
+			t.Errorf("LookupSignature(%q): no error", query)
+		}
+	}
+}
+
+func TestIsSignatureQuery(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  bool
+	}{
+		{"func(io.Reader) error", true},
+		{" (*bytes.Buffer) -> string", true},
+		{"Reader", false},
+		{"(a|b)", false},
+		{"funcs", false},
+	} {
+		if got := IsSignatureQuery(tt.query); got != tt.want {
+			t.Errorf("IsSignatureQuery(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestLookupSignatureIndex(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+
+	result := c.Lookup("() -> *Foo")
+	if ids := result.Idents[FuncDecl]; len(ids) != 1 || ids[0].Path != "foo" || ids[0].Name != "New" {
+		t.Errorf("Lookup(() -> *Foo) = %+v", result.Idents)
+	}
+
+	// the signatures are written with the index
+	ix, _ := c.CurrentIndex()
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	ix2 := new(Index)
+	if _, err := ix2.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if idents, err := ix2.LookupSignature("func() *foo.Foo"); err != nil || len(idents[FuncDecl]) != 1 {
+		t.Errorf("LookupSignature after ReadFrom = %v, %v", idents, err)
+	}
+}
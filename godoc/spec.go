@@ -25,6 +25,10 @@ type ebnfParser struct {
 	pos     int    // offset of current token
 	tok     rune   // one token look-ahead
 	lit     string // token literal
+	prods   []*Production
+	errs    []string // syntax errors
+
+	rawStrings bool // accept tokens in `` as in "", see parseGrammar
 }
 
 func (p *ebnfParser) flush() {
@@ -44,6 +48,11 @@ func (p *ebnfParser) printf(format string, args ...interface{}) {
 }
 
 func (p *ebnfParser) errorExpected(msg string) {
+	name := "?"
+	if n := len(p.prods); n > 0 {
+		name = p.prods[n-1].Name
+	}
+	p.errs = append(p.errs, fmt.Sprintf("after %s: expected %s, found %s", name, msg, scanner.TokenString(p.tok)))
 	p.printf(`<span class="highlight">error: expected %s, found %s</span>`, msg, scanner.TokenString(p.tok))
 }
 
@@ -54,9 +63,9 @@ func (p *ebnfParser) expect(tok rune) {
 	p.next() // make progress in any case
 }
 
-func (p *ebnfParser) parseIdentifier(def bool) {
+func (p *ebnfParser) parseIdentifier(def bool) (name string) {
 	if p.tok == scanner.Ident {
-		name := p.lit
+		name = p.lit
 		if def {
 			p.printf(`<a id="%s">%s</a>`, name, name)
 		} else {
@@ -67,68 +76,100 @@ func (p *ebnfParser) parseIdentifier(def bool) {
 	} else {
 		p.expect(scanner.Ident)
 	}
+	return
 }
 
-func (p *ebnfParser) parseTerm() bool {
+// parseTerm returns the parsed term, or nil if no term was found.
+func (p *ebnfParser) parseTerm() (x ebnfExpr) {
 	switch p.tok {
 	case scanner.Ident:
-		p.parseIdentifier(false)
+		x = ebnfName(p.parseIdentifier(false))
 
-	case scanner.String:
+	case scanner.String, scanner.RawString:
+		if p.tok == scanner.RawString && !p.rawStrings {
+			break // no term, Linkify renders the spec as written
+		}
+		lit := p.lit
 		p.next()
 		const ellipsis = '…' // U+2026, the horizontal ellipsis character
 		if p.tok == ellipsis {
 			p.next()
-			p.expect(scanner.String)
+			x = &ebnfRange{lit, p.lit}
+			if p.tok == scanner.RawString && p.rawStrings {
+				p.next()
+			} else {
+				p.expect(scanner.String)
+			}
+		} else {
+			x = ebnfToken(lit)
 		}
 
 	case '(':
 		p.next()
-		p.parseExpression()
+		x = &ebnfGroup{p.parseExpression()}
 		p.expect(')')
 
 	case '[':
 		p.next()
-		p.parseExpression()
+		x = &ebnfOption{p.parseExpression()}
 		p.expect(']')
 
 	case '{':
 		p.next()
-		p.parseExpression()
+		x = &ebnfRepetition{p.parseExpression()}
 		p.expect('}')
-
-	default:
-		return false // no term found
 	}
 
-	return true
+	return
 }
 
-func (p *ebnfParser) parseSequence() {
-	if !p.parseTerm() {
+func (p *ebnfParser) parseSequence() ebnfExpr {
+	var list ebnfSequence
+	x := p.parseTerm()
+	if x == nil {
 		p.errorExpected("term")
 	}
-	for p.parseTerm() {
+	for x != nil {
+		list = append(list, x)
+		x = p.parseTerm()
 	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return list
 }
 
-func (p *ebnfParser) parseExpression() {
+func (p *ebnfParser) parseExpression() ebnfExpr {
+	var list ebnfAlternative
 	for {
-		p.parseSequence()
+		list = append(list, p.parseSequence())
 		if p.tok != '|' {
 			break
 		}
 		p.next()
 	}
+	if len(list) == 1 {
+		return list[0]
+	}
+	return list
 }
 
 func (p *ebnfParser) parseProduction() {
-	p.parseIdentifier(true)
+	start := p.pos
+	prod := &Production{Name: p.parseIdentifier(true)}
 	p.expect('=')
 	if p.tok != '.' {
-		p.parseExpression()
+		prod.expr = p.parseExpression()
+	}
+	end := p.pos
+	if p.tok == '.' {
+		end++ // include the terminating period
 	}
 	p.expect('.')
+	prod.Src = string(bytes.TrimSpace(p.src[start:end]))
+	if prod.Name != "" {
+		p.prods = append(p.prods, prod)
+	}
 }
 
 func (p *ebnfParser) parse(out io.Writer, src []byte) {
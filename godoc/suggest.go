@@ -0,0 +1,323 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the search-as-you-type suggestions of
+// identifiers and packages matching a prefix:
+//
+//	/search/suggest?q=Lis&n=10&lang=zh_CN			JSON list of suggestions
+//	/search/suggest?q=Lis&format=opensearch		OpenSearch suggestions
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/doc"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+)
+
+// A Suggestion is an identifier or package matching a prefix.
+type Suggestion struct {
+	Kind     string `json:"kind"` // as in SearchJSON
+	Name     string `json:"name"` // "T.M" for methods
+	Path     string `json:"path"` // import path
+	Package  string `json:"package"`
+	Synopsis string `json:"synopsis,omitempty"`
+	URL      string `json:"url"`
+}
+
+// A suggestEntry is an entry of the prefix table.
+type suggestEntry struct {
+	key  string // canonical name
+	kind SpotKind
+	id   Ident
+}
+
+// suggestTable is the prefix table of an index: the entries sorted
+// by key, such that the entries with a prefix are consecutive.
+type suggestTable struct {
+	once    sync.Once
+	entries []suggestEntry
+}
+
+// build builds the prefix table from the identifiers and exports of x.
+func (t *suggestTable) build(x *Index) {
+	seen := make(map[string]bool)
+	add := func(key string, kind SpotKind, id Ident) {
+		k := spotKindNames[kind] + ":" + id.Path + "." + id.Name + ":" + key
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		t.entries = append(t.entries, suggestEntry{canonical(key), kind, id})
+	}
+	for kind, idents := range x.idents {
+		for _, list := range idents {
+			for _, id := range list {
+				if kind == PackageClause {
+					// packages are listed under their name and import path
+					add(id.Name, kind, id)
+					add(id.Path, kind, id)
+					continue
+				}
+				add(id.Name, kind, id)
+				if i := strings.IndexByte(id.Name, '.'); i >= 0 {
+					add(id.Name[i+1:], kind, id) // method name
+				}
+			}
+		}
+	}
+	// exported declarations without docs
+	for path, exports := range x.exports {
+		pkg := path[strings.LastIndex(path, "/")+1:]
+		for name, kind := range exports {
+			if !ast.IsExported(name) || len(x.idents[kind][name]) > 0 {
+				continue
+			}
+			add(name, kind, Ident{Path: path, Package: pkg, Name: name})
+		}
+	}
+	sort.Sort(byKey(t.entries))
+}
+
+type byKey []suggestEntry
+
+func (s byKey) Len() int      { return len(s) }
+func (s byKey) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byKey) Less(i, j int) bool {
+	if s[i].key != s[j].key {
+		return s[i].key < s[j].key
+	}
+	if s[i].id.Path != s[j].id.Path {
+		return s[i].id.Path < s[j].id.Path
+	}
+	return s[i].id.Name < s[j].id.Name
+}
+
+// lookup returns the entries whose keys start with prefix.
+func (t *suggestTable) lookup(prefix string) []suggestEntry {
+	prefix = canonical(prefix)
+	i := sort.Search(len(t.entries), func(i int) bool { return t.entries[i].key >= prefix })
+	j := i
+	for j < len(t.entries) && strings.HasPrefix(t.entries[j].key, prefix) {
+		j++
+	}
+	return t.entries[i:j]
+}
+
+// LookupPrefix returns at most n identifiers and packages whose names
+// start with prefix. The exact and case-sensitive matches, shorter names
+// and more imported packages come first.
+func (x *Index) LookupPrefix(prefix string, n int) []Suggestion {
+	if prefix == "" || n <= 0 {
+		return nil
+	}
+	x.suggest.once.Do(func() { x.suggest.build(x) })
+	entries := x.suggest.lookup(prefix)
+
+	// select the n best entries, one per identifier or package
+	top := make([]rankedEntry, 0, n)
+	for i := range entries {
+		e := &entries[i]
+		r := 0
+		name := e.id.Name
+		if e.kind == PackageClause {
+			name = e.id.Path
+		}
+		if !strings.HasPrefix(name, prefix) && !strings.HasPrefix(name[strings.LastIndex(name, ".")+1:], prefix) {
+			r += 2 // case-folded match
+		}
+		if e.key != canonical(prefix) {
+			r++ // not exact
+		}
+		re := rankedEntry{e, r, x.importCount[e.id.Path], i}
+		if len(top) == n && !re.less(&top[n-1]) {
+			continue
+		}
+		j := 0
+		for j < len(top) && !re.same(&top[j]) {
+			j++
+		}
+		switch {
+		case j < len(top) && !re.less(&top[j]):
+			continue // a better entry of the same suggestion
+		case j < len(top):
+			top = append(top[:j], top[j+1:]...)
+		case len(top) == n:
+			top = top[:n-1]
+		}
+		k := sort.Search(len(top), func(k int) bool { return re.less(&top[k]) })
+		top = append(top, rankedEntry{})
+		copy(top[k+1:], top[k:])
+		top[k] = re
+	}
+
+	list := make([]Suggestion, len(top))
+	for i, r := range top {
+		e := r.suggestEntry
+		s := Suggestion{
+			Kind:     spotKindNames[e.kind],
+			Name:     e.id.Name,
+			Path:     e.id.Path,
+			Package:  e.id.Package,
+			Synopsis: e.id.Doc,
+		}
+		if e.kind == PackageClause {
+			s.Name = e.id.Path
+		}
+		s.URL = s.url("")
+		list[i] = s
+	}
+	return list
+}
+
+// A rankedEntry is an entry of a prefix lookup, with its rank.
+type rankedEntry struct {
+	*suggestEntry
+	rank        int // 0 for an exact match
+	importCount int
+	index       int // in the lookup, for a stable order
+}
+
+func (a *rankedEntry) less(b *rankedEntry) bool {
+	if a.rank != b.rank {
+		return a.rank < b.rank
+	}
+	if la, lb := len(a.key), len(b.key); la != lb {
+		return la < lb
+	}
+	if a.importCount != b.importCount {
+		return a.importCount > b.importCount
+	}
+	return a.index < b.index
+}
+
+// same reports whether a and b are entries of the same suggestion.
+func (a *rankedEntry) same(b *rankedEntry) bool {
+	pa, pb := a.kind == PackageClause, b.kind == PackageClause
+	return pa == pb && a.id.Path == b.id.Path && (pa || a.id.Name == b.id.Name)
+}
+
+// url returns the documentation URL of s in lang.
+func (s *Suggestion) url(lang string) string {
+	u := "/pkg/" + s.Path + "/"
+	if lang != "" {
+		u += "?lang=" + url.QueryEscape(lang)
+	}
+	if s.Kind != "package" {
+		u += "#" + s.Name
+	}
+	return u
+}
+
+// HandleSuggest serves the suggestions for the prefix q, as JSON.
+// With format=opensearch, the suggestions are in the OpenSearch
+// suggestions format.
+func (p *Presentation) HandleSuggest(w http.ResponseWriter, r *http.Request) {
+	prefix := strings.TrimSpace(r.FormValue("q"))
+	lang := r.FormValue("lang")
+	n, err := strconv.Atoi(r.FormValue("n"))
+	if err != nil || n <= 0 {
+		n = defaultSuggestions
+	}
+	if n > maxSuggestions {
+		n = maxSuggestions
+	}
+
+	var list []Suggestion
+	if index, _ := p.Corpus.CurrentIndex(); index != nil {
+		list = index.LookupPrefix(prefix, n)
+	}
+	docLang := lang
+	if docLang == "" {
+		docLang = p.Lang
+	}
+	for i := range list {
+		s := &list[i]
+		if docLang != "" {
+			id := s.Name
+			if s.Kind == "package" {
+				id = ""
+			}
+			if text, ok := p.Corpus.translateDoc(docLang, s.Path, id); ok {
+				s.Synopsis = text
+			}
+		}
+		if lang != "" {
+			s.URL = s.url(lang)
+		}
+	}
+
+	var v interface{} = list
+	if r.FormValue("format") == "opensearch" {
+		// [query, [completions], [descriptions], [urls]]
+		names := []string{}
+		synopses := []string{}
+		urls := []string{}
+		for _, s := range list {
+			names = append(names, s.Name)
+			synopses = append(synopses, s.Synopsis)
+			urls = append(urls, baseURL(r)+s.URL)
+		}
+		v = []interface{}{prefix, names, synopses, urls}
+		w.Header().Set("Content-Type", "application/x-suggestions+json; charset=utf-8")
+	} else {
+		if list == nil {
+			v = []Suggestion{}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+// translateDoc returns the synopsis of the translated doc of the
+// identifier id of the package path, if any.
+func (c *Corpus) translateDoc(lang, path, id string) (string, bool) {
+	if c.TranslateDoc == nil {
+		return "", false
+	}
+	text, ok := c.TranslateDoc(lang, path, id)
+	if !ok || text == "" {
+		return "", false
+	}
+	return doc.Synopsis(text), true
+}
+
+// opensearchSuggest is the OpenSearch suggestions URL of the search
+// description, added if the SearchDescXML template has none.
+const opensearchSuggest = `<Url type="application/x-suggestions+json" template="{{.BaseURL}}/search/suggest?q={searchTerms}&amp;format=opensearch"/>`
+
+// addSearchSuggest adds the suggestions URL to the OpenSearch
+// description xml.
+func addSearchSuggest(xml []byte, baseURL string) []byte {
+	if bytes.Contains(xml, []byte("application/x-suggestions+json")) {
+		return xml
+	}
+	i := bytes.LastIndex(xml, []byte("</OpenSearchDescription>"))
+	if i < 0 {
+		return xml
+	}
+	u := strings.Replace(opensearchSuggest, "{{.BaseURL}}", baseURL, 1)
+	var buf bytes.Buffer
+	buf.Write(xml[:i])
+	buf.WriteString(u + "\n")
+	buf.Write(xml[i:])
+	return buf.Bytes()
+}
@@ -0,0 +1,114 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestLookupPrefix(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+	ix, _ := c.CurrentIndex()
+
+	names := func(list []Suggestion) []string {
+		var names []string
+		for _, s := range list {
+			names = append(names, s.Kind+":"+s.Name)
+		}
+		return names
+	}
+	for _, tt := range []struct {
+		prefix string
+		n      int
+		want   []string
+	}{
+		{"Fo", 10, []string{"type:Foo", "var:Foos", "package:foo"}},
+		{"fo", 10, []string{"package:foo", "type:Foo", "var:Foos"}},
+		{"Fo", 1, []string{"type:Foo"}},
+		{"fo", 2, []string{"package:foo", "type:Foo"}},
+		{"other/", 10, []string{"package:other/bar"}},
+		{"Ne", 10, []string{"func:New"}},
+		{"Zzz", 10, nil},
+		{"", 10, nil},
+	} {
+		if got := names(ix.LookupPrefix(tt.prefix, tt.n)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LookupPrefix(%q, %d) = %v, want %v", tt.prefix, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestHandleSuggest(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+	c.TranslateDoc = func(lang, importPath, id string) (string, bool) {
+		if lang == "zh_CN" && importPath == "foo" && id == "Foo" {
+			return "Foo 是一个类型. 更多说明.", true
+		}
+		return "", false
+	}
+	p := NewPresentation(c)
+	get := func(url string, header ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Host = "example.com"
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, req)
+		return w
+	}
+
+	var list []Suggestion
+	w := get("/search/suggest?q=Foo&lang=zh_CN")
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("%v\n%s", err, w.Body)
+	}
+	want := Suggestion{
+		Kind:     "type",
+		Name:     "Foo",
+		Path:     "foo",
+		Package:  "foo",
+		Synopsis: "Foo 是一个类型.",
+		URL:      "/pkg/foo/?lang=zh_CN#Foo",
+	}
+	if len(list) == 0 || list[0] != want {
+		t.Errorf("got %+v, want first %+v", list, want)
+	}
+
+	var os []interface{}
+	w = get("/search/suggest?q=Ne&format=opensearch")
+	if err := json.Unmarshal(w.Body.Bytes(), &os); err != nil {
+		t.Fatalf("%v\n%s", err, w.Body)
+	}
+	if len(os) != 4 || os[0] != "Ne" || !reflect.DeepEqual(os[1], []interface{}{"New"}) {
+		t.Errorf("got %v", os)
+	}
+	w = get("/search/suggest?q=Ne&format=opensearch", "X-Forwarded-Proto", "https")
+	if err := json.Unmarshal(w.Body.Bytes(), &os); err != nil {
+		t.Fatalf("%v\n%s", err, w.Body)
+	}
+	if len(os) != 4 || !reflect.DeepEqual(os[3], []interface{}{"https://example.com/pkg/foo/#New"}) {
+		t.Errorf("got %v", os)
+	}
+
+	// the search description has the suggestions URL
+	p.SearchDescXML = template.Must(template.New("opensearch.xml").Parse(
+		`<OpenSearchDescription><Url type="text/html" template="{{.BaseURL}}/search?q={searchTerms}"/></OpenSearchDescription>`))
+	w = get("/opensearch.xml")
+	if s := w.Body.String(); !strings.Contains(s, `<Url type="application/x-suggestions+json" template="http://example.com/search/suggest?q={searchTerms}&amp;format=opensearch"/>`) {
+		t.Errorf("got %s", s)
+	}
+	w = get("/opensearch.xml", "X-Forwarded-Proto", "https")
+	if s := w.Body.String(); !strings.Contains(s, `template="https://example.com/search?q={searchTerms}"`) {
+		t.Errorf("got %s", s)
+	}
+}
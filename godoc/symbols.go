@@ -0,0 +1,248 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the alphabetical index of the exported
+// identifiers of the corpus:
+//
+//	/symbols/			identifiers starting with the first letter
+//	/symbols/H?kind=func,type	functions and types starting with H
+//	/symbols/H?m=json		as JSON; m=text for plain text
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// symbolKinds are the kinds of the exported identifiers, as in SearchJSON.
+var symbolKinds = []string{"const", "func", "type", "var"}
+
+// A SymbolsJSON is the alphabetical index of exported identifiers,
+// as served in JSON.
+type SymbolsJSON struct {
+	Letters []string     `json:"letters"` // initial letters of the identifiers of the kinds
+	Symbols []Suggestion `json:"symbols"`
+}
+
+// A symbolTable is the sorted list of exported identifiers of an Index.
+type symbolTable struct {
+	once sync.Once
+	list []Suggestion
+}
+
+// Symbols returns the exported package-level identifiers of the index,
+// sorted by name and import path. The URLs are not set. The list is
+// built on first use and shared, it must not be modified.
+func (x *Index) Symbols() []Suggestion {
+	x.symbols.once.Do(func() { x.symbols.list = x.buildSymbols() })
+	return x.symbols.list
+}
+
+// buildSymbols returns the sorted list of exported identifiers of x.
+func (x *Index) buildSymbols() []Suggestion {
+	type key struct{ path, name string }
+	docs := make(map[key]Ident)
+	for _, kind := range []SpotKind{ConstDecl, TypeDecl, VarDecl, FuncDecl} {
+		for _, list := range x.idents[kind] {
+			for _, id := range list {
+				docs[key{id.Path, id.Name}] = id
+			}
+		}
+	}
+
+	var list []Suggestion
+	for path, exports := range x.exports {
+		for name, kind := range exports {
+			if !ast.IsExported(name) {
+				continue
+			}
+			s := Suggestion{
+				Kind:    spotKindNames[kind],
+				Name:    name,
+				Path:    path,
+				Package: path[strings.LastIndex(path, "/")+1:],
+			}
+			if id, ok := docs[key{path, name}]; ok {
+				s.Package = id.Package
+				s.Synopsis = id.Doc
+			}
+			list = append(list, s)
+		}
+	}
+	sort.Sort(symbolsByName(list))
+	return list
+}
+
+type symbolsByName []Suggestion
+
+func (s symbolsByName) Len() int      { return len(s) }
+func (s symbolsByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s symbolsByName) Less(i, j int) bool {
+	if s[i].Name != s[j].Name {
+		return s[i].Name < s[j].Name
+	}
+	return s[i].Path < s[j].Path
+}
+
+// initial returns the initial letter of the identifier name, in upper case.
+func initial(name string) string {
+	r, _ := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r))
+}
+
+// HandleSymbols serves the alphabetical index of the exported
+// identifiers starting with the initial letter in the URL path, by
+// default the first one, with a comma-separated list of kinds in the
+// form value kind. Only the served identifiers are translated.
+func (p *Presentation) HandleSymbols(w http.ResponseWriter, r *http.Request) {
+	lang := r.FormValue("lang")
+	letter := strings.ToUpper(strings.TrimPrefix(r.URL.Path, "/symbols/"))
+	kinds := make(map[string]bool)
+	for _, k := range strings.Split(r.FormValue("kind"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			kinds[k] = true
+		}
+	}
+
+	var all []Suggestion
+	if index, _ := p.Corpus.CurrentIndex(); index != nil {
+		all = index.Symbols()
+	}
+	res := SymbolsJSON{Letters: []string{}, Symbols: []Suggestion{}}
+	for _, s := range all {
+		if len(kinds) > 0 && !kinds[s.Kind] {
+			continue
+		}
+		l := initial(s.Name)
+		if n := len(res.Letters); n == 0 || res.Letters[n-1] != l {
+			res.Letters = append(res.Letters, l)
+		}
+		if letter == "" {
+			letter = l
+		}
+		if l == letter {
+			res.Symbols = append(res.Symbols, s)
+		}
+	}
+
+	docLang := lang
+	if docLang == "" {
+		docLang = p.Lang
+	}
+	for i := range res.Symbols {
+		s := &res.Symbols[i]
+		if docLang != "" {
+			if text, ok := p.Corpus.translateDoc(docLang, s.Path, s.Name); ok {
+				s.Synopsis = text
+			}
+		}
+		s.URL = s.url(lang)
+	}
+
+	mode := p.GetPageInfoMode(r)
+	switch {
+	case mode&JSON != 0:
+		data, err := json.MarshalIndent(res, "", "\t")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(data)
+	case mode&NoHTML != 0:
+		var buf bytes.Buffer
+		tw := tabwriter.NewWriter(&buf, 0, 8, 1, ' ', 0)
+		for _, s := range res.Symbols {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Name, s.Kind, s.Path, s.Synopsis)
+		}
+		tw.Flush()
+		p.ServeText(w, buf.Bytes())
+	default:
+		p.ServePage(w, Page{
+			Title:    p.Translate(lang, "Exported identifiers"),
+			Subtitle: letter,
+			Lang:     lang,
+			Body:     p.symbolsHTML(lang, letter, r.FormValue("kind"), &res),
+		})
+	}
+}
+
+// symbolsHTML returns the HTML body of the index of exported identifiers
+// res, with the links to the other letters and kinds.
+func (p *Presentation) symbolsHTML(lang, letter, kind string, res *SymbolsJSON) []byte {
+	esc := template.HTMLEscapeString
+	link := func(letter, kind string) string {
+		v := url.Values{}
+		if kind != "" {
+			v.Set("kind", kind)
+		}
+		if lang != "" {
+			v.Set("lang", lang)
+		}
+		u := "/symbols/" + letter
+		if len(v) > 0 {
+			u += "?" + v.Encode()
+		}
+		return esc(u)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<div id=\"symbols-nav\">\n<p>")
+	for i, l := range res.Letters {
+		if i > 0 {
+			buf.WriteString(" &middot;\n")
+		}
+		if l == letter {
+			fmt.Fprintf(&buf, "<b>%s</b>", esc(l))
+		} else {
+			fmt.Fprintf(&buf, `<a href="%s">%s</a>`, link(l, kind), esc(l))
+		}
+	}
+	buf.WriteString("</p>\n<p>")
+	all := p.Translate(lang, "All")
+	for i, k := range append([]string{""}, symbolKinds...) {
+		if i > 0 {
+			buf.WriteString(" &middot;\n")
+		}
+		name := k
+		if k == "" {
+			name = all
+		}
+		if k == kind {
+			fmt.Fprintf(&buf, "<b>%s</b>", esc(name))
+		} else {
+			fmt.Fprintf(&buf, `<a href="%s">%s</a>`, link(letter, k), esc(name))
+		}
+	}
+	buf.WriteString("</p>\n</div>\n")
+
+	last := ""
+	for _, s := range res.Symbols {
+		if l := initial(s.Name); l != last {
+			if last != "" {
+				buf.WriteString("</table>\n")
+			}
+			fmt.Fprintf(&buf, "<h2 id=\"%s\">%s</h2>\n<table class=\"dir\">\n", esc(l), esc(l))
+			last = l
+		}
+		fmt.Fprintf(&buf, "<tr><td><a href=\"%s\">%s</a></td><td>%s</td><td><a href=\"/pkg/%s/\">%s</a></td><td>%s</td></tr>\n",
+			esc(s.URL), esc(s.Name), esc(s.Kind), esc(s.Path), esc(s.Path), esc(s.Synopsis))
+	}
+	if last != "" {
+		buf.WriteString("</table>\n")
+	}
+	return buf.Bytes()
+}
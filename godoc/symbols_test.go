@@ -0,0 +1,102 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestSymbols(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+	x, _ := c.CurrentIndex()
+	var got []string
+	for _, s := range x.Symbols() {
+		got = append(got, s.Kind+":"+s.Path+"."+s.Name)
+	}
+	want := []string{"type:foo.Foo", "var:foo.Foos", "func:foo.New", "const:foo.Pi", "func:other/bar.X"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols() = %v; want %v", got, want)
+	}
+	if &x.Symbols()[0] != &x.Symbols()[0] {
+		t.Errorf("Symbols() rebuilt on each call")
+	}
+}
+
+func TestHandleSymbols(t *testing.T) {
+	c := newCorpus(t)
+	c.UpdateIndex()
+	translated := 0
+	c.TranslateDoc = func(lang, importPath, id string) (string, bool) {
+		translated++
+		if lang == "zh_CN" && importPath == "foo" && id == "Foo" {
+			return "Foo 是一个类型.", true
+		}
+		return "", false
+	}
+	p := NewPresentation(c)
+	p.GodocHTML = template.Must(template.New("godoc").Parse("{{.Title}}|{{printf \"%s\" .Body}}"))
+	get := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	var res SymbolsJSON
+	body := get("/symbols/f?kind=type,func&m=json&lang=zh_CN")
+	if err := json.Unmarshal([]byte(body), &res); err != nil {
+		t.Fatalf("%v\n%s", err, body)
+	}
+	if want := []string{"F", "N", "X"}; !reflect.DeepEqual(res.Letters, want) {
+		t.Errorf("letters = %v; want %v", res.Letters, want)
+	}
+	want := []Suggestion{{
+		Kind:     "type",
+		Name:     "Foo",
+		Path:     "foo",
+		Package:  "foo",
+		Synopsis: "Foo 是一个类型.",
+		URL:      "/pkg/foo/?lang=zh_CN#Foo",
+	}}
+	if !reflect.DeepEqual(res.Symbols, want) {
+		t.Errorf("symbols = %+v; want %+v", res.Symbols, want)
+	}
+
+	// the first letter by default
+	if body := get("/symbols/?m=text"); !strings.HasPrefix(body, "Foo  type foo Foo is stuff.\n") || strings.Count(body, "\n") != 2 {
+		t.Errorf("text = %q", body)
+	}
+
+	// only the served identifiers are translated
+	translated = 0
+	get("/symbols/P?lang=zh_CN")
+	if translated != 1 {
+		t.Errorf("%d identifiers translated; want 1", translated)
+	}
+
+	body = get("/symbols/P")
+	for _, s := range []string{
+		"Exported identifiers|",
+		`<a href="/symbols/F">F</a>`,
+		`<b>P</b>`,
+		`<a href="/symbols/P?kind=func">func</a>`,
+		`<h2 id="P">P</h2>`,
+		`<a href="/pkg/foo/#Pi">Pi</a>`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Errorf("page does not contain %s:\n%s", s, body)
+		}
+	}
+	if strings.Contains(body, `#Foo"`) {
+		t.Errorf("page lists identifiers of other letters:\n%s", body)
+	}
+}
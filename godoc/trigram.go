@@ -0,0 +1,386 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the trigram index of the full text index, in the
+// style of Russ Cox's codesearch: for each trigram (sequence of three
+// bytes) the posting list of the files containing it. A regular
+// expression is translated into a query of trigrams, which must be
+// present in any matching text; only the files satisfying the query
+// are searched with the regular expression itself.
+//
+// The trigrams are case-folded (ASCII only), such that case-insensitive
+// queries can be narrowed as well.
+
+import (
+	"go/token"
+	"regexp"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/godoc/util"
+)
+
+// A trigramIndex maps trigrams to the files containing them. The files
+// are identified by their index in Bases, in file set order.
+type trigramIndex struct {
+	Bases []int               // file set base of each file
+	Posts map[uint32][]uint32 // trigram => sorted file ids
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func trigramOf(a, b, c byte) uint32 {
+	return uint32(lowerASCII(a))<<16 | uint32(lowerASCII(b))<<8 | uint32(lowerASCII(c))
+}
+
+// newTrigramIndex returns the trigram index of the files of fset, whose
+// contents are at the offsets of their base in src.
+func newTrigramIndex(fset *token.FileSet, src []byte, throttle *util.Throttle) *trigramIndex {
+	t := &trigramIndex{Posts: make(map[uint32][]uint32)}
+	fset.Iterate(func(f *token.File) bool {
+		id := uint32(len(t.Bases))
+		t.Bases = append(t.Bases, f.Base())
+		data := src[f.Base() : f.Base()+f.Size()]
+		for i := 0; i+3 <= len(data); i++ {
+			tri := trigramOf(data[i], data[i+1], data[i+2])
+			list := t.Posts[tri]
+			if n := len(list); n > 0 && list[n-1] == id {
+				continue // already seen in this file
+			}
+			t.Posts[tri] = append(list, id)
+		}
+		if throttle != nil {
+			throttle.Throttle()
+		}
+		return true
+	})
+	return t
+}
+
+// ----------------------------------------------------------------------------
+// Queries
+
+type trigramOp int
+
+const (
+	qAll  trigramOp = iota // all files
+	qNone                  // no file
+	qAnd                   // all of the trigrams and subqueries
+	qOr                    // any of the trigrams and subqueries
+)
+
+// A trigramQuery is a boolean query of trigrams.
+type trigramQuery struct {
+	op       trigramOp
+	trigrams []uint32
+	sub      []*trigramQuery
+}
+
+var (
+	allQuery  = &trigramQuery{op: qAll}
+	noneQuery = &trigramQuery{op: qNone}
+)
+
+// and returns the query matching both q and r.
+func (q *trigramQuery) and(r *trigramQuery) *trigramQuery {
+	switch {
+	case q.op == qAll || r.op == qNone:
+		return r
+	case r.op == qAll || q.op == qNone:
+		return q
+	}
+	return &trigramQuery{op: qAnd, sub: []*trigramQuery{q, r}}
+}
+
+// or returns the query matching either q or r.
+func (q *trigramQuery) or(r *trigramQuery) *trigramQuery {
+	switch {
+	case q.op == qAll || r.op == qNone:
+		return q
+	case r.op == qAll || q.op == qNone:
+		return r
+	}
+	return &trigramQuery{op: qOr, sub: []*trigramQuery{q, r}}
+}
+
+// stringQuery returns the query matching the texts containing s.
+func stringQuery(s string) *trigramQuery {
+	if len(s) < 3 {
+		return allQuery
+	}
+	q := &trigramQuery{op: qAnd}
+	for i := 0; i+3 <= len(s); i++ {
+		q.trigrams = append(q.trigrams, trigramOf(s[i], s[i+1], s[i+2]))
+	}
+	return q
+}
+
+const (
+	maxExact = 16 // maximum number of strings of an exact set
+	maxClass = 8  // maximum number of runes of an enumerated class
+)
+
+// An rxInfo is the result of the analysis of a regular expression:
+// the exact set of strings it matches if small, or else a query that
+// the texts it matches satisfy.
+type rxInfo struct {
+	exact []string // if not nil, the lower-cased strings matched
+	match *trigramQuery
+}
+
+func exactInfo(list ...string) rxInfo { return rxInfo{exact: list} }
+
+var anyInfo = rxInfo{match: allQuery}
+
+// query returns the query of the texts matched by info.
+func (info rxInfo) query() *trigramQuery {
+	if info.exact == nil {
+		return info.match
+	}
+	q := noneQuery
+	for _, s := range info.exact {
+		q = q.or(stringQuery(s))
+	}
+	return q
+}
+
+func addUnique(list []string, s string) []string {
+	for _, x := range list {
+		if x == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// concat returns the info of the concatenation of a and b.
+func concat(a, b rxInfo) rxInfo {
+	if a.exact != nil && b.exact != nil && len(a.exact)*len(b.exact) <= maxExact {
+		var list []string
+		for _, x := range a.exact {
+			for _, y := range b.exact {
+				list = addUnique(list, x+y)
+			}
+		}
+		return exactInfo(list...)
+	}
+	return rxInfo{match: a.query().and(b.query())}
+}
+
+// alternate returns the info of the alternation of a and b.
+func alternate(a, b rxInfo) rxInfo {
+	if a.exact != nil && b.exact != nil && len(a.exact)+len(b.exact) <= maxExact {
+		list := append([]string(nil), a.exact...)
+		for _, s := range b.exact {
+			list = addUnique(list, s)
+		}
+		return exactInfo(list...)
+	}
+	return rxInfo{match: a.query().or(b.query())}
+}
+
+// runeInfo returns the info of the rune r, and of its case variants
+// if fold is set.
+func runeInfo(r rune, fold bool) rxInfo {
+	var list []string
+	add := func(r rune) {
+		var buf [utf8.UTFMax]byte
+		n := utf8.EncodeRune(buf[:], r)
+		for i := range buf[:n] {
+			buf[i] = lowerASCII(buf[i])
+		}
+		list = addUnique(list, string(buf[:n]))
+	}
+	add(r)
+	if fold {
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			add(f)
+		}
+	}
+	return exactInfo(list...)
+}
+
+// analyze returns the info of the regular expression re.
+func analyze(re *syntax.Regexp) rxInfo {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return rxInfo{match: noneQuery}
+	case syntax.OpEmptyMatch,
+		syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return exactInfo("")
+	case syntax.OpLiteral:
+		info := exactInfo("")
+		for _, r := range re.Rune {
+			info = concat(info, runeInfo(r, re.Flags&syntax.FoldCase != 0))
+		}
+		return info
+	case syntax.OpCharClass:
+		n := 0
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			n += int(re.Rune[i+1]-re.Rune[i]) + 1
+		}
+		if n == 0 || n > maxClass {
+			return anyInfo
+		}
+		info := rxInfo{exact: []string{}}
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				info = alternate(info, runeInfo(r, false))
+			}
+		}
+		return info
+	case syntax.OpCapture:
+		return analyze(re.Sub[0])
+	case syntax.OpQuest:
+		return alternate(exactInfo(""), analyze(re.Sub[0]))
+	case syntax.OpPlus:
+		return rxInfo{match: analyze(re.Sub[0]).query()}
+	case syntax.OpRepeat:
+		if re.Min == 0 {
+			return anyInfo
+		}
+		return rxInfo{match: analyze(re.Sub[0]).query()}
+	case syntax.OpConcat:
+		info := exactInfo("")
+		for _, sub := range re.Sub {
+			info = concat(info, analyze(sub))
+		}
+		return info
+	case syntax.OpAlternate:
+		info := analyze(re.Sub[0])
+		for _, sub := range re.Sub[1:] {
+			info = alternate(info, analyze(sub))
+		}
+		return info
+	}
+	// OpAnyChar, OpAnyCharNotNL, OpStar
+	return anyInfo
+}
+
+// regexpQuery returns the trigram query of the texts matched by r.
+func regexpQuery(r *regexp.Regexp) *trigramQuery {
+	re, err := syntax.Parse(r.String(), syntax.Perl)
+	if err != nil {
+		return allQuery
+	}
+	return analyze(re.Simplify()).query()
+}
+
+// ----------------------------------------------------------------------------
+// Lookup
+
+// intersectIds returns the file ids in both sorted lists.
+func intersectIds(a, b []uint32) []uint32 {
+	var list []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			list = append(list, a[i])
+			i++
+			j++
+		}
+	}
+	return list
+}
+
+// unionIds returns the file ids in any of the sorted lists.
+func unionIds(a, b []uint32) []uint32 {
+	list := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			list = append(list, a[i])
+			i++
+		case a[i] > b[j]:
+			list = append(list, b[j])
+			j++
+		default:
+			list = append(list, a[i])
+			i++
+			j++
+		}
+	}
+	list = append(list, a[i:]...)
+	return append(list, b[j:]...)
+}
+
+// files returns the sorted ids of the files satisfying q. If all is
+// set, all files do and ids is nil.
+func (t *trigramIndex) files(q *trigramQuery) (ids []uint32, all bool) {
+	switch q.op {
+	case qAll:
+		return nil, true
+	case qNone:
+		return nil, false
+	case qAnd:
+		all = true
+		for _, tri := range q.trigrams {
+			if all {
+				ids, all = t.Posts[tri], false
+			} else {
+				ids = intersectIds(ids, t.Posts[tri])
+			}
+			if len(ids) == 0 {
+				return nil, false
+			}
+		}
+		for _, sub := range q.sub {
+			list, subAll := t.files(sub)
+			switch {
+			case subAll:
+				continue
+			case all:
+				ids, all = list, false
+			default:
+				ids = intersectIds(ids, list)
+			}
+			if len(ids) == 0 {
+				return nil, false
+			}
+		}
+		return ids, all
+	case qOr:
+		for _, tri := range q.trigrams {
+			ids = unionIds(ids, t.Posts[tri])
+		}
+		for _, sub := range q.sub {
+			list, subAll := t.files(sub)
+			if subAll {
+				return nil, true
+			}
+			ids = unionIds(ids, list)
+		}
+		return ids, false
+	}
+	panic("unreachable")
+}
+
+// candidates returns the bases of the files that may contain matches
+// of r.
+func (t *trigramIndex) candidates(r *regexp.Regexp) []int {
+	ids, all := t.files(regexpQuery(r))
+	if all {
+		return t.Bases
+	}
+	bases := make([]int, len(ids))
+	for i, id := range ids {
+		bases[i] = t.Bases[id]
+	}
+	return bases
+}
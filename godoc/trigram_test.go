@@ -0,0 +1,112 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"bytes"
+	"go/token"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var trigramTexts = []string{
+	"var Foos []Foo",
+	"package bar\n\nfunc X() {}\n",
+	"Whitelisted text file.\n",
+	"ReadAll reads from r until an error or EOF",
+	"Kelvin: 273K, straße",
+	"ab",
+}
+
+// newTestTrigramIndex returns the file set, sources and trigram index
+// of the texts, laid out as by the Indexer.
+func newTestTrigramIndex(texts []string) (*token.FileSet, []byte, *trigramIndex) {
+	fset := token.NewFileSet()
+	var src []byte
+	for i, text := range texts {
+		src = append(src, 0)
+		f := fset.AddFile(strconv.Itoa(i), fset.Base(), len(text))
+		f.SetLinesForContent([]byte(text))
+		src = append(src, text...)
+	}
+	return fset, src, newTrigramIndex(fset, src, nil)
+}
+
+func TestTrigramCandidates(t *testing.T) {
+	fset, src, ix := newTestTrigramIndex(trigramTexts)
+	for _, test := range []struct {
+		rx   string
+		want int // number of candidates, or -1
+	}{
+		{`Foos`, 1},
+		{`(?i)foos`, 1},
+		{`foos`, 1}, // the trigrams are case-folded
+		{`Foos|func`, 2},
+		{`Fo+s`, -1},
+		{`F[aeiou]o`, 1},
+		{`Read.*EOF`, 1},
+		{`Read(All|Dir)`, 1},
+		{`(?i)kelvin: 273k`, 1},
+		{`stra(ß|ss)e`, 1},
+		{`x{2,}`, -1},
+		{`^package`, 1},
+		{`zzz`, 0},
+		{`ab`, len(trigramTexts)},
+		{`.`, len(trigramTexts)},
+	} {
+		r := regexp.MustCompile(test.rx)
+		bases := ix.candidates(r)
+		if test.want >= 0 && len(bases) != test.want {
+			t.Errorf("%s: got %d candidates; want %d", test.rx, len(bases), test.want)
+		}
+		// all files with matches must be candidates
+		have := make(map[int]bool)
+		for _, base := range bases {
+			have[base] = true
+		}
+		fset.Iterate(func(f *token.File) bool {
+			if r.Match(src[f.Base():f.Base()+f.Size()]) && !have[f.Base()] {
+				t.Errorf("%s: file %s matches but is not a candidate", test.rx, f.Name())
+			}
+			return true
+		})
+	}
+}
+
+func TestLookupRegexpTrigrams(t *testing.T) {
+	c := newCorpus(t)
+	c.IndexFullText = true
+	c.UpdateIndex()
+	ix, _ := c.CurrentIndex()
+	if ix == nil {
+		t.Fatal("no index")
+	}
+	found, result := ix.LookupRegexp(regexp.MustCompile(`Foo\b`), 10)
+	want := []FileLines{{"/src/foo/foo.go", []int{8, 10, 11, 13, 14}}}
+	if found != 5 || !reflect.DeepEqual(result, want) {
+		t.Errorf("LookupRegexp = %d, %v; want 5, %v", found, result, want)
+	}
+	if found, _ := ix.LookupRegexp(regexp.MustCompile(`Foo\b`), 3); found != 3 {
+		t.Errorf("LookupRegexp with limit 3 found %d", found)
+	}
+
+	// the trigram index is persisted
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	ix2 := new(Index)
+	if _, err := ix2.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ix2.text[0].trigrams, ix.text[0].trigrams) {
+		t.Errorf("trigram index differs after ReadFrom")
+	}
+	if found2, result2 := ix2.LookupRegexp(regexp.MustCompile(`Foo\b`), 10); found2 != found || !reflect.DeepEqual(result2, result) {
+		t.Errorf("LookupRegexp after ReadFrom = %d, %v; want %d, %v", found2, result2, found, result)
+	}
+}
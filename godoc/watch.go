@@ -0,0 +1,193 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+// This file contains the watching of the file system: when files
+// change under the watched directories of the operating system, the
+// directory tree, the metadata and the index are refreshed, and the
+// translations are reloaded if they changed. The changes are watched
+// with inotify on Linux, and by scanning the directories periodically
+// otherwise.
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 10 * time.Second
+	watchDelay           = time.Second // time for the changes to settle
+	maxWatchDelays       = 10          // maximum number of watchDelays per refresh
+)
+
+// ignoredName reports whether changes of the file or directory name
+// are ignored, such as those of version control or editor files.
+func ignoredName(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")
+}
+
+// RunWatcher runs forever, refreshing the directory tree, the metadata
+// and the index when files change under the directories roots of the
+// operating system. The roots are the directories bound in the corpus
+// file system. The translations are reloaded when files change under
+// the TranslationRoots. It should be launched in a goroutine.
+func (c *Corpus) RunWatcher(roots []string) {
+	if len(c.TranslationRoots) > 0 {
+		go c.watch(c.TranslationRoots, c.refreshTranslations)
+	}
+	c.watch(roots, c.refreshFS)
+}
+
+// watch runs forever, calling refresh when files changed under roots,
+// once the changes settled.
+func (c *Corpus) watch(roots []string, refresh func()) {
+	changes := make(chan bool, 1)
+	notify := func() {
+		select {
+		case changes <- true:
+		default:
+		}
+	}
+	go func() {
+		err := watchOS(roots, notify)
+		interval := c.WatchInterval
+		if interval <= 0 {
+			interval = defaultWatchInterval
+		}
+		log.Printf("watching file system: %v; scanning every %v", err, interval)
+		watchScan(roots, interval, notify)
+	}()
+
+	for range changes {
+		// wait until the changes settle
+		for i := 0; i < maxWatchDelays; i++ {
+			select {
+			case <-changes:
+				continue
+			case <-time.After(watchDelay):
+			}
+			break
+		}
+		if c.Verbose {
+			log.Printf("file system changed under %v", roots)
+		}
+		refresh()
+	}
+}
+
+// refreshFS refreshes the directory tree after a change of the file
+// system, which invalidates the index and refreshes the metadata, and
+// wakes up the indexer.
+func (c *Corpus) refreshFS() {
+	if err := c.initFSTree(); err != nil {
+		log.Printf("refreshing directory tree: %v", err)
+		return
+	}
+	select {
+	case c.indexSignal <- true:
+	default:
+	}
+}
+
+// refreshTranslations drops the loaded translations, their signature
+// and the cached per-language /doc trees after a change of the
+// translation files, and refreshes the file system. The index is
+// rebuilt if its options include the signature of the translations.
+func (c *Corpus) refreshTranslations() {
+	if c.ReloadTranslations != nil {
+		c.ReloadTranslations()
+	}
+	c.langMu.Lock()
+	c.langFS = nil
+	c.langMetadata = nil
+	c.langMu.Unlock()
+	c.trSigMu.Lock()
+	c.trSigOK = false
+	c.trSigMu.Unlock()
+	c.refreshFS()
+}
+
+// translationsSignature returns the content signature of the files
+// under c.TranslationRoots, computed on first use and after a change
+// of the translation files.
+func (c *Corpus) translationsSignature() uint64 {
+	c.trSigMu.Lock()
+	defer c.trSigMu.Unlock()
+	if !c.trSigOK {
+		c.trSig = contentSignature(c.TranslationRoots)
+		c.trSigOK = true
+	}
+	return c.trSig
+}
+
+// scanSignature returns the signature of the files and directories
+// under roots: their names, and the sizes and modification times of
+// the files.
+func scanSignature(roots []string) uint64 {
+	return signature(roots, true)
+}
+
+// contentSignature returns the signature of the files and directories
+// under roots that does not depend on where the roots are nor on when
+// the files were written: their names relative to the roots, and the
+// sizes of the files.
+func contentSignature(roots []string) uint64 {
+	return signature(roots, false)
+}
+
+func signature(roots []string, modTimes bool) uint64 {
+	h := fnv.New64a()
+	for i, root := range roots {
+		if !modTimes {
+			fmt.Fprintf(h, "root %d\n", i)
+		}
+		filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return nil // ignore unreadable files
+			}
+			if path != root && ignoredName(fi.Name()) {
+				if fi.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			name := path
+			if !modTimes {
+				if rel, err := filepath.Rel(root, path); err == nil {
+					name = filepath.ToSlash(rel)
+				}
+			}
+			switch {
+			case fi.IsDir():
+				// changes of the directory are those of its entries
+				fmt.Fprintf(h, "%s\n", name)
+			case modTimes:
+				fmt.Fprintf(h, "%s %d %d\n", name, fi.Size(), fi.ModTime().UnixNano())
+			default:
+				fmt.Fprintf(h, "%s %d\n", name, fi.Size())
+			}
+			return nil
+		})
+	}
+	return h.Sum64()
+}
+
+// watchScan runs forever, calling notify when the files under roots
+// changed, by scanning them every interval.
+func watchScan(roots []string, interval time.Duration, notify func()) {
+	sig := scanSignature(roots)
+	for {
+		time.Sleep(interval)
+		if s := scanSignature(roots); s != sig {
+			sig = s
+			notify()
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+package godoc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// An inotify watches directories with inotify.
+type inotify struct {
+	fd   int
+	dirs map[int32]string // watch descriptor => directory
+}
+
+// addTree watches the directory root and its subdirectories.
+func (w *inotify) addTree(root string) error {
+	return filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.IsDir() {
+			return nil
+		}
+		if path != root && ignoredName(fi.Name()) {
+			return filepath.SkipDir
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err == syscall.ENOENT {
+			return nil // already removed
+		}
+		if err != nil {
+			return os.NewSyscallError("inotify_add_watch", err)
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+}
+
+// watchOS calls notify when the files under roots change, until an
+// error occurs.
+func watchOS(roots []string, notify func()) error {
+	fd, err := syscall.InotifyInit()
+	if err != nil {
+		return os.NewSyscallError("inotify_init", err)
+	}
+	defer syscall.Close(fd)
+	w := &inotify{fd: fd, dirs: make(map[int32]string)}
+	for _, root := range roots {
+		if err := w.addTree(root); err != nil {
+			return err
+		}
+	}
+
+	var buf [64 * (syscall.SizeofInotifyEvent + syscall.PathMax)]byte
+	for {
+		n, err := syscall.Read(fd, buf[:])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return os.NewSyscallError("read", err)
+		}
+		changed := false
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			name := string(buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)])
+			name = strings.TrimRight(name, "\x00")
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			dir, ok := w.dirs[ev.Wd]
+			switch {
+			case ev.Mask&syscall.IN_Q_OVERFLOW != 0:
+				changed = true // events were lost
+			case ev.Mask&syscall.IN_IGNORED != 0:
+				delete(w.dirs, ev.Wd) // directory removed
+			case !ok || ignoredName(name):
+			case ev.Mask&syscall.IN_ISDIR != 0 && ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+				if err := w.addTree(filepath.Join(dir, name)); err != nil {
+					return err
+				}
+				changed = true
+			default:
+				changed = true
+			}
+		}
+		if changed {
+			notify()
+		}
+	}
+}
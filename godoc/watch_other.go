@@ -0,0 +1,15 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !linux appengine
+
+package godoc
+
+import "errors"
+
+// watchOS returns an error, the file system notifications are not
+// supported.
+func watchOS(roots []string, notify func()) error {
+	return errors.New("file system notifications not supported")
+}
@@ -0,0 +1,241 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package godoc
+
+import (
+	"fmt"
+	"go/doc"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/godoc/vfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+// testWatch checks that the watcher started by watch notices the
+// creation of a package, of a directory, of a file in the new directory
+// and a change. The files are renamed into place, so that each step
+// makes a single change.
+func testWatch(t *testing.T, watch func(root string, notify func()) error) {
+	root, err := ioutil.TempDir("", "godoc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	changes := make(chan bool, 100)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- watch(root, func() { changes <- true })
+	}()
+
+	write := func(name, src string) {
+		path := filepath.Join(root, name)
+		tmp := filepath.Join(filepath.Dir(path), ".tmp")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(tmp, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	timeout := time.After(10 * time.Second)
+	wait := func(what string) {
+		select {
+		case err := <-errc:
+			t.Skipf("watcher not supported: %v", err)
+		case <-changes:
+		case <-timeout:
+			t.Fatalf("%s: no change", what)
+		}
+	}
+
+	// the watcher started once it notices a change
+	for i := 0; ; i++ {
+		write("ready", fmt.Sprint(i))
+		select {
+		case err := <-errc:
+			t.Skipf("watcher not supported: %v", err)
+		case <-changes:
+		case <-time.After(50 * time.Millisecond):
+			continue
+		case <-timeout:
+			t.Fatal("watcher not started")
+		}
+		break
+	}
+	for len(changes) > 0 {
+		<-changes
+	}
+
+	write("foo/foo.go", "package foo\n")
+	wait("new package")
+	if err := os.Mkdir(filepath.Join(root, "foo", "bar"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wait("new directory")
+	write("foo/bar/bar.go", "package bar\n")
+	wait("new file in new directory")
+	write("foo/foo.go", "package foo\n\nfunc Foo() {}\n")
+	wait("changed file")
+}
+
+func TestScanSignature(t *testing.T) {
+	root, err := ioutil.TempDir("", "godoc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(name, src string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("foo/foo.go", "package foo\n")
+	sig := scanSignature([]string{root})
+	write(".git/index", "ignored")
+	write("foo/foo.go~", "ignored")
+	if s := scanSignature([]string{root}); s != sig {
+		t.Errorf("signature changed by ignored files")
+	}
+	write("foo/foo.go", "package foo\n\nfunc Foo() {}\n")
+	if s := scanSignature([]string{root}); s == sig {
+		t.Errorf("signature unchanged by changed file")
+	}
+
+	// the content signature is that of a copy written later elsewhere
+	sig = contentSignature([]string{root})
+	copied, err := ioutil.TempDir("", "godoc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(copied)
+	root = copied
+	write("foo/foo.go", "package foo\n\nfunc Foo() {}\n")
+	old := time.Now().Add(-time.Hour)
+	os.Chtimes(filepath.Join(root, "foo/foo.go"), old, old)
+	if s := contentSignature([]string{root}); s != sig {
+		t.Errorf("content signature changed by the location or times of the files")
+	}
+	write("foo/foo.go", "package foo\n\nfunc Foo2() {}\n")
+	if s := contentSignature([]string{root}); s == sig {
+		t.Errorf("content signature unchanged by changed file")
+	}
+}
+
+func TestWatchOS(t *testing.T) {
+	testWatch(t, func(root string, notify func()) error {
+		return watchOS([]string{root}, notify)
+	})
+}
+
+func TestWatchScan(t *testing.T) {
+	testWatch(t, func(root string, notify func()) error {
+		watchScan([]string{root}, 20*time.Millisecond, notify)
+		return nil
+	})
+}
+
+func TestRefreshFS(t *testing.T) {
+	files := map[string]string{
+		"src/foo/foo.go": "package foo\n",
+	}
+	c := NewCorpus(mapfs.New(files))
+	initTestCorpus(t, c)
+	files["src/bar/bar.go"] = "package bar\n"
+	c.refreshFS()
+	select {
+	case <-c.indexSignal:
+	default:
+		t.Errorf("indexer not signalled")
+	}
+	tree, _ := c.fsTree.Get()
+	if tree.(*Directory).lookup("/src/bar") == nil {
+		t.Errorf("new package not in directory tree")
+	}
+}
+
+func TestRunIndexerOnce(t *testing.T) {
+	files := map[string]string{
+		"src/foo/foo.go": "package foo\n\nfunc Foo() {}\n",
+	}
+	c := NewCorpus(mapfs.New(files))
+	c.IndexEnabled = true
+	c.IndexInterval = -1
+	initTestCorpus(t, c)
+	go c.RunIndexer()
+
+	// wait for the index with the identifier name
+	wait := func(name string) {
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if ix, _ := c.CurrentIndex(); ix != nil && ix.words[name] != nil {
+				return
+			}
+		}
+		t.Fatalf("%s not indexed", name)
+	}
+	wait("Foo")
+
+	// the changes reported by the watcher are indexed
+	files["src/bar/bar.go"] = "package bar\n\nfunc Bar() {}\n"
+	c.refreshFS()
+	wait("Bar")
+}
+
+func TestRefreshTranslations(t *testing.T) {
+	root, err := ioutil.TempDir("", "godoc-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	c := NewCorpus(mapfs.New(map[string]string{
+		"src/foo/foo.go": "// Package foo is an example.\npackage foo\n",
+	}))
+	c.IndexEnabled = true
+	c.IndexLangs = []string{"zh_CN"}
+	c.TranslateDocPackage = func(pkg *doc.Package, lang ...string) *doc.Package { return pkg }
+	c.TranslationRoots = []string{root}
+	reloaded := false
+	c.ReloadTranslations = func() { reloaded = true }
+	c.DocumentFS = func(lang string) vfs.FileSystem {
+		return mapfs.New(map[string]string{"install.html": "<p>安装 Go</p>"})
+	}
+	initTestCorpus(t, c)
+	c.UpdateIndex()
+	ix1, _ := c.CurrentIndex()
+	c.langDocFS("zh_CN")
+
+	if err := ioutil.WriteFile(filepath.Join(root, "doc_zh_CN.go"), []byte("package foo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.refreshTranslations()
+	if !reloaded {
+		t.Errorf("translations not reloaded")
+	}
+	if c.langFS != nil {
+		t.Errorf("translated /doc trees still cached")
+	}
+	select {
+	case <-c.indexSignal:
+	default:
+		t.Errorf("indexer not signalled")
+	}
+	c.UpdateIndex()
+	if ix, _ := c.CurrentIndex(); ix == ix1 {
+		t.Errorf("index not rebuilt after a change of the translations")
+	}
+}
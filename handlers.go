@@ -15,6 +15,7 @@ package main
 import (
 	"log"
 	"net/http"
+	pathpkg "path"
 	"text/template"
 
 	"golang.org/x/tools/godoc/redirect"
@@ -35,6 +36,9 @@ func registerHandlers(pres *godoc.Presentation) {
 	http.HandleFunc("/doc/codewalk/", codewalk)
 	http.Handle("/doc/play/", pres.FileServer())
 	http.Handle("/robots.txt", pres.FileServer())
+	http.HandleFunc("/align/", alignHandler)
+	http.HandleFunc("/ref/spec/grammar", pres.ServeGrammar)
+	http.HandleFunc("/ref/spec/grammar/", pres.ServeGrammar)
 	http.Handle("/", pres)
 	http.Handle("/pkg/C/", redirect.Handler("/cmd/cgo/"))
 	redirect.Register(nil)
@@ -44,20 +48,23 @@ func readTemplate(name string) *template.Template {
 	if pres == nil {
 		panic("no global Presentation set yet")
 	}
-	path := "lib/godoc/" + name
+	t, err := readTemplateFS(fs, "lib/godoc/"+name, pres.FuncMap())
+	if err != nil {
+		log.Fatal("readTemplate: ", err)
+	}
+	return t
+}
 
+func readTemplateFS(fs vfs.FileSystem, path string, funcs template.FuncMap) (*template.Template, error) {
 	// use underlying file system fs to read the template file
 	// (cannot use template ParseFile functions directly)
 	data, err := vfs.ReadFile(fs, path)
 	if err != nil {
-		log.Fatal("readTemplate: ", err)
+		return nil, err
 	}
 	// be explicit with errors (for app engine use)
-	t, err := template.New(name).Funcs(pres.FuncMap()).Parse(string(data))
-	if err != nil {
-		log.Fatal("readTemplate: ", err)
-	}
-	return t
+	_, name := pathpkg.Split(path)
+	return template.New(name).Funcs(funcs).Parse(string(data))
 }
 
 func readTemplates(p *godoc.Presentation, html bool) {
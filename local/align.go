@@ -0,0 +1,286 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// AlignIssue kinds.
+const (
+	AlignMissing      = "missing"      // section or id of the english doc missing in the translation
+	AlignExtra        = "extra"        // section or id of the translation missing in the english doc
+	AlignCode         = "code"         // <pre> block differs from the english doc
+	AlignParagraphs   = "paragraphs"   // english paragraphs without translation
+	AlignUntranslated = "untranslated" // paragraph same as the english doc
+)
+
+// AlignIssue is a difference between a translated doc and its english source.
+type AlignIssue struct {
+	Kind        string
+	Section     string // section id or title, empty for the head of the doc
+	English     string // english text, if any
+	Translation string // translated text, if any
+}
+
+// Alignment is the result of aligning a translated doc with its english source.
+type Alignment struct {
+	Name     string // e.g. "/go_spec.html"
+	Sections int    // sections of the english doc
+	Missing  bool   // no translation of the english doc
+	Issues   []*AlignIssue
+}
+
+// docSection is a part of a HTML doc started by a heading.
+type docSection struct {
+	Key   string // heading id, or "#n" for the n-th heading without id
+	Title string
+	Pre   []string
+	Paras []string
+}
+
+var (
+	alignBlockRx = regexp.MustCompile(`(?i)<(h[1-6]|pre|p)(\s[^>]*)?>`)
+	alignIdRx    = regexp.MustCompile(`(?i)\sid\s*=\s*"([^"]*)"`)
+	alignTagRx   = regexp.MustCompile(`<[^>]*>`)
+	alignSpaceRx = regexp.MustCompile(`\s+`)
+
+	alignLineCommentRx  = regexp.MustCompile(`//[^\n]*`)
+	alignBlockCommentRx = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// AlignDocuments align the translated /doc HTML files of lang with
+// the english ones. The english docs without translation are reported
+// as Missing. It return an error if lang has no translated /doc tree.
+func AlignDocuments(lang string) ([]*Alignment, error) {
+	langs := DocumentLangs()
+	if i := sort.SearchStrings(langs, lang); lang == "" || i == len(langs) || langs[i] != lang {
+		return nil, fmt.Errorf("local: no translated /doc tree for %q", lang)
+	}
+	return alignFS(DocumentFS(""), DocumentFS(lang)), nil
+}
+
+func alignFS(english, translation vfs.FileSystem) []*Alignment {
+	var results []*Alignment
+	var walk func(dir string)
+	walk = func(dir string) {
+		fis, err := english.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, fi := range fis {
+			name := path.Join(dir, fi.Name())
+			if fi.IsDir() {
+				walk(name)
+				continue
+			}
+			if !strings.HasSuffix(name, ".html") {
+				continue
+			}
+			en, err := vfs.ReadFile(english, name)
+			if err != nil {
+				continue
+			}
+			tr, err := vfs.ReadFile(translation, name)
+			if err != nil {
+				results = append(results, &Alignment{
+					Name:     name,
+					Sections: len(parseDocSections(en)),
+					Missing:  true,
+				})
+				continue
+			}
+			results = append(results, AlignDocument(name, en, tr))
+		}
+	}
+	walk("/")
+	sort.Sort(byAlignName(results))
+	return results
+}
+
+type byAlignName []*Alignment
+
+func (p byAlignName) Len() int           { return len(p) }
+func (p byAlignName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byAlignName) Less(i, j int) bool { return p[i].Name < p[j].Name }
+
+// AlignDocument align the translated HTML doc with its english source,
+// by the element ids, the headings and the <pre> blocks.
+func AlignDocument(name string, english, translation []byte) *Alignment {
+	a := &Alignment{Name: name}
+	add := func(kind, section, en, tr string) {
+		a.Issues = append(a.Issues, &AlignIssue{kind, section, en, tr})
+	}
+
+	// element ids
+	enIds, trIds := docIds(english), docIds(translation)
+	for _, id := range enIds.list {
+		if !trIds.has[id] {
+			add(AlignMissing, id, "", "")
+		}
+	}
+	for _, id := range trIds.list {
+		if !enIds.has[id] {
+			add(AlignExtra, id, "", "")
+		}
+	}
+
+	// sections
+	enSections, trSections := parseDocSections(english), parseDocSections(translation)
+	a.Sections = len(enSections)
+	trIndex := make(map[string]*docSection)
+	for _, s := range trSections {
+		trIndex[s.Key] = s
+	}
+	enIndex := make(map[string]*docSection)
+	for _, s := range enSections {
+		enIndex[s.Key] = s
+	}
+
+	for _, en := range enSections {
+		tr := trIndex[en.Key]
+		if tr == nil {
+			if !enIds.has[en.Key] { // missing ids are reported above
+				add(AlignMissing, en.Key, en.Title, "")
+			}
+			continue
+		}
+		section := en.Key
+		for i, pre := range en.Pre {
+			switch {
+			case i >= len(tr.Pre):
+				add(AlignCode, section, pre, "")
+			case normalizeCode(pre) != normalizeCode(tr.Pre[i]):
+				add(AlignCode, section, pre, tr.Pre[i])
+			}
+		}
+		for i := len(en.Pre); i < len(tr.Pre); i++ {
+			add(AlignCode, section, "", tr.Pre[i])
+		}
+		for i := len(tr.Paras); i < len(en.Paras); i++ {
+			add(AlignParagraphs, section, en.Paras[i], "")
+		}
+		for i := 0; i < len(tr.Paras) && i < len(en.Paras); i++ {
+			if tr.Paras[i] == en.Paras[i] && hasLetter(en.Paras[i]) {
+				add(AlignUntranslated, section, en.Paras[i], tr.Paras[i])
+			}
+		}
+	}
+	for _, tr := range trSections {
+		if enIndex[tr.Key] == nil && !trIds.has[tr.Key] {
+			add(AlignExtra, tr.Key, "", tr.Title)
+		}
+	}
+	return a
+}
+
+type idSet struct {
+	list []string
+	has  map[string]bool
+}
+
+func docIds(src []byte) idSet {
+	ids := idSet{has: make(map[string]bool)}
+	for _, m := range alignIdRx.FindAllSubmatch(src, -1) {
+		id := string(m[1])
+		if !ids.has[id] {
+			ids.list = append(ids.list, id)
+			ids.has[id] = true
+		}
+	}
+	return ids
+}
+
+// parseDocSections split the HTML doc into sections by its headings.
+// The <p> end tags may be omitted.
+func parseDocSections(src []byte) []*docSection {
+	head := &docSection{Key: ""}
+	sections := []*docSection{head}
+	cur := head
+	anonymous := 0
+
+	locs := alignBlockRx.FindAllSubmatchIndex(src, -1)
+	end := 0 // end of the last block
+	for i, loc := range locs {
+		if loc[0] < end {
+			continue // inside a <pre> block
+		}
+		tag := strings.ToLower(string(src[loc[2]:loc[3]]))
+		attrs := ""
+		if loc[4] >= 0 {
+			attrs = string(src[loc[4]:loc[5]])
+		}
+
+		// the block ends at its end tag, or the next block
+		next := len(src)
+		if tag != "pre" {
+			for _, l := range locs[i+1:] {
+				if l[0] >= loc[1] {
+					next = l[0]
+					break
+				}
+			}
+		}
+		content := src[loc[1]:next]
+		if j := strings.Index(strings.ToLower(string(content)), "</"+tag+">"); j >= 0 {
+			content = content[:j]
+			end = loc[1] + j + len("</"+tag+">")
+		} else {
+			end = next
+		}
+
+		switch tag {
+		case "pre":
+			cur.Pre = append(cur.Pre, string(content))
+		case "p":
+			if s := plainText(content); s != "" {
+				cur.Paras = append(cur.Paras, s)
+			}
+		default: // heading
+			s := &docSection{Title: plainText(content)}
+			if m := alignIdRx.FindStringSubmatch(attrs); m != nil {
+				s.Key = m[1]
+			} else {
+				anonymous++
+				s.Key = "#" + strconv.Itoa(anonymous)
+			}
+			sections = append(sections, s)
+			cur = s
+		}
+	}
+	return sections
+}
+
+func plainText(s []byte) string {
+	text := alignTagRx.ReplaceAllString(string(s), "")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(alignSpaceRx.ReplaceAllString(text, " "))
+}
+
+// normalizeCode remove the comments and the spaces of the code,
+// the translations may translate the comments.
+func normalizeCode(s string) string {
+	s = html.UnescapeString(alignTagRx.ReplaceAllString(s, ""))
+	s = alignBlockCommentRx.ReplaceAllString(s, "")
+	s = alignLineCommentRx.ReplaceAllString(s, "")
+	return alignSpaceRx.ReplaceAllString(s, "")
+}
+
+func hasLetter(s string) bool {
+	for _, c := range s {
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' {
+			return true
+		}
+	}
+	return false
+}
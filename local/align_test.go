@@ -0,0 +1,122 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+const alignEnglish = `<!--{
+	"Title": "Example"
+}-->
+
+<h2 id="intro">Introduction</h2>
+<p>
+This is an example.
+<p>
+The second paragraph.
+
+<h2 id="code">Code</h2>
+<p>Some code:</p>
+<pre>
+// hello prints hello
+func hello() { fmt.Println("hello") }
+</pre>
+<pre>
+x := 1
+</pre>
+
+<h2 id="new">New section</h2>
+<p>Added recently.</p>
+`
+
+const alignTranslation = `<!--{
+	"Title": "例子"
+}-->
+
+<h2 id="intro">介绍</h2>
+<p>
+这是一个例子.
+
+<h2 id="code">代码</h2>
+<p>Some code:</p>
+<pre>
+// hello 打印 hello
+func hello() { fmt.Println("hello") }
+</pre>
+<pre>
+x := 2
+</pre>
+
+<h2 id="old">旧的章节</h2>
+`
+
+func TestAlignDocument(t *testing.T) {
+	a := AlignDocument("/example.html", []byte(alignEnglish), []byte(alignTranslation))
+	if a.Sections != 4 {
+		t.Errorf("Sections = %d, want 4", a.Sections)
+	}
+	var got [][2]string
+	for _, x := range a.Issues {
+		got = append(got, [2]string{x.Kind, x.Section})
+	}
+	want := [][2]string{
+		{AlignMissing, "new"},
+		{AlignExtra, "old"},
+		{AlignParagraphs, "intro"},
+		{AlignCode, "code"},
+		{AlignUntranslated, "code"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("issues = %v, want %v", got, want)
+	}
+}
+
+func TestParseDocSections(t *testing.T) {
+	sections := parseDocSections([]byte(alignEnglish))
+	if len(sections) != 4 {
+		t.Fatalf("len(sections) = %d, want 4", len(sections))
+	}
+	if s := sections[1]; s.Key != "intro" || s.Title != "Introduction" ||
+		!reflect.DeepEqual(s.Paras, []string{"This is an example.", "The second paragraph."}) {
+		t.Errorf("sections[1] = %+v", s)
+	}
+	if s := sections[2]; len(s.Pre) != 2 || len(s.Paras) != 1 {
+		t.Errorf("sections[2] = %+v", s)
+	}
+}
+
+func TestAlignFS(t *testing.T) {
+	english := mapfs.New(map[string]string{
+		"example.html":     alignEnglish,
+		"sub/missing.html": alignEnglish,
+		"style.css":        "",
+	})
+	translation := mapfs.New(map[string]string{
+		"example.html":  alignTranslation,
+		"obsolete.html": alignTranslation,
+	})
+	var got []string
+	for _, a := range alignFS(english, translation) {
+		got = append(got, fmt.Sprintf("%s %d %v %d", a.Name, a.Sections, a.Missing, len(a.Issues)))
+	}
+	want := []string{
+		"/example.html 4 false 5",
+		"/sub/missing.html 4 true 0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alignFS = %q; want %q", got, want)
+	}
+}
+
+func TestAlignDocumentsUnknownLang(t *testing.T) {
+	if _, err := AlignDocuments("xx_NOSUCH"); err == nil {
+		t.Errorf("AlignDocuments(xx_NOSUCH): no error")
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/doc"
+	"io"
+	"io/ioutil"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// A bundle file holds all translated entries of a language, so that
+// the translation files need not be parsed at run time. The file is
+//
+//	golangdoc-bundle <BundleVersion>\n
+//	<hex sha256 of the payload>\n
+//	<gob encoded Bundle>
+//
+// Bundles are named doc_$(lang).bundle in the translations root,
+// and generated by "docgen bundle".
+const (
+	BundleVersion = 1
+
+	bundleMagic = "golangdoc-bundle"
+)
+
+// Bundle is the precompiled translations of a language.
+type Bundle struct {
+	Version   int    // BundleVersion
+	Lang      string // e.g. "zh_CN"
+	GoVersion string // Go version of the translations, e.g. "go1.5"
+	GOOS      string // empty if the translations are the same on all targets
+	GOARCH    string // empty if the translations are the same on all targets
+	Packages  []*BundlePackage
+}
+
+// BundlePackage is the translated entries of a package.
+type BundlePackage struct {
+	ImportPath string
+	Name       string
+	Doc        string
+	Notes      map[string][]*doc.Note
+	Docs       map[string]string // map[id]doc, see DocText
+	Metas      map[string]*Meta  // map[id]meta, see LookupMeta
+}
+
+// BundleFilename return the bundle file name of lang.
+func BundleFilename(lang string) string {
+	return fmt.Sprintf("doc_%s.bundle", lang)
+}
+
+// MakeBundle return the bundle of the translated packages of lang,
+// parsed from the translation files of the current GOOS/GOARCH, see
+// SetTarget. The GOOS/GOARCH are recorded only if lang has translation
+// files specific to a target.
+func MakeBundle(lang string) *Bundle {
+	b := &Bundle{
+		Version:   BundleVersion,
+		Lang:      lang,
+		GoVersion: GoVersion(),
+	}
+	if hasTargetFiles(lang) {
+		b.GOOS, b.GOARCH = defaultGodocGoos, defaultGodocGoarch
+	}
+	for _, importPath := range TranslatedPackages(lang) {
+		pkg := Package(lang, importPath)
+		if pkg == nil {
+			continue
+		}
+		bp := &BundlePackage{
+			ImportPath: importPath,
+			Name:       pkg.Name,
+			Doc:        pkg.Doc,
+			Notes:      pkg.Notes,
+			Docs:       docIndex(pkg),
+			Metas:      make(map[string]*Meta),
+		}
+		for id := range bp.Docs {
+			if m := lookupMetaTable(lang, importPath, id); m != nil {
+				bp.Metas[id] = m
+			}
+		}
+		b.Packages = append(b.Packages, bp)
+	}
+	return b
+}
+
+// TranslatedPackages return the import paths of the packages which
+// have a translation file of lang, in sorted order.
+func TranslatedPackages(lang string) []string {
+	names := map[string]bool{
+		fmt.Sprintf("doc_%s_%s_%s.go", lang, defaultGodocGoos, defaultGodocGoarch): true,
+		fmt.Sprintf("doc_%s_%s.go", lang, defaultGodocGoarch):                      true,
+		fmt.Sprintf("doc_%s_%s.go", lang, defaultGodocGoos):                        true,
+		fmt.Sprintf("doc_%s.go", lang):                                             true,
+	}
+	seen := make(map[string]bool)
+	for _, dir := range localVersionDirs() {
+		for filename := range buildManifest(defaultLocalBaseFS, []string{dir}) {
+			dirname, name := path.Split(filename)
+			if names[name] {
+				seen[strings.Trim(strings.TrimPrefix(dirname, dir+"/src/"), "/")] = true
+			}
+		}
+	}
+	var pkgs []string
+	for importPath := range seen {
+		pkgs = append(pkgs, importPath)
+	}
+	sort.Strings(pkgs)
+	return pkgs
+}
+
+// hasTargetFiles reports whether lang has translation files specific
+// to a GOOS or a GOARCH, such as doc_zh_CN_linux.go. The files of the
+// languages with a longer name, such as doc_zh_CN.go for "zh", are
+// taken as specific too.
+func hasTargetFiles(lang string) bool {
+	prefix := fmt.Sprintf("doc_%s_", lang)
+	for _, files := range []map[string]bool{
+		buildManifest(defaultLocalBaseFS, localVersionDirs()),
+		buildManifest(defaultRootFS, []string{""}),
+	} {
+		for filename := range files {
+			if strings.HasPrefix(path.Base(filename), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteBundle write the bundle b to w.
+func WriteBundle(w io.Writer, b *Bundle) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(b); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if _, err := fmt.Fprintf(w, "%s %d\n%x\n", bundleMagic, b.Version, sum); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// ReadBundle read a bundle written by WriteBundle, and verify its
+// version and checksum.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	br := bufio.NewReader(r)
+	header, err := br.ReadString('\n')
+	if err != nil {
+		return nil, errors.New("local: bad bundle header")
+	}
+	var version int
+	if _, err := fmt.Sscanf(header, bundleMagic+" %d\n", &version); err != nil {
+		return nil, errors.New("local: bad bundle header")
+	}
+	if version != BundleVersion {
+		return nil, fmt.Errorf("local: bundle version %d, want %d", version, BundleVersion)
+	}
+	checksum, err := br.ReadString('\n')
+	if err != nil {
+		return nil, errors.New("local: bad bundle checksum")
+	}
+	payload, err := ioutil.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(payload)
+	if strings.TrimSpace(checksum) != hex.EncodeToString(sum[:]) {
+		return nil, errors.New("local: bundle checksum mismatch")
+	}
+
+	b := new(Bundle)
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(b); err != nil {
+		return nil, err
+	}
+	if b.Version != version {
+		return nil, fmt.Errorf("local: bundle version %d, want %d", b.Version, version)
+	}
+	return b, nil
+}
+
+// checkTarget return an error if the bundle b was not generated for
+// the Go version and the GOOS/GOARCH of the translations in use:
+// its entries would hide the version-specific translation files.
+// An empty GOOS or GOARCH matches all targets.
+func (b *Bundle) checkTarget() error {
+	if v := GoVersion(); b.GoVersion != v {
+		return fmt.Errorf("local: bundle for %q, want %q", b.GoVersion, v)
+	}
+	if b.GOOS != "" && b.GOOS != defaultGodocGoos || b.GOARCH != "" && b.GOARCH != defaultGodocGoarch {
+		return fmt.Errorf("local: bundle for %s/%s, want %s/%s", b.GOOS, b.GOARCH, defaultGodocGoos, defaultGodocGoarch)
+	}
+	return nil
+}
+
+// loadBundles read the bundles in the root of fs.
+// The bad bundles and the bundles of another target are logged and
+// skipped.
+func loadBundles(fs vfs.FileSystem) []*Bundle {
+	fis, err := fs.ReadDir("/")
+	if err != nil {
+		return nil
+	}
+	var bundles []*Bundle
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasPrefix(fi.Name(), "doc_") || !strings.HasSuffix(fi.Name(), ".bundle") {
+			continue
+		}
+		b, err := readBundleFile(fs, "/"+fi.Name())
+		if err == nil {
+			err = b.checkTarget()
+		}
+		if err != nil {
+			log.Printf("local: %s: %v", fi.Name(), err)
+			continue
+		}
+		bundles = append(bundles, b)
+	}
+	return bundles
+}
+
+func readBundleFile(fs vfs.FileSystem, name string) (*Bundle, error) {
+	rc, err := fs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ReadBundle(rc)
+}
+
+type bundleTranslater struct {
+	pkgs map[string]*BundlePackage // map[mapKey(lang, importPath, __pkg__)]..., guarded by tableMu
+}
+
+// NewBundleTranslater return a Translater of the package docs in bundles.
+func NewBundleTranslater(bundles ...*Bundle) Translater {
+	p := &bundleTranslater{pkgs: make(map[string]*BundlePackage)}
+	for _, b := range bundles {
+		for _, bp := range b.Packages {
+			p.pkgs[mapKey(b.Lang, bp.ImportPath, __pkg__)] = bp
+		}
+	}
+	return p
+}
+
+// dropBundlePackage remove the package from the registered bundles,
+// so that the translation file patched on disk takes precedence.
+func dropBundlePackage(lang, importPath string) {
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	for _, tr := range trList {
+		if p, ok := tr.(*bundleTranslater); ok {
+			delete(p.pkgs, mapKey(lang, importPath, __pkg__))
+		}
+	}
+}
+
+func (p *bundleTranslater) Static(lang string) vfs.FileSystem {
+	return nil
+}
+
+func (p *bundleTranslater) Document(lang string) vfs.FileSystem {
+	return nil
+}
+
+func (p *bundleTranslater) Blog(lang string) vfs.FileSystem {
+	return nil
+}
+
+func (p *bundleTranslater) Package(lang, importPath string, pkg ...*doc.Package) *doc.Package {
+	tableMu.RLock()
+	bp, _ := p.pkgs[mapKey(lang, importPath, __pkg__)]
+	tableMu.RUnlock()
+	if bp == nil {
+		return nil
+	}
+
+	// register the bundle entries, the package doc has no declarations
+	tableMu.Lock()
+	pkgDocTable[mapKey(lang, importPath, __pkg__)] = &doc.Package{
+		Doc:        bp.Doc,
+		Name:       bp.Name,
+		ImportPath: bp.ImportPath,
+		Notes:      bp.Notes,
+	}
+	for id, s := range bp.Docs {
+		pkgDocIndexTable[mapKey(lang, importPath, id)] = s
+	}
+	for id, m := range bp.Metas {
+		pkgMetaTable[mapKey(lang, importPath, id)] = m
+	}
+	loadedPkgTable[pkgKey{lang, importPath}] = true
+	tableMu.Unlock()
+
+	// retry Package func
+	return Package(lang, importPath, pkg...)
+}
@@ -0,0 +1,179 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"bytes"
+	"go/doc"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{
+		Version: BundleVersion,
+		Lang:    "xx_TEST",
+		Packages: []*BundlePackage{{
+			ImportPath: "bundle/foo",
+			Name:       "foo",
+			Doc:        "Package foo 是一个例子.\n",
+			Docs: map[string]string{
+				__doc__:   "Package foo 是一个例子.\n",
+				"Foo":     "Foo 是一个类型.\n",
+				"Foo.Bar": "Bar 方法.\n",
+			},
+			Metas: map[string]*Meta{
+				"Foo": {Translator: "chai2010", Status: StatusReviewed},
+			},
+		}},
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, testBundle()); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b, err := ReadBundle(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, testBundle()) {
+		t.Errorf("ReadBundle = %+v; want %+v", b, testBundle())
+	}
+
+	// corrupt the payload
+	data[len(data)-1] ^= 0xff
+	if _, err := ReadBundle(bytes.NewReader(data)); err == nil {
+		t.Errorf("ReadBundle: expected checksum error")
+	}
+	if _, err := ReadBundle(bytes.NewReader([]byte("golangdoc-bundle 999\n"))); err == nil {
+		t.Errorf("ReadBundle: expected version error")
+	}
+}
+
+func TestBundleTranslater(t *testing.T) {
+	tr := NewBundleTranslater(testBundle())
+	if pkg := tr.Package("xx_TEST", "bundle/missing"); pkg != nil {
+		t.Errorf("Package(missing) = %v; want nil", pkg)
+	}
+	pkg := tr.Package("xx_TEST", "bundle/foo")
+	if pkg == nil || pkg.Name != "foo" {
+		t.Fatalf("Package(bundle/foo) = %+v", pkg)
+	}
+	if s, ok := DocText("xx_TEST", "bundle/foo", "Foo.Bar"); !ok || s != "Bar 方法.\n" {
+		t.Errorf("DocText(Foo.Bar) = %q, %v", s, ok)
+	}
+	if m, ok := LookupMeta("xx_TEST", "bundle/foo", "Foo"); !ok || m.Status != StatusReviewed {
+		t.Errorf("LookupMeta(Foo) = %+v, %v", m, ok)
+	}
+}
+
+func TestLoadBundles(t *testing.T) {
+	files := make(map[string]string)
+	for name, target := range map[string][3]string{
+		"doc_xx_TEST.bundle":           {defaultGoVersion, defaultGodocGoos, defaultGodocGoarch},
+		"doc_yy_TEST_plan9_arm.bundle": {defaultGoVersion, "plan9", "arm"},
+		"doc_zz_TEST.bundle":           {"go0.1", defaultGodocGoos, defaultGodocGoarch},
+		"doc_ww_TEST.bundle":           {defaultGoVersion, "", ""}, // all targets
+		"doc_bad.bundle":               {},
+	} {
+		b := testBundle()
+		b.Lang = name[len("doc_") : len(name)-len(".bundle")]
+		b.GoVersion, b.GOOS, b.GOARCH = target[0], target[1], target[2]
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, b); err != nil {
+			t.Fatal(err)
+		}
+		files[name] = buf.String()
+	}
+	files["doc_bad.bundle"] = "golangdoc-bundle 1\n"
+
+	bundles := loadBundles(mapfs.New(files))
+	var langs []string
+	for _, b := range bundles {
+		langs = append(langs, b.Lang)
+	}
+	if want := []string{"ww_TEST", "xx_TEST"}; !reflect.DeepEqual(langs, want) {
+		t.Errorf("loadBundles = %v; want %v", langs, want)
+	}
+}
+
+func TestDropBundlePackage(t *testing.T) {
+	tr := NewBundleTranslater(testBundle())
+	trList = append(trList, tr)
+	defer func() { trList = trList[:len(trList)-1] }()
+
+	dropBundlePackage("xx_TEST", "bundle/foo")
+	if pkg := tr.Package("xx_TEST", "bundle/foo"); pkg != nil {
+		t.Errorf("Package(bundle/foo) after dropBundlePackage = %+v; want nil", pkg)
+	}
+}
+
+func TestReload(t *testing.T) {
+	saved := append([]Translater(nil), trList...)
+	defer func() { trList = saved }()
+	trList = append(trList, NewBundleTranslater(testBundle()))
+
+	if pkg := Package("xx_TEST", "bundle/foo"); pkg == nil {
+		t.Fatalf("Package(bundle/foo) = nil")
+	}
+	RegisterPackage("xx_TEST", &doc.Package{Name: "bar", ImportPath: "bundle/bar", Doc: "registered"})
+	defer unregisterPackage("xx_TEST", "bundle/bar")
+
+	Reload()
+	if _, ok := pkgDocTable[mapKey("xx_TEST", "bundle/foo", __pkg__)]; ok {
+		t.Errorf("bundle package still registered after Reload")
+	}
+	if _, ok := DocText("xx_TEST", "bundle/foo", ""); ok {
+		t.Errorf("bundle doc still found after Reload")
+	}
+	if pkg := Package("xx_TEST", "bundle/bar"); pkg == nil || pkg.Doc != "registered" {
+		t.Errorf("Package(bundle/bar) after Reload = %+v; want the registered package", pkg)
+	}
+}
+
+func TestReloadConcurrent(t *testing.T) {
+	saved := append([]Translater(nil), trList...)
+	defer func() { trList = saved }()
+	RegisterTranslater(NewBundleTranslater(testBundle()))
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			Package("xx_TEST", "bundle/foo")
+			DocText("xx_TEST", "bundle/foo", "Foo")
+			LookupMeta("xx_TEST", "bundle/foo", "Foo")
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		Reload()
+		RegisterTranslater(NewBundleTranslater(testBundle()))
+	}
+	<-done
+}
+
+func TestHasTargetFiles(t *testing.T) {
+	savedLocal, savedRoot := defaultLocalBaseFS, defaultRootFS
+	defer func() { defaultLocalBaseFS, defaultRootFS = savedLocal, savedRoot }()
+	defaultRootFS = getNameSpace(mapfs.New(map[string]string{
+		"src/fmt/print.go": "package fmt\n",
+	}), "/")
+	defaultLocalBaseFS = getNameSpace(mapfs.New(map[string]string{
+		"src/fmt/doc_xx_TEST.go":             "package fmt\n",
+		"src/syscall/doc_yy_TEST_windows.go": "package syscall\n",
+	}), "/")
+
+	for lang, want := range map[string]bool{"xx_TEST": false, "yy_TEST": true} {
+		if got := hasTargetFiles(lang); got != want {
+			t.Errorf("hasTargetFiles(%q) = %v; want %v", lang, got, want)
+		}
+	}
+}
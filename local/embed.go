@@ -0,0 +1,48 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"golang.org/x/tools/godoc/vfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+// The embedded files are generated by "docgen embed", and hold
+//
+//	translations/...   the translation files of the selected languages
+//	lib/godoc/...      the static overrides of lib/godoc
+//
+// If no translations root exists on disk, Init serves the embedded
+// translations, so that the default Translater needs no GOROOT files.
+var embedFS vfs.FileSystem // nil if none
+
+// RegisterEmbedFiles Register the embedded files.
+func RegisterEmbedFiles(files map[string]string) {
+	if len(files) == 0 {
+		embedFS = nil
+		return
+	}
+	embedFS = mapfs.New(files)
+}
+
+// initEmbedFS use the embedded translations if the translations
+// root does not exist, and the embedded static overrides if the
+// templates dir is not set.
+func initEmbedFS(goTemplateDir string) {
+	if embedFS == nil {
+		return
+	}
+	if _, err := defaultLocalBaseFS.Stat("/"); err != nil {
+		if fi, err := embedFS.Stat("/translations"); err == nil && fi.IsDir() {
+			defaultLocalBaseFS = getNameSpace(embedFS, "/translations")
+			defaultLocalRoot = ""
+		}
+	}
+	if goTemplateDir == "" {
+		if fi, err := embedFS.Stat("/lib/godoc"); err == nil && fi.IsDir() {
+			defaultStaticFS.Bind("/", embedFS, "/lib/godoc", vfs.BindBefore)
+		}
+	}
+}
@@ -31,10 +31,50 @@ var (
 	defaultStaticFS    vfs.NameSpace = getNameSpace(mapfs.New(static.Files), "/")
 	defaultDocFS       vfs.NameSpace = getNameSpace(defaultRootFS, "/doc")
 	defaultBlogFS      vfs.NameSpace = getNameSpace(defaultRootFS, "/blog")
-	defaultLocalFS     vfs.NameSpace = getLocalRootNS(defaultRootFS)
+	defaultLocalBaseFS vfs.NameSpace = getLocalRootNS(defaultRootFS)
+	defaultLocalRoot   string        = getLocalRoot() // OS path of defaultLocalBaseFS, empty for zip file
 	defaultTranslater  Translater    = new(localTranslater)
+
+	// set by initVersionedLocalFS
+	defaultGoVersion     string        // Go version of defaultRootFS
+	defaultLocalFS       vfs.NameSpace // defaultLocalBaseFS with version-specific trees
+	defaultLocalVersions []string      // version dirs of defaultLocalFS, the nearest first
 )
 
+func init() {
+	initVersionedLocalFS()
+}
+
+func initVersionedLocalFS() {
+	goVersion := getGoVersion(defaultRootFS)
+	fs, versions := getVersionedNS(defaultLocalBaseFS, goVersion)
+
+	tableMu.Lock()
+	defaultGoVersion, defaultLocalFS, defaultLocalVersions = goVersion, fs, versions
+	tableMu.Unlock()
+	ResetCache()
+}
+
+// localFS return defaultLocalFS, replaced by Reload.
+func localFS() vfs.NameSpace {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	return defaultLocalFS
+}
+
+// SetTarget set the GOOS and GOARCH of the translation files to use,
+// by default those of the environment. Empty values are unchanged.
+// It must be called before the translations are used.
+func SetTarget(goos, goarch string) {
+	if goos != "" {
+		defaultGodocGoos = goos
+	}
+	if goarch != "" {
+		defaultGodocGoarch = goarch
+	}
+	ResetCache()
+}
+
 func getGodocGoos() string {
 	if v := strings.TrimSpace(os.Getenv("GOOS")); v != "" {
 		return v
@@ -56,6 +96,13 @@ func getLocalRootNS(rootfs vfs.NameSpace) vfs.NameSpace {
 	return getNameSpace(defaultRootFS, "/"+Default)
 }
 
+func getLocalRoot() string {
+	if s := os.Getenv("GODOC_LOCAL_ROOT"); s != "" {
+		return s
+	}
+	return filepath.Join(runtime.GOROOT(), Default)
+}
+
 // Init initialize the translations environment.
 func Init(goRoot, goTranslations, goZipFile, goTemplateDir, goPath string) {
 	if goZipFile != "" {
@@ -68,21 +115,24 @@ func Init(goRoot, goTranslations, goZipFile, goTemplateDir, goPath string) {
 		defaultDocFS = getNameSpace(defaultRootFS, "/doc")
 		defaultBlogFS = getNameSpace(defaultRootFS, "/blog")
 		if goTranslations != "" && goTranslations != Default {
-			defaultLocalFS = getNameSpace(defaultRootFS, "/"+goTranslations)
+			defaultLocalBaseFS = getNameSpace(defaultRootFS, "/"+goTranslations)
 		} else {
-			defaultLocalFS = getNameSpace(defaultRootFS, "/"+Default)
+			defaultLocalBaseFS = getNameSpace(defaultRootFS, "/"+Default)
 		}
+		defaultLocalRoot = ""
 	} else {
 		if goRoot != "" && goRoot != runtime.GOROOT() {
 			defaultRootFS = getNameSpace(vfs.OS(goRoot), "/")
 			defaultDocFS = getNameSpace(defaultRootFS, "/doc")
 			defaultBlogFS = getNameSpace(defaultRootFS, "/blog")
 			if goTranslations == "" || goTranslations == Default {
-				defaultLocalFS = getNameSpace(defaultRootFS, "/"+Default)
+				defaultLocalBaseFS = getNameSpace(defaultRootFS, "/"+Default)
+				defaultLocalRoot = filepath.Join(goRoot, Default)
 			}
 		}
 		if goTranslations != "" && goTranslations != Default {
-			defaultLocalFS = getNameSpace(vfs.OS(goTranslations), "/")
+			defaultLocalBaseFS = getNameSpace(vfs.OS(goTranslations), "/")
+			defaultLocalRoot = goTranslations
 		}
 
 		if goTemplateDir != "" {
@@ -103,6 +153,13 @@ func Init(goRoot, goTranslations, goZipFile, goTemplateDir, goPath string) {
 		}
 	}
 
+	initEmbedFS(goTemplateDir)
+	initVersionedLocalFS()
+
+	// Prefer the precompiled bundles, if any.
+	if bundles := loadBundles(localFS()); len(bundles) > 0 {
+		RegisterTranslater(NewBundleTranslater(bundles...))
+	}
 }
 
 func getNameSpace(fs vfs.FileSystem, ns string) vfs.NameSpace {
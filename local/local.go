@@ -7,6 +7,9 @@ package local
 import (
 	"fmt"
 	"go/doc"
+	"sort"
+	"strings"
+	"sync"
 
 	"golang.org/x/tools/godoc/vfs"
 )
@@ -31,9 +34,24 @@ var (
 	blogFSTable      = make(map[string]vfs.FileSystem) // map[lang]...
 	pkgDocTable      = make(map[string]*doc.Package)   // map[mapKey(...)]...
 	pkgDocIndexTable = make(map[string]string)         // map[mapKey(...)]...
+	pkgMetaTable     = make(map[string]*Meta)          // map[mapKey(...)]...
 	trList           = make([]Translater, 0)
+
+	// packages registered from the translation files and the bundles,
+	// dropped by Reload
+	loadedPkgTable = make(map[pkgKey]bool)
 )
 
+// tableMu guards pkgDocTable, pkgDocIndexTable, pkgMetaTable,
+// loadedPkgTable, trList and the translation tree of init.go: the
+// packages are registered on first use and dropped by Reload while
+// they are read. It is not held while calling other locked functions.
+var tableMu sync.RWMutex
+
+type pkgKey struct {
+	lang, importPath string
+}
+
 func mapKey(lang, importPath, id string) string {
 	return fmt.Sprintf("%s.%s@%s", importPath, id, lang)
 }
@@ -59,13 +77,118 @@ func RegisterBlogFS(lang string, blogFiles vfs.FileSystem) {
 
 // RegisterPackage Register Package.
 func RegisterPackage(lang string, pkg *doc.Package) {
+	tableMu.Lock()
+	defer tableMu.Unlock()
 	pkgDocTable[mapKey(lang, pkg.ImportPath, __pkg__)] = pkg
 	initDocTable(lang, pkg)
 }
 
+// RegisterMeta Register the metadata of the package identifier.
+func RegisterMeta(lang, importPath, id string, m *Meta) {
+	if id == "" {
+		id = __doc__
+	}
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	pkgMetaTable[mapKey(lang, importPath, id)] = m
+}
+
+// markLoaded record the package as read from the translation files or
+// the bundles, to be dropped by Reload.
+func markLoaded(lang, importPath string) {
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	loadedPkgTable[pkgKey{lang, importPath}] = true
+}
+
+// lookupPkgDoc return the registered package, nil if none.
+func lookupPkgDoc(lang, importPath string) *doc.Package {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	return pkgDocTable[mapKey(lang, importPath, __pkg__)]
+}
+
+// lookupDocIndex return the registered doc of the package identifier.
+func lookupDocIndex(lang, importPath, id string) (s string, ok bool) {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	s, ok = pkgDocIndexTable[mapKey(lang, importPath, id)]
+	return
+}
+
+// lookupMetaTable return the registered metadata of the package
+// identifier, nil if none.
+func lookupMetaTable(lang, importPath, id string) *Meta {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	return pkgMetaTable[mapKey(lang, importPath, id)]
+}
+
+// translaters return the registered translaters. The list is never
+// changed in place, so it may be used without lock.
+func translaters() []Translater {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	return trList
+}
+
+func unregisterPackage(lang, importPath string) {
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	unregisterPackageLocked(lang, importPath)
+}
+
+func unregisterPackageLocked(lang, importPath string) {
+	delete(pkgDocTable, mapKey(lang, importPath, __pkg__))
+	for k := range pkgDocIndexTable {
+		if strings.HasPrefix(k, importPath+".") && strings.HasSuffix(k, "@"+lang) {
+			delete(pkgDocIndexTable, k)
+		}
+	}
+	for k := range pkgMetaTable {
+		if strings.HasPrefix(k, importPath+".") && strings.HasSuffix(k, "@"+lang) {
+			delete(pkgMetaTable, k)
+		}
+	}
+	delete(loadedPkgTable, pkgKey{lang, importPath})
+}
+
+// Reload drop the packages read from the translation files and the
+// bundles, and the caches of ResetCache, so that the translations are
+// read again on next access. It must be called when the translation
+// files have changed.
+func Reload() {
+	initVersionedLocalFS()
+	bundles := loadBundles(localFS())
+
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	for k := range loadedPkgTable {
+		unregisterPackageLocked(k.lang, k.importPath)
+	}
+	list := make([]Translater, 0, len(trList)+1)
+	for _, tr := range trList {
+		if _, ok := tr.(*bundleTranslater); !ok {
+			list = append(list, tr)
+		}
+	}
+	if len(bundles) > 0 {
+		list = append(list, NewBundleTranslater(bundles...))
+	}
+	trList = list
+}
+
 // RegisterTranslater Register Translater.
 func RegisterTranslater(tr Translater) {
-	trList = append(trList, tr)
+	tableMu.Lock()
+	defer tableMu.Unlock()
+	trList = append(trList[:len(trList):len(trList)], tr)
+}
+
+// TranslationsRoot return the directory of the translations on disk,
+// empty if they are read from a zip file or embedded.
+func TranslationsRoot() string {
+	return defaultLocalRoot
 }
 
 // RootFS return root filesystem.
@@ -81,7 +204,7 @@ func StaticFS(lang string) vfs.FileSystem {
 	if fs, _ := staticFSTable[lang]; fs != nil {
 		return fs
 	}
-	for _, tr := range trList {
+	for _, tr := range translaters() {
 		if fs := tr.Static(lang); fs != nil {
 			return fs
 		}
@@ -100,7 +223,7 @@ func DocumentFS(lang string) vfs.FileSystem {
 	if fs, _ := docFSTable[lang]; fs != nil {
 		return fs
 	}
-	for _, tr := range trList {
+	for _, tr := range translaters() {
 		if fs := tr.Document(lang); fs != nil {
 			return fs
 		}
@@ -111,6 +234,40 @@ func DocumentFS(lang string) vfs.FileSystem {
 	return defaultDocFS
 }
 
+// DocumentLangs return the languages with a translated doc tree,
+// registered or in the translations directory.
+func DocumentLangs() []string {
+	return langDirs(docFSTable, "/doc")
+}
+
+// StaticLangs return the languages with translated templates,
+// registered or in the translations directory.
+func StaticLangs() []string {
+	return langDirs(staticFSTable, "/static")
+}
+
+// langDirs return the languages of table and of the sub dirs of dir
+// in the translations directory, in sorted order.
+func langDirs(table map[string]vfs.FileSystem, dir string) []string {
+	seen := make(map[string]bool)
+	for lang := range table {
+		seen[lang] = true
+	}
+	if fis, err := localFS().ReadDir(dir); err == nil {
+		for _, fi := range fis {
+			if fi.IsDir() {
+				seen[fi.Name()] = true
+			}
+		}
+	}
+	var langs []string
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
 // Package translate Package doc.
 func Package(lang, importPath string, pkg ...*doc.Package) *doc.Package {
 	if lang == "" {
@@ -121,15 +278,15 @@ func Package(lang, importPath string, pkg ...*doc.Package) *doc.Package {
 		}
 	}
 	if len(pkg) > 0 && pkg[0] != nil {
-		if p := trPackage(lang, pkg[0].ImportPath, pkg[0]); p != nil {
+		if p := trPackage(lang, pkg[0].ImportPath, pkg[0], StatusUnknown); p != nil {
 			return p
 		}
 	} else {
-		if p, _ := pkgDocTable[mapKey(lang, importPath, __pkg__)]; p != nil {
+		if p := lookupPkgDoc(lang, importPath); p != nil {
 			return p
 		}
 	}
-	for _, tr := range trList {
+	for _, tr := range translaters() {
 		if p := tr.Package(lang, importPath, pkg...); p != nil {
 			return p
 		}
@@ -143,6 +300,36 @@ func Package(lang, importPath string, pkg ...*doc.Package) *doc.Package {
 	return nil
 }
 
+// DocText return the translated doc of the package identifier.
+// The id is a name like "Reader" or "Reader.Read", empty for package doc.
+func DocText(lang, importPath, id string) (doc string, ok bool) {
+	if lang == "" || Package(lang, importPath) == nil {
+		return "", false
+	}
+	if id == "" {
+		id = __doc__
+	}
+	return lookupDocIndex(lang, importPath, id)
+}
+
+// PackageWithStatus translate Package doc, the entries whose review
+// status is lower than min keep the original doc.
+func PackageWithStatus(lang string, min Status, pkg *doc.Package) *doc.Package {
+	if lang == "" || pkg == nil {
+		return pkg
+	}
+	if min <= StatusUnknown {
+		return Package(lang, pkg.ImportPath, pkg)
+	}
+	if Package(lang, pkg.ImportPath) == nil {
+		return pkg
+	}
+	if p := trPackage(lang, pkg.ImportPath, pkg, min); p != nil {
+		return p
+	}
+	return pkg
+}
+
 // BlogFS return Blog filesystem.
 func BlogFS(lang string) vfs.FileSystem {
 	if lang == "" {
@@ -151,7 +338,7 @@ func BlogFS(lang string) vfs.FileSystem {
 	if fs, _ := blogFSTable[lang]; fs != nil {
 		return fs
 	}
-	for _, tr := range trList {
+	for _, tr := range translaters() {
 		if fs := tr.Blog(lang); fs != nil {
 			return fs
 		}
@@ -163,107 +350,131 @@ func BlogFS(lang string) vfs.FileSystem {
 }
 
 func initDocTable(lang string, pkg *doc.Package) {
-	pkgDocIndexTable[mapKey(lang, pkg.ImportPath, __name__)] = pkg.Name
-	pkgDocIndexTable[mapKey(lang, pkg.ImportPath, __doc__)] = pkg.Doc
+	for id, s := range docIndex(pkg) {
+		pkgDocIndexTable[mapKey(lang, pkg.ImportPath, id)] = s
+	}
+}
+
+// docIndex return the doc of the package identifiers.
+func docIndex(pkg *doc.Package) map[string]string {
+	index := make(map[string]string)
+	index[__name__] = pkg.Name
+	index[__doc__] = pkg.Doc
 
 	for _, v := range pkg.Consts {
 		for _, id := range v.Names {
-			pkgDocIndexTable[mapKey(lang, pkg.ImportPath, id)] = v.Doc
+			index[id] = v.Doc
 		}
 	}
 	for _, v := range pkg.Types {
-		pkgDocIndexTable[mapKey(lang, pkg.ImportPath, v.Name)] = v.Doc
+		index[v.Name] = v.Doc
 
 		for _, x := range v.Consts {
 			for _, id := range x.Names {
-				pkgDocIndexTable[mapKey(lang, pkg.ImportPath, id)] = x.Doc
+				index[id] = x.Doc
 			}
 		}
 		for _, x := range v.Vars {
 			for _, id := range x.Names {
-				pkgDocIndexTable[mapKey(lang, pkg.ImportPath, id)] = x.Doc
+				index[id] = x.Doc
 			}
 		}
 		for _, x := range v.Funcs {
-			pkgDocIndexTable[mapKey(lang, pkg.ImportPath, x.Name)] = x.Doc
+			index[x.Name] = x.Doc
 		}
 		for _, x := range v.Methods {
-			pkgDocIndexTable[mapKey(lang, pkg.ImportPath, methodId(v.Name, x.Name))] = x.Doc
+			index[methodId(v.Name, x.Name)] = x.Doc
 		}
 	}
 	for _, v := range pkg.Vars {
 		for _, id := range v.Names {
-			pkgDocIndexTable[mapKey(lang, pkg.ImportPath, id)] = v.Doc
+			index[id] = v.Doc
 		}
 	}
 	for _, v := range pkg.Funcs {
-		pkgDocIndexTable[mapKey(lang, pkg.ImportPath, v.Name)] = v.Doc
+		index[v.Name] = v.Doc
 	}
+	return index
 }
 
-func trPackage(lang, importPath string, pkg *doc.Package) *doc.Package {
-	key := mapKey(lang, pkg.ImportPath, __pkg__)
-	localPkg, _ := pkgDocTable[key]
+func trPackage(lang, importPath string, pkg *doc.Package, min Status) *doc.Package {
+	localPkg := lookupPkgDoc(lang, pkg.ImportPath)
 	if localPkg == nil {
 		return nil
 	}
 
-	pkg.Name = localPkg.Name
-	pkg.Doc = localPkg.Doc
+	// reviewed report whether the translation of id has the status min
+	reviewed := func(id string) bool {
+		if min > StatusUnknown {
+			if m, _ := LookupMeta(lang, pkg.ImportPath, id); m == nil || m.Status < min {
+				return false
+			}
+		}
+		return true
+	}
 
-	for k, _ := range pkg.Notes {
-		if notes, _ := localPkg.Notes[k]; notes != nil {
-			pkg.Notes[k] = notes
+	// trDoc return the translated doc of id, empty if none
+	trDoc := func(id string) string {
+		if !reviewed(id) {
+			return ""
+		}
+		s, _ := lookupDocIndex(lang, pkg.ImportPath, id)
+		return s
+	}
+
+	if s := trDoc(__doc__); s != "" || min <= StatusUnknown {
+		pkg.Name = localPkg.Name
+		pkg.Doc = localPkg.Doc
+	}
+
+	// notes have no metadata of their own: they follow the package doc
+	if reviewed("") {
+		for k, _ := range pkg.Notes {
+			if notes, _ := localPkg.Notes[k]; notes != nil {
+				pkg.Notes[k] = notes
+			}
 		}
 	}
 
 	for i := 0; i < len(pkg.Consts); i++ {
-		key := mapKey(lang, pkg.ImportPath, pkg.Consts[i].Names[0])
-		if s, _ := pkgDocIndexTable[key]; s != "" {
+		if s := trDoc(pkg.Consts[i].Names[0]); s != "" {
 			pkg.Consts[i].Doc = s
 		}
 	}
 	for i := 0; i < len(pkg.Types); i++ {
-		key := mapKey(lang, pkg.ImportPath, pkg.Types[i].Name)
-		if s, _ := pkgDocIndexTable[key]; s != "" {
+		if s := trDoc(pkg.Types[i].Name); s != "" {
 			pkg.Types[i].Doc = s
 		}
 
 		for j := 0; j < len(pkg.Types[i].Consts); j++ {
-			key := mapKey(lang, pkg.ImportPath, pkg.Types[i].Consts[j].Names[0])
-			if s, _ := pkgDocIndexTable[key]; s != "" {
+			if s := trDoc(pkg.Types[i].Consts[j].Names[0]); s != "" {
 				pkg.Types[i].Consts[j].Doc = s
 			}
 		}
 		for j := 0; j < len(pkg.Types[i].Vars); j++ {
-			key := mapKey(lang, pkg.ImportPath, pkg.Types[i].Vars[j].Names[0])
-			if s, _ := pkgDocIndexTable[key]; s != "" {
+			if s := trDoc(pkg.Types[i].Vars[j].Names[0]); s != "" {
 				pkg.Types[i].Vars[j].Doc = s
 			}
 		}
 		for j := 0; j < len(pkg.Types[i].Funcs); j++ {
-			key := mapKey(lang, pkg.ImportPath, pkg.Types[i].Funcs[j].Name)
-			if s, _ := pkgDocIndexTable[key]; s != "" {
+			if s := trDoc(pkg.Types[i].Funcs[j].Name); s != "" {
 				pkg.Types[i].Funcs[j].Doc = s
 			}
 		}
 		for j := 0; j < len(pkg.Types[i].Methods); j++ {
 			id := methodId(pkg.Types[i].Name, pkg.Types[i].Methods[j].Name)
-			key := mapKey(lang, pkg.ImportPath, id)
-			if s, _ := pkgDocIndexTable[key]; s != "" {
+			if s := trDoc(id); s != "" {
 				pkg.Types[i].Methods[j].Doc = s
 			}
 		}
 	}
 	for i := 0; i < len(pkg.Vars); i++ {
-		key := mapKey(lang, pkg.ImportPath, pkg.Vars[i].Names[0])
-		if s, _ := pkgDocIndexTable[key]; s != "" {
+		if s := trDoc(pkg.Vars[i].Names[0]); s != "" {
 			pkg.Vars[i].Doc = s
 		}
 	}
 	for i := 0; i < len(pkg.Funcs); i++ {
-		key := mapKey(lang, pkg.ImportPath, pkg.Funcs[i].Name)
-		if s, _ := pkgDocIndexTable[key]; s != "" {
+		if s := trDoc(pkg.Funcs[i].Name); s != "" {
 			pkg.Funcs[i].Doc = s
 		}
 	}
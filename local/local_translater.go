@@ -11,6 +11,7 @@ import (
 	"go/parser"
 	"go/token"
 	"log"
+	"path"
 
 	"golang.org/x/tools/godoc/vfs"
 )
@@ -41,11 +42,15 @@ func (p *localTranslater) Package(lang, importPath string, pkg ...*doc.Package)
 	}
 
 	// try parse and register new pkg doc
-	localPkg := p.ParseDocPackage(lang, importPath)
+	localPkg, metas := p.parseDocPackage(lang, importPath)
 	if localPkg == nil {
 		return nil
 	}
+	for id, m := range metas {
+		RegisterMeta(lang, importPath, id, m)
+	}
 	RegisterPackage(lang, localPkg)
+	markLoaded(lang, importPath)
 
 	// retry Package func
 	return Package(lang, importPath, pkg...)
@@ -59,12 +64,19 @@ func (p *localTranslater) Blog(lang string) vfs.FileSystem {
 }
 
 func (p *localTranslater) ParseDocPackage(lang, importPath string) *doc.Package {
+	docPkg, _ := p.parseDocPackage(lang, importPath)
+	return docPkg
+}
+
+// parseDocPackage parse the translated package doc, and the review
+// metadata of its identifiers.
+func (p *localTranslater) parseDocPackage(lang, importPath string) (*doc.Package, map[string]*Meta) {
 	if lang == "" || importPath == "" || importPath[0] == '/' {
-		return nil
+		return nil, nil
 	}
 	docCode := p.loadDocCode(lang, importPath)
 	if docCode == nil {
-		return nil
+		return nil, nil
 	}
 
 	// parse doc
@@ -72,30 +84,38 @@ func (p *localTranslater) ParseDocPackage(lang, importPath string) *doc.Package
 	astFile, err := parser.ParseFile(fset, importPath, docCode, parser.ParseComments)
 	if err != nil {
 		log.Printf("local.localTranslater.ParseDocPackage: err = %v\n", err)
-		return nil
+		return nil, nil
 	}
+	metas := parseMeta(astFile)
 	astPkg, _ := ast.NewPackage(fset,
 		map[string]*ast.File{importPath: astFile},
 		nil,
 		nil,
 	)
 	docPkg := doc.New(astPkg, importPath, doc.AllDecls)
-	return docPkg
+	return docPkg, metas
 }
 
 func (p *localTranslater) NameSpace(ns string) vfs.FileSystem {
+	fs := localFS()
 	if ns != "" {
-		if fi, err := defaultLocalFS.Stat(ns); err != nil || !fi.IsDir() {
+		if fi, err := fs.Stat(ns); err != nil || !fi.IsDir() {
 			return nil
 		}
 		subfs := make(vfs.NameSpace)
-		subfs.Bind("/", defaultLocalFS, ns, vfs.BindReplace)
+		subfs.Bind("/", fs, ns, vfs.BindReplace)
 		return subfs
 	}
-	return defaultLocalFS
+	return fs
 }
 
 func (p *localTranslater) loadDocCode(lang, importPath string) []byte {
+	manifestLookups.Add(1)
+	if isMissing(lang, importPath) {
+		manifestCacheHits.Add(1)
+		return nil
+	}
+
 	// {FS}:/src/importPath/doc_$(lang)_GOOS_GOARCH.go
 	// {FS}:/src/importPath/doc_$(lang)_GOARCH.go
 	// {FS}:/src/importPath/doc_$(lang)_GOOS.go
@@ -107,30 +127,32 @@ func (p *localTranslater) loadDocCode(lang, importPath string) []byte {
 		fmt.Sprintf("/src/%s/doc_%s.go", importPath, lang),
 	}
 
-	for i := 0; i < len(filenames); i++ {
-		// $(GOROOT)/translates/
-		if p.fileExists(defaultLocalFS, filenames[i]) {
-			docCode, _ := vfs.ReadFile(defaultLocalFS, filenames[i])
-			if docCode != nil {
-				return docCode
+	// $(GOROOT)/translates/goX.Y/, the nearest version first
+	// $(GOROOT)/translates/
+	for _, dir := range localVersionDirs() {
+		for i := 0; i < len(filenames); i++ {
+			if manifestHas(path.Clean(dir + filenames[i])) {
+				docCode, _ := vfs.ReadFile(defaultLocalBaseFS, dir+filenames[i])
+				if docCode != nil {
+					manifestFound.Add(1)
+					return docCode
+				}
 			}
 		}
+	}
 
+	for i := 0; i < len(filenames); i++ {
 		// $(GOROOT)/
-		if p.fileExists(defaultRootFS, filenames[i]) {
+		if rootManifestHas(filenames[i]) {
 			docCode, _ := vfs.ReadFile(defaultRootFS, filenames[i])
 			if docCode != nil {
+				manifestFound.Add(1)
 				return docCode
 			}
 		}
 	}
 
+	manifestNotFound.Add(1)
+	setMissing(lang, importPath)
 	return nil
 }
-
-func (p *localTranslater) fileExists(fs vfs.NameSpace, name string) bool {
-	if fi, err := fs.Stat(name); err != nil || fi.IsDir() {
-		return false
-	}
-	return true
-}
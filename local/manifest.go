@@ -0,0 +1,138 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"expvar"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// maxMissing is the maximum number of entries of the negative cache.
+// The lookups are keyed by request-controlled languages and import
+// paths, so the cache must not grow without bound.
+const maxMissing = 10000
+
+// The manifest is the set of translation files in defaultLocalBaseFS
+// and in the src tree of defaultRootFS, built on the first lookup. The
+// misses is the negative cache of the packages without translation
+// file, dropped with the manifest by ResetCache when the translation
+// files change.
+var (
+	manifestMu        sync.Mutex
+	manifestFiles     map[string]bool         // nil if not built
+	manifestRootFiles map[string]bool         // of defaultRootFS, nil if not built
+	manifestMiss      = make(map[string]bool) // map[mapKey(lang, importPath, "")]...
+)
+
+// Lookup counts, served in /debug/vars.
+var (
+	manifestStats     = expvar.NewMap("translations")
+	manifestSize      = new(expvar.Int) // translation files in the manifest
+	manifestBuilds    = new(expvar.Int) // manifest builds
+	manifestLookups   = new(expvar.Int) // loadDocCode calls
+	manifestFound     = new(expvar.Int) // lookups found a translation file
+	manifestNotFound  = new(expvar.Int) // lookups probed and found no file
+	manifestCacheHits = new(expvar.Int) // lookups answered by the negative cache
+)
+
+func init() {
+	manifestStats.Set("manifestSize", manifestSize)
+	manifestStats.Set("manifestBuilds", manifestBuilds)
+	manifestStats.Set("lookups", manifestLookups)
+	manifestStats.Set("found", manifestFound)
+	manifestStats.Set("notFound", manifestNotFound)
+	manifestStats.Set("negativeCacheHits", manifestCacheHits)
+}
+
+// ResetCache drop the manifest and the negative cache of the translation
+// files, and the loaded message catalogs and synonyms. It must be called when the
+// translation files have changed.
+func ResetCache() {
+	manifestMu.Lock()
+	manifestFiles = nil
+	manifestRootFiles = nil
+	manifestMiss = make(map[string]bool)
+	manifestMu.Unlock()
+
+	resetMessages()
+	resetSynonyms()
+}
+
+// manifestHas reports whether the translation file name exists in
+// defaultLocalBaseFS.
+func manifestHas(name string) bool {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	if manifestFiles == nil {
+		manifestFiles = buildManifest(defaultLocalBaseFS, localVersionDirs())
+		manifestSize.Set(int64(len(manifestFiles)))
+		manifestBuilds.Add(1)
+	}
+	return manifestFiles[name]
+}
+
+// rootManifestHas reports whether the translation file name exists in
+// defaultRootFS.
+func rootManifestHas(name string) bool {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+
+	if manifestRootFiles == nil {
+		manifestRootFiles = buildManifest(defaultRootFS, []string{""})
+		manifestBuilds.Add(1)
+	}
+	return manifestRootFiles[name]
+}
+
+// isMissing reports whether the package has no translation file,
+// as recorded by setMissing.
+func isMissing(lang, importPath string) bool {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	return manifestMiss[mapKey(lang, importPath, "")]
+}
+
+func setMissing(lang, importPath string) {
+	manifestMu.Lock()
+	defer manifestMu.Unlock()
+	if len(manifestMiss) >= maxMissing {
+		// evict an arbitrary entry: a miss is only a probe to redo
+		for k := range manifestMiss {
+			delete(manifestMiss, k)
+			break
+		}
+	}
+	manifestMiss[mapKey(lang, importPath, "")] = true
+}
+
+// buildManifest return the doc_*.go files in the src trees of fs.
+func buildManifest(fs vfs.FileSystem, dirs []string) map[string]bool {
+	files := make(map[string]bool)
+	var walk func(dir string)
+	walk = func(dir string) {
+		fis, err := fs.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, fi := range fis {
+			name := path.Join(dir, fi.Name())
+			switch {
+			case fi.IsDir():
+				walk(name)
+			case strings.HasPrefix(fi.Name(), "doc_") && strings.HasSuffix(fi.Name(), ".go"):
+				files[name] = true
+			}
+		}
+	}
+	for _, dir := range dirs {
+		walk(dir + "/src")
+	}
+	return files
+}
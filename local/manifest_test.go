@@ -0,0 +1,88 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestBuildManifest(t *testing.T) {
+	fs := mapfs.New(map[string]string{
+		"src/fmt/doc_zh_CN.go":            "",
+		"src/fmt/print.go":                "",
+		"src/net/http/doc_zh_CN_linux.go": "",
+		"go1.5/src/fmt/doc_zh_CN.go":      "",
+		"go1.6/src/fmt/doc_zh_CN.go":      "",
+		"doc/zh_CN/go_spec.html":          "",
+		"static/zh_CN/doc_not_in_src.go":  "",
+	})
+	want := map[string]bool{
+		"/src/fmt/doc_zh_CN.go":            true,
+		"/src/net/http/doc_zh_CN_linux.go": true,
+		"/go1.5/src/fmt/doc_zh_CN.go":      true,
+	}
+	if got := buildManifest(fs, []string{"/go1.5", ""}); !reflect.DeepEqual(got, want) {
+		t.Errorf("buildManifest = %v; want %v", got, want)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	ResetCache()
+	if isMissing("zh_CN", "no/such/pkg") {
+		t.Fatal("isMissing before setMissing")
+	}
+	setMissing("zh_CN", "no/such/pkg")
+	if !isMissing("zh_CN", "no/such/pkg") {
+		t.Fatal("isMissing after setMissing = false")
+	}
+	if isMissing("ja_JP", "no/such/pkg") {
+		t.Fatal("isMissing for other language")
+	}
+	ResetCache()
+	if isMissing("zh_CN", "no/such/pkg") {
+		t.Fatal("isMissing after ResetCache")
+	}
+}
+
+func TestNegativeCacheBound(t *testing.T) {
+	ResetCache()
+	defer ResetCache()
+	for i := 0; i < maxMissing+10; i++ {
+		setMissing("zh_CN", fmt.Sprintf("no/such/pkg%d", i))
+	}
+	if n := len(manifestMiss); n != maxMissing {
+		t.Errorf("negative cache has %d entries; want %d", n, maxMissing)
+	}
+	if !isMissing("zh_CN", fmt.Sprintf("no/such/pkg%d", maxMissing+9)) {
+		t.Errorf("last miss not cached")
+	}
+}
+
+func TestRootManifest(t *testing.T) {
+	saved := defaultRootFS
+	defer func() {
+		defaultRootFS = saved
+		ResetCache()
+	}()
+	defaultRootFS = getNameSpace(mapfs.New(map[string]string{
+		"src/fmt/doc_zh_CN.go": "package fmt\n",
+		"src/fmt/print.go":     "package fmt\n",
+	}), "/")
+	ResetCache()
+
+	if !rootManifestHas("/src/fmt/doc_zh_CN.go") {
+		t.Errorf("GOROOT translation file not in manifest")
+	}
+	if rootManifestHas("/src/fmt/doc_ja_JP.go") {
+		t.Errorf("missing GOROOT translation file in manifest")
+	}
+	if n := len(manifestRootFiles); n != 1 {
+		t.Errorf("GOROOT manifest has %d files; want 1", n)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// The message catalog of a language is the json file
+// $(translations)/messages/$(lang).json, which maps the english
+// template messages to the translations:
+//
+//	{
+//		"Package": "包",
+//		"%d packages": "%d 个包",
+//		"2006-01-02 15:04:05 -0700 MST": "2006年01月02日 15:04:05"
+//	}
+//
+// The catalog files are loaded on first use. The messages they do not
+// translate are looked up in the built-in catalogs.
+var (
+	messageMu    sync.Mutex
+	messageTable = make(map[string]map[string]string) // map[lang]map[msg]..., registered
+	messageFiles map[string]map[string]string         // map[lang]map[msg]..., loaded from catalog files, nil if not loaded
+)
+
+// builtinMessages is the built-in message catalogs, see message_$(lang).go.
+var builtinMessages = make(map[string]map[string]string) // map[lang]map[msg]...
+
+// RegisterMessages Register the message catalog of lang.
+func RegisterMessages(lang string, messages map[string]string) {
+	messageMu.Lock()
+	defer messageMu.Unlock()
+	messageTable[lang] = messages
+}
+
+// Message return the translation of the template message msg,
+// msg if none. The registered catalog is used first, then the
+// catalog file and the built-in messages.
+func Message(lang, msg string) string {
+	if lang == "" {
+		return msg
+	}
+
+	messageMu.Lock()
+	if messageFiles == nil {
+		messageFiles = loadMessages(localFS())
+	}
+	catalogs := []map[string]string{messageTable[lang], messageFiles[lang], builtinMessages[lang]}
+	messageMu.Unlock()
+
+	for _, messages := range catalogs {
+		if s, ok := messages[msg]; ok && s != "" {
+			return s
+		}
+	}
+	return msg
+}
+
+// MessageLangs return the languages with a message catalog, in
+// sorted order.
+func MessageLangs() []string {
+	messageMu.Lock()
+	defer messageMu.Unlock()
+	if messageFiles == nil {
+		messageFiles = loadMessages(localFS())
+	}
+
+	seen := make(map[string]bool)
+	for _, catalogs := range []map[string]map[string]string{messageTable, messageFiles, builtinMessages} {
+		for lang := range catalogs {
+			seen[lang] = true
+		}
+	}
+	var langs []string
+	for lang := range seen {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// loadMessages return the message catalogs of the /messages dir of fs.
+// The languages without catalog file have no entry.
+func loadMessages(fs vfs.FileSystem) map[string]map[string]string {
+	catalogs := make(map[string]map[string]string)
+	fis, err := fs.ReadDir("/messages")
+	if err != nil {
+		return catalogs
+	}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ".json") {
+			continue
+		}
+		filename := "/messages/" + fi.Name()
+		data, err := vfs.ReadFile(fs, filename)
+		if err != nil {
+			log.Printf("local: %s: %v", filename, err)
+			continue
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			log.Printf("local: %s: %v", filename, err)
+			continue
+		}
+		catalogs[strings.TrimSuffix(fi.Name(), ".json")] = messages
+	}
+	return catalogs
+}
+
+func resetMessages() {
+	messageMu.Lock()
+	defer messageMu.Unlock()
+	messageFiles = nil
+}
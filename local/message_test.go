@@ -0,0 +1,40 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestMessage(t *testing.T) {
+	saved := defaultLocalFS
+	defer func() {
+		defaultLocalFS = saved
+		resetMessages()
+	}()
+	defaultLocalFS = getNameSpace(mapfs.New(map[string]string{
+		"messages/zh_CN.json":   `{"Package": "程序包"}`,
+		"messages/xx_TEST.json": `{"Package": "xx"}`,
+	}), "/")
+	resetMessages()
+
+	for _, test := range []struct{ lang, msg, want string }{
+		{"zh_CN", "Package", "程序包"}, // catalog file
+		{"zh_CN", "Submit", "提交"},   // built-in
+		{"xx_TEST", "Package", "xx"},
+		{"xx_TEST", "Submit", "Submit"},
+		{"yy_TEST", "Package", "Package"},
+		{"", "Package", "Package"},
+	} {
+		if got := Message(test.lang, test.msg); got != test.want {
+			t.Errorf("Message(%q, %q) = %q; want %q", test.lang, test.msg, got, test.want)
+		}
+	}
+	if _, ok := messageFiles["yy_TEST"]; ok {
+		t.Errorf("message catalog of unknown language cached")
+	}
+}
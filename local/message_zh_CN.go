@@ -0,0 +1,66 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+// The built-in zh_CN messages of the golangdoc pages and templates,
+// overridden by $(translations)/messages/zh_CN.json.
+func init() {
+	builtinMessages["zh_CN"] = map[string]string{
+		"2006-01-02 15:04:05 -0700 MST": "2006年01月02日 15:04:05",
+
+		// templates
+		"Package":     "包",
+		"%d packages": "%d 个包",
+
+		// review status
+		"translator":                      "翻译",
+		"reviewer":                        "审校",
+		"date":                            "日期",
+		"unknown":                         "未知",
+		"machine":                         "机器翻译",
+		"draft":                           "草稿",
+		"reviewed":                        "已审校",
+		"show all translations":           "显示全部翻译",
+		"show reviewed translations only": "只显示已审校的翻译",
+
+		// suggestions
+		"Suggest a better translation": "建议更好的翻译",
+		"package %s":                   "包 %s",
+		"Submit":                       "提交",
+
+		// alignment
+		"Translation alignment": "翻译对齐",
+		"All documents":         "全部文档",
+		"translation":           "翻译",
+		"english":               "英文",
+		"The translation is aligned with the english document.": "翻译和英文文档是对齐的.",
+		"The english document has no translation.":              "英文文档没有翻译.",
+		"no translation":                  "没有翻译",
+		"No translated documents for %s.": "没有 %s 的翻译文档.",
+		"Document":                        "文档",
+		"Sections":                        "章节",
+		"Missing":                         "缺少",
+		"Extra":                           "多余",
+		"Code":                            "代码",
+		"Paragraphs":                      "段落",
+		"Untranslated":                    "未翻译",
+		"missing":                         "缺少",
+		"extra":                           "多余",
+		"code":                            "代码",
+		"paragraphs":                      "段落",
+		"untranslated":                    "未翻译",
+
+		// references, symbols and grammar
+		"References to %s.%s":               "%s.%s 的引用",
+		"Declarations":                      "声明",
+		"Uses":                              "使用",
+		"Used by":                           "被使用",
+		"%d uses in %d packages":            "%d 处使用, 在 %d 个包中",
+		"Only the first %d uses are shown.": "只显示前 %d 处使用.",
+		"Exported identifiers":              "导出的标识符",
+		"All":                               "全部",
+		"Grammar":                           "语法",
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// Translation files may carry the review metadata of each entry as
+// comment directives in its doc comment:
+//
+//	// Reader 是...
+//	//
+//	//golangdoc:translator chai2010
+//	//golangdoc:reviewer   someone
+//	//golangdoc:status     reviewed
+//	//golangdoc:date       2015-10-01
+//	type Reader interface { ... }
+//
+// The metadata of the package doc is the default of all entries.
+const metaPrefix = "//golangdoc:"
+
+// Status is the review status of a translated entry.
+type Status int
+
+const (
+	StatusUnknown  Status = iota // no status directive
+	StatusMachine                // machine translation
+	StatusDraft                  // translated, not reviewed
+	StatusReviewed               // translated and reviewed
+)
+
+var statusNames = []string{
+	StatusUnknown:  "unknown",
+	StatusMachine:  "machine",
+	StatusDraft:    "draft",
+	StatusReviewed: "reviewed",
+}
+
+func (s Status) String() string {
+	if 0 <= s && int(s) < len(statusNames) {
+		return statusNames[s]
+	}
+	return statusNames[StatusUnknown]
+}
+
+// ParseStatus return the Status named s, StatusUnknown if none.
+func ParseStatus(s string) Status {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, name := range statusNames {
+		if name == s {
+			return Status(i)
+		}
+	}
+	return StatusUnknown
+}
+
+// Meta is the review metadata of a translated entry.
+type Meta struct {
+	Translator string
+	Reviewer   string
+	Status     Status
+	Date       string // e.g. "2015-10-01"
+}
+
+// LookupMeta return the metadata of the package identifier.
+// The id is a name like "Reader" or "Reader.Read", empty for package doc.
+func LookupMeta(lang, importPath, id string) (m *Meta, ok bool) {
+	if lang == "" || Package(lang, importPath) == nil {
+		return nil, false
+	}
+	pkgMeta := lookupMetaTable(lang, importPath, __doc__)
+	if id != "" {
+		if m = lookupMetaTable(lang, importPath, id); m != nil {
+			return m.inherit(pkgMeta), true
+		}
+	}
+	if pkgMeta != nil {
+		return pkgMeta, true
+	}
+	return nil, false
+}
+
+// inherit return m with the fields it leaves unset taken from the
+// package metadata pkg.
+func (m *Meta) inherit(pkg *Meta) *Meta {
+	if pkg == nil {
+		return m
+	}
+	r := *pkg
+	if m.Translator != "" {
+		r.Translator = m.Translator
+	}
+	if m.Reviewer != "" {
+		r.Reviewer = m.Reviewer
+	}
+	if m.Status != StatusUnknown {
+		r.Status = m.Status
+	}
+	if m.Date != "" {
+		r.Date = m.Date
+	}
+	return &r
+}
+
+// parseMeta remove the metadata directives from the comments of f,
+// and return the metadata of the documented identifiers.
+func parseMeta(f *ast.File) map[string]*Meta {
+	metas := make(map[string]*Meta)
+	add := func(doc *ast.CommentGroup, ids ...string) {
+		if m := stripMeta(doc); m != nil {
+			for _, id := range ids {
+				metas[id] = m
+			}
+		}
+	}
+
+	add(f.Doc, __doc__)
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if typeName := recvTypeName(d); typeName != "" {
+				add(d.Doc, methodId(typeName, d.Name.Name))
+			} else {
+				add(d.Doc, d.Name.Name)
+			}
+		case *ast.GenDecl:
+			var ids []string
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add(s.Doc, s.Name.Name)
+					ids = append(ids, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						ids = append(ids, ident.Name)
+					}
+				}
+			}
+			add(d.Doc, ids...)
+		}
+	}
+
+	// strip the remaining directives, and drop the empty comments
+	comments := f.Comments[:0]
+	for _, c := range f.Comments {
+		stripMeta(c)
+		if len(c.List) > 0 {
+			comments = append(comments, c)
+		}
+	}
+	f.Comments = comments
+	dropEmptyDoc(f)
+	return metas
+}
+
+// stripMeta remove the metadata directives from doc, and return the
+// metadata, nil if none.
+func stripMeta(doc *ast.CommentGroup) *Meta {
+	if doc == nil {
+		return nil
+	}
+	var m *Meta
+	list := doc.List[:0]
+	for _, c := range doc.List {
+		if !strings.HasPrefix(c.Text, metaPrefix) {
+			list = append(list, c)
+			continue
+		}
+		if m == nil {
+			m = new(Meta)
+		}
+		directive := strings.TrimSpace(c.Text[len(metaPrefix):])
+		key, value := directive, ""
+		if i := strings.IndexAny(directive, " \t"); i >= 0 {
+			key, value = directive[:i], strings.TrimSpace(directive[i:])
+		}
+		switch key {
+		case "translator":
+			m.Translator = value
+		case "reviewer":
+			m.Reviewer = value
+		case "status":
+			m.Status = ParseStatus(value)
+		case "date":
+			m.Date = value
+		}
+	}
+	doc.List = list
+	return m
+}
+
+func dropEmptyDoc(f *ast.File) {
+	isEmpty := func(doc *ast.CommentGroup) bool {
+		return doc != nil && len(doc.List) == 0
+	}
+	if isEmpty(f.Doc) {
+		f.Doc = nil
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if isEmpty(d.Doc) {
+				d.Doc = nil
+			}
+		case *ast.GenDecl:
+			if isEmpty(d.Doc) {
+				d.Doc = nil
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if isEmpty(s.Doc) {
+						s.Doc = nil
+					}
+				case *ast.ValueSpec:
+					if isEmpty(s.Doc) {
+						s.Doc = nil
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+const metaSrc = `// +build ingore
+
+// Package foo 是一个例子.
+//
+//golangdoc:translator chai2010
+//golangdoc:status     draft
+package foo
+
+// Foo 是一个类型.
+//
+//golangdoc:reviewer someone
+//golangdoc:status   reviewed
+//golangdoc:date     2015-10-01
+type Foo struct{}
+
+//golangdoc:status machine
+func (f *Foo) Bar() {}
+
+// New 创建 Foo.
+func New() *Foo
+`
+
+func TestParseMeta(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "foo", metaSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metas := parseMeta(f)
+
+	for id, want := range map[string]Meta{
+		__doc__:   {Translator: "chai2010", Status: StatusDraft},
+		"Foo":     {Reviewer: "someone", Status: StatusReviewed, Date: "2015-10-01"},
+		"Foo.Bar": {Status: StatusMachine},
+	} {
+		if m := metas[id]; m == nil || *m != want {
+			t.Errorf("parseMeta: %s = %+v; want %+v", id, m, want)
+		}
+	}
+	if m := metas["New"]; m != nil {
+		t.Errorf("parseMeta: New = %+v; want nil", m)
+	}
+
+	astPkg, _ := ast.NewPackage(fset, map[string]*ast.File{"foo": f}, nil, nil)
+	pkg := doc.New(astPkg, "foo", doc.AllDecls)
+	if want := "Package foo 是一个例子.\n"; pkg.Doc != want {
+		t.Errorf("package doc = %q; want %q", pkg.Doc, want)
+	}
+	if want := "Foo 是一个类型.\n"; pkg.Types[0].Doc != want {
+		t.Errorf("Foo doc = %q; want %q", pkg.Types[0].Doc, want)
+	}
+	if doc := pkg.Types[0].Methods[0].Doc; doc != "" {
+		t.Errorf("Foo.Bar doc = %q; want empty", doc)
+	}
+}
+
+func TestParseStatus(t *testing.T) {
+	for s, want := range map[string]Status{
+		"reviewed":  StatusReviewed,
+		" Draft ":   StatusDraft,
+		"machine":   StatusMachine,
+		"":          StatusUnknown,
+		"something": StatusUnknown,
+	} {
+		if got := ParseStatus(s); got != want {
+			t.Errorf("ParseStatus(%q) = %v; want %v", s, got, want)
+		}
+	}
+}
+
+func TestMetaInherit(t *testing.T) {
+	pkg := &Meta{Translator: "chai2010", Status: StatusDraft, Date: "2015-09-01"}
+	for _, test := range []struct{ m, want Meta }{
+		{Meta{}, *pkg},
+		{Meta{Reviewer: "someone"}, Meta{Translator: "chai2010", Reviewer: "someone", Status: StatusDraft, Date: "2015-09-01"}},
+		{Meta{Status: StatusReviewed, Date: "2015-10-01"}, Meta{Translator: "chai2010", Status: StatusReviewed, Date: "2015-10-01"}},
+	} {
+		m := test.m
+		if got := m.inherit(pkg); *got != test.want {
+			t.Errorf("%+v.inherit(%+v) = %+v; want %+v", test.m, *pkg, *got, test.want)
+		}
+	}
+	if pkg.Status != StatusDraft {
+		t.Errorf("inherit modified the package metadata")
+	}
+}
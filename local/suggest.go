@@ -0,0 +1,389 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MaxSuggestionText = 16 << 10 // maximum length of a suggested doc text
+	MaxSuggestions    = 1000     // maximum number of queued suggestions
+)
+
+// Suggestion is a translation suggested by a reader.
+type Suggestion struct {
+	Name       string    // queue entry name
+	Key        string    // mapKey(Lang, ImportPath, Id)
+	Lang       string    // e.g. "zh_CN"
+	ImportPath string    // e.g. "net/http"
+	Id         string    // e.g. "Handler" or "Request.Write", empty for package doc
+	Text       string    // suggested doc text
+	Remote     string    // remote address of the reader
+	Time       time.Time // submit time
+}
+
+// SuggestionQueue is an on-disk queue of suggestions.
+// Each suggestion is stored as a json file in Dir.
+type SuggestionQueue struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewSuggestionQueue return a suggestion queue stored in dir.
+func NewSuggestionQueue(dir string) (*SuggestionQueue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &SuggestionQueue{Dir: dir}, nil
+}
+
+// Add add a new suggestion to the queue.
+// The identifier must have a translated doc.
+func (q *SuggestionQueue) Add(lang, importPath, id, text, remote string) (*Suggestion, error) {
+	if _, ok := DocText(lang, importPath, id); !ok {
+		return nil, fmt.Errorf("local: no translation for %s", mapKey(lang, importPath, id))
+	}
+	text = strings.TrimSpace(strings.Replace(text, "\r\n", "\n", -1))
+	if text == "" {
+		return nil, errors.New("local: empty suggestion")
+	}
+	if len(text) > MaxSuggestionText {
+		return nil, errors.New("local: suggestion too long")
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names, err := filepath.Glob(filepath.Join(q.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) >= MaxSuggestions {
+		return nil, errors.New("local: too many pending suggestions")
+	}
+
+	now := time.Now()
+	s := &Suggestion{
+		Key:        mapKey(lang, importPath, id),
+		Lang:       lang,
+		ImportPath: importPath,
+		Id:         id,
+		Text:       text,
+		Remote:     remote,
+		Time:       now,
+	}
+	for {
+		s.Name = newSuggestionName(now)
+		data, err := json.MarshalIndent(s, "", "\t")
+		if err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(q.filename(s.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue // name collision, try another one
+		}
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.Write(data)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(q.filename(s.Name))
+			return nil, err
+		}
+		return s, nil
+	}
+}
+
+// newSuggestionName return a queue entry name for a suggestion submitted
+// at t: the time and a random number, in hex, such that the names sort
+// by time.
+func newSuggestionName(t time.Time) string {
+	var r [4]byte
+	rand.Read(r[:])
+	return fmt.Sprintf("%016x%08x", t.UnixNano(), binary.BigEndian.Uint32(r[:]))
+}
+
+// List return all suggestions in the queue, oldest first.
+func (q *SuggestionQueue) List() ([]*Suggestion, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names, err := filepath.Glob(filepath.Join(q.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	var list []*Suggestion
+	for _, name := range names {
+		s, err := q.load(name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Get return the named suggestion.
+func (q *SuggestionQueue) Get(name string) (*Suggestion, error) {
+	if !isSuggestionName(name) {
+		return nil, fmt.Errorf("local: bad suggestion name %q", name)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load(q.filename(name))
+}
+
+// Reject remove the named suggestion from the queue.
+func (q *SuggestionQueue) Reject(name string) error {
+	if !isSuggestionName(name) {
+		return fmt.Errorf("local: bad suggestion name %q", name)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return os.Remove(q.filename(name))
+}
+
+// Accept patch the doc_$(lang).go file with the named suggestion,
+// and remove it from the queue.
+func (q *SuggestionQueue) Accept(name string) error {
+	s, err := q.Get(name)
+	if err != nil {
+		return err
+	}
+	if err := PatchDoc(s.Lang, s.ImportPath, s.Id, s.Text); err != nil {
+		return err
+	}
+	return q.Reject(name)
+}
+
+func (q *SuggestionQueue) filename(name string) string {
+	return filepath.Join(q.Dir, name+".json")
+}
+
+func (q *SuggestionQueue) load(filename string) (*Suggestion, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Suggestion)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("local: %s: %v", filename, err)
+	}
+	return s, nil
+}
+
+func isSuggestionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// PatchDoc replace the doc of the package identifier in the
+// translation file on disk.
+func PatchDoc(lang, importPath, id, text string) error {
+	if defaultLocalRoot == "" {
+		return errors.New("local: translations root is not on disk")
+	}
+	filename := docFilename(defaultLocalRoot, lang, importPath)
+	if filename == "" {
+		return fmt.Errorf("local: no translation file for %s", mapKey(lang, importPath, id))
+	}
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	src, err = patchDoc(src, id, text)
+	if err != nil {
+		return fmt.Errorf("local: %s: %v", filename, err)
+	}
+	if err := ioutil.WriteFile(filename, src, 0644); err != nil {
+		return err
+	}
+
+	// parse the patched file on next access
+	ResetCache()
+	unregisterPackage(lang, importPath)
+	dropBundlePackage(lang, importPath)
+	return nil
+}
+
+// docFilename return the first translation file existing on disk,
+// in the same order as localTranslater.loadDocCode.
+func docFilename(root, lang, importPath string) string {
+	for _, dir := range localVersionDirs() {
+		for _, name := range []string{
+			fmt.Sprintf("doc_%s_%s_%s.go", lang, defaultGodocGoos, defaultGodocGoarch),
+			fmt.Sprintf("doc_%s_%s.go", lang, defaultGodocGoarch),
+			fmt.Sprintf("doc_%s_%s.go", lang, defaultGodocGoos),
+			fmt.Sprintf("doc_%s.go", lang),
+		} {
+			filename := filepath.Join(root, filepath.FromSlash(dir), "src", filepath.FromSlash(importPath), name)
+			if fi, err := os.Stat(filename); err == nil && !fi.IsDir() {
+				return filename
+			}
+		}
+	}
+	return ""
+}
+
+// patchDoc replace the doc comment of id in the Go source src. The
+// review directives of the comment are kept, except that the status of
+// an identifier is reset to draft: the new text is not reviewed. The
+// directives of the package doc are the default of all identifiers,
+// they are kept unchanged; without a status in the file, none is added.
+func patchDoc(src []byte, id, text string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *ast.CommentGroup
+	var pos token.Pos
+	if id == "" || id == __doc__ {
+		doc, pos = f.Doc, f.Package
+	} else if doc, pos = findDocComment(f, id); !pos.IsValid() {
+		return nil, fmt.Errorf("%s not found", id)
+	}
+
+	start := fset.Position(pos).Offset
+	end := start
+	if doc != nil {
+		start = fset.Position(doc.Pos()).Offset
+		end = fset.Position(doc.End()).Offset
+	}
+
+	directives := func(doc *ast.CommentGroup, prefix string) (list []string) {
+		if doc != nil {
+			for _, c := range doc.List {
+				if strings.HasPrefix(c.Text, metaPrefix+prefix) {
+					list = append(list, c.Text)
+				}
+			}
+		}
+		return list
+	}
+	meta := directives(doc, "")
+	if id != "" && id != __doc__ && (meta != nil || directives(f.Doc, "status") != nil) {
+		list := meta[:0]
+		for _, line := range meta {
+			if !strings.HasPrefix(line, metaPrefix+"status") {
+				list = append(list, line)
+			}
+		}
+		meta = append(list, metaPrefix+"status draft")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(src[:start])
+	buf.WriteString(commentLines(text))
+	if len(meta) > 0 {
+		buf.WriteString("\n//\n" + strings.Join(meta, "\n"))
+	}
+	if doc == nil {
+		buf.WriteString("\n")
+	}
+	buf.Write(src[end:])
+	return format.Source(buf.Bytes())
+}
+
+// findDocComment return the doc comment of id as used by go/doc,
+// and the position of the declaration.
+func findDocComment(f *ast.File, id string) (doc *ast.CommentGroup, pos token.Pos) {
+	typeName, name := "", id
+	if i := strings.Index(id, "."); i >= 0 {
+		typeName, name = id[:i], id[i+1:]
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name == name && recvTypeName(d) == typeName {
+				return d.Doc, d.Pos()
+			}
+		case *ast.GenDecl:
+			if typeName != "" {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Name != name {
+						continue
+					}
+					if s.Doc != nil || d.Lparen.IsValid() {
+						return s.Doc, s.Pos()
+					}
+					return d.Doc, d.Pos()
+				case *ast.ValueSpec:
+					for _, ident := range s.Names {
+						if ident.Name == name {
+							return d.Doc, d.Pos()
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil, token.NoPos
+}
+
+func recvTypeName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return ""
+	}
+	typ := d.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// commentLines format text as // comment lines.
+func commentLines(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		switch {
+		case line == "":
+			lines[i] = "//"
+		case line[0] == '\t':
+			lines[i] = "//" + line
+		default:
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
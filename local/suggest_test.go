@@ -0,0 +1,92 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const patchDocSrc = `// +build ingore
+
+// Package foo 是一个例子.
+package foo
+
+const (
+	A = 1
+	B = 2
+)
+
+// Foo 是一个类型.
+type Foo struct{}
+
+func (f *Foo) Bar() {}
+
+// New 创建 Foo.
+func New() *Foo
+`
+
+func TestPatchDoc(t *testing.T) {
+	for _, tc := range []struct {
+		id, text string
+		want     string
+	}{
+		{"", "Package foo 是新的例子.", "// Package foo 是新的例子.\npackage foo\n"},
+		{"Foo", "Foo 是新类型.\n\n\tvar f Foo", "// Foo 是新类型.\n//\n//\tvar f Foo\ntype Foo struct{}\n"},
+		{"Foo.Bar", "Bar 方法.", "// Bar 方法.\nfunc (f *Foo) Bar() {}\n"},
+		{"New", "New 返回新的 Foo.", "// New 返回新的 Foo.\nfunc New() *Foo\n"},
+		{"B", "常量.", "// 常量.\nconst (\n"},
+	} {
+		src, err := patchDoc([]byte(patchDocSrc), tc.id, tc.text)
+		if err != nil {
+			t.Errorf("patchDoc(%q): %v", tc.id, err)
+			continue
+		}
+		if !strings.Contains(string(src), tc.want) {
+			t.Errorf("patchDoc(%q) = %s; want %q", tc.id, src, tc.want)
+		}
+	}
+
+	// the review directives are kept, the status of identifiers is reset
+	metaSrc := strings.Replace(patchDocSrc, "// Foo 是一个类型.\n", "// Foo 是一个类型.\n//\n//golangdoc:translator chai2010\n//golangdoc:status reviewed\n", 1)
+	metaSrc = strings.Replace(metaSrc, "package foo\n", "//\n//golangdoc:status reviewed\npackage foo\n", 1)
+	for _, tc := range []struct {
+		id, want string
+	}{
+		{"", "// 新的.\n//\n//golangdoc:status reviewed\npackage foo\n"},
+		{"Foo", "// 新的.\n//\n//golangdoc:translator chai2010\n//golangdoc:status draft\ntype Foo struct{}\n"},
+		{"New", "// 新的.\n//\n//golangdoc:status draft\nfunc New() *Foo\n"},
+	} {
+		src, err := patchDoc([]byte(metaSrc), tc.id, "新的.")
+		if err != nil {
+			t.Errorf("patchDoc(%q): %v", tc.id, err)
+			continue
+		}
+		if !strings.Contains(string(src), tc.want) {
+			t.Errorf("patchDoc(%q) = %s; want %q", tc.id, src, tc.want)
+		}
+	}
+
+	if _, err := patchDoc([]byte(patchDocSrc), "Missing", "x"); err == nil {
+		t.Errorf("patchDoc(%q): expected error", "Missing")
+	}
+}
+
+func TestNewSuggestionName(t *testing.T) {
+	now := time.Now()
+	a, b := newSuggestionName(now), newSuggestionName(now)
+	if a == b {
+		t.Errorf("newSuggestionName: same name %q for the same time", a)
+	}
+	for _, name := range []string{a, b} {
+		if !isSuggestionName(name) {
+			t.Errorf("newSuggestionName: bad name %q", name)
+		}
+	}
+	if later := newSuggestionName(now.Add(time.Second)); later < a || later < b {
+		t.Errorf("newSuggestionName: %q sorts before %q, %q", later, a, b)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// The search synonyms of a language is the json file
+// $(translations)/synonyms/$(lang).json, which maps the native
+// terms to the Go identifiers:
+//
+//	{
+//		"互斥锁": ["sync.Mutex"],
+//		"超时": ["context.WithTimeout", "time.After"]
+//	}
+var (
+	synonymMu    sync.Mutex
+	synonymTable = make(map[string]map[string][]string) // map[lang]map[term]..., registered
+	synonymFiles = make(map[string]map[string][]string) // map[lang]map[term]..., loaded from synonyms files
+)
+
+// RegisterSynonyms Register the search synonyms of lang.
+func RegisterSynonyms(lang string, synonyms map[string][]string) {
+	m := make(map[string][]string, len(synonyms))
+	for term, ids := range synonyms {
+		m[synonymKey(term)] = ids
+	}
+
+	synonymMu.Lock()
+	defer synonymMu.Unlock()
+	synonymTable[lang] = m
+}
+
+// Synonyms return the Go identifiers of the query in lang: those of
+// the whole query, or else those of its words.
+func Synonyms(lang, query string) []string {
+	if lang == "" {
+		return nil
+	}
+
+	synonymMu.Lock()
+	synonyms, ok := synonymTable[lang]
+	if !ok {
+		if synonyms, ok = synonymFiles[lang]; !ok {
+			synonyms = loadSynonyms(localFS(), lang)
+			synonymFiles[lang] = synonyms // nil if no synonyms file
+		}
+	}
+	synonymMu.Unlock()
+
+	if ids := synonyms[synonymKey(query)]; len(ids) > 0 {
+		return ids
+	}
+	var list []string
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(query) {
+		for _, id := range synonyms[synonymKey(word)] {
+			if !seen[id] {
+				list = append(list, id)
+				seen[id] = true
+			}
+		}
+	}
+	return list
+}
+
+func synonymKey(term string) string {
+	return strings.ToLower(strings.Join(strings.Fields(term), " "))
+}
+
+func loadSynonyms(fs vfs.FileSystem, lang string) map[string][]string {
+	filename := "/synonyms/" + lang + ".json"
+	data, err := vfs.ReadFile(fs, filename)
+	if err != nil {
+		return nil
+	}
+	var synonyms map[string][]string
+	if err := json.Unmarshal(data, &synonyms); err != nil {
+		log.Printf("local: %s: %v", filename, err)
+		return nil
+	}
+	m := make(map[string][]string, len(synonyms))
+	for term, ids := range synonyms {
+		m[synonymKey(term)] = ids
+	}
+	return m
+}
+
+func resetSynonyms() {
+	synonymMu.Lock()
+	defer synonymMu.Unlock()
+	synonymFiles = make(map[string]map[string][]string)
+}
@@ -0,0 +1,62 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestSynonyms(t *testing.T) {
+	RegisterSynonyms("zz", map[string][]string{
+		"互斥锁":       {"sync.Mutex"},
+		"超时":        {"context.WithTimeout", "time.After"},
+		"Read Lock": {"sync.RWMutex.RLock"},
+	})
+	defer func() {
+		synonymMu.Lock()
+		delete(synonymTable, "zz")
+		synonymMu.Unlock()
+	}()
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"互斥锁", []string{"sync.Mutex"}},
+		{" read  lock ", []string{"sync.RWMutex.RLock"}},
+		{"互斥锁 超时", []string{"sync.Mutex", "context.WithTimeout", "time.After"}},
+		{"通道", nil},
+	} {
+		if got := Synonyms("zz", tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Synonyms(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got := Synonyms("", "互斥锁"); got != nil {
+		t.Errorf("Synonyms without lang = %v, want nil", got)
+	}
+}
+
+func TestLoadSynonyms(t *testing.T) {
+	fs := mapfs.New(map[string]string{
+		"synonyms/zh_CN.json": `{"互斥锁": ["sync.Mutex"], "Goroutine": ["runtime.Gosched"]}`,
+		"synonyms/bad.json":   `{`,
+	})
+	want := map[string][]string{
+		"互斥锁":       {"sync.Mutex"},
+		"goroutine": {"runtime.Gosched"},
+	}
+	if got := loadSynonyms(fs, "zh_CN"); !reflect.DeepEqual(got, want) {
+		t.Errorf("loadSynonyms = %v, want %v", got, want)
+	}
+	if got := loadSynonyms(fs, "bad"); got != nil {
+		t.Errorf("loadSynonyms(bad) = %v, want nil", got)
+	}
+	if got := loadSynonyms(fs, "ja"); got != nil {
+		t.Errorf("loadSynonyms(ja) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/godoc/vfs"
+)
+
+// The translations root may hold version-specific trees beside the
+// shared base tree:
+//
+//	translations/src/...         shared base
+//	translations/go1.4/src/...   for Go 1.4
+//	translations/go1.5/src/...   for Go 1.5 and later
+//
+// The served GOROOT version is read from its VERSION file, and the
+// trees of the same or older versions are layered over the base tree,
+// the nearest version first.
+
+// GoVersion return the Go version of the served GOROOT, e.g. "go1.5.1".
+// It is empty if the GOROOT has no VERSION file.
+func GoVersion() string {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	return defaultGoVersion
+}
+
+// localVersionDirs return the prefixes of the translation trees in
+// defaultLocalBaseFS, the nearest version first and the base tree last.
+func localVersionDirs() []string {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+	dirs := make([]string, 0, len(defaultLocalVersions)+1)
+	for _, dir := range defaultLocalVersions {
+		dirs = append(dirs, "/"+dir)
+	}
+	return append(dirs, "")
+}
+
+// getGoVersion return the first line of the VERSION file in rootfs.
+func getGoVersion(rootfs vfs.FileSystem) string {
+	data, err := vfs.ReadFile(rootfs, "/VERSION")
+	if err != nil {
+		return ""
+	}
+	s := string(data)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// getVersionedNS return the namespace of the translations in localfs
+// for the Go version, and the version dirs used, the nearest first.
+func getVersionedNS(localfs vfs.FileSystem, version string) (vfs.NameSpace, []string) {
+	dirs := versionDirs(localfs, version)
+	ns := getNameSpace(localfs, "/")
+	for i := len(dirs) - 1; i >= 0; i-- {
+		ns.Bind("/", localfs, "/"+dirs[i], vfs.BindBefore)
+	}
+	return ns, dirs
+}
+
+// versionDirs return the version dirs in localfs which are not newer
+// than version, the nearest first. If version is unknown (e.g. a devel
+// GOROOT), all the version dirs are used.
+func versionDirs(localfs vfs.FileSystem, version string) []string {
+	fis, err := localfs.ReadDir("/")
+	if err != nil {
+		return nil
+	}
+	v := parseVersion(version)
+
+	var dirs []string
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		if dv := parseVersion(fi.Name()); dv != nil && (v == nil || compareVersion(dv, v) <= 0) {
+			dirs = append(dirs, fi.Name())
+		}
+	}
+	sort.Sort(byVersion(dirs))
+	return dirs
+}
+
+// parseVersion parse a version like "go1.5.1" or "go1.5beta1",
+// and return nil for other names.
+func parseVersion(s string) []int {
+	if !strings.HasPrefix(s, "go") {
+		return nil
+	}
+	var v []int
+	for _, field := range strings.Split(s[2:], ".") {
+		i := 0
+		for i < len(field) && '0' <= field[i] && field[i] <= '9' {
+			i++
+		}
+		n, err := strconv.Atoi(field[:i])
+		if err != nil {
+			break
+		}
+		v = append(v, n)
+		if i < len(field) {
+			break // e.g. "5beta1"
+		}
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
+
+func compareVersion(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// byVersion sort the version dirs, the newest first.
+type byVersion []string
+
+func (p byVersion) Len() int      { return len(p) }
+func (p byVersion) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byVersion) Less(i, j int) bool {
+	return compareVersion(parseVersion(p[i]), parseVersion(p[j])) > 0
+}
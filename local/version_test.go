@@ -0,0 +1,64 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package local
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/godoc/vfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestVersionedNS(t *testing.T) {
+	localfs := mapfs.New(map[string]string{
+		"src/a/doc_zh_CN.go":       "base a",
+		"src/b/doc_zh_CN.go":       "base b",
+		"src/c/doc_zh_CN.go":       "base c",
+		"go1.4/src/b/doc_zh_CN.go": "go1.4 b",
+		"go1.4/src/c/doc_zh_CN.go": "go1.4 c",
+		"go1.5/src/c/doc_zh_CN.go": "go1.5 c",
+		"go1.6/src/a/doc_zh_CN.go": "go1.6 a",
+		"notes/README":             "not a version",
+	})
+
+	for _, tc := range []struct {
+		version string
+		dirs    []string
+		files   map[string]string
+	}{
+		{"go1.5.1", []string{"go1.5", "go1.4"}, map[string]string{"a": "base a", "b": "go1.4 b", "c": "go1.5 c"}},
+		{"go1.4", []string{"go1.4"}, map[string]string{"a": "base a", "b": "go1.4 b", "c": "go1.4 c"}},
+		{"go1.3", nil, map[string]string{"a": "base a", "b": "base b", "c": "base c"}},
+		{"devel +abcdef", []string{"go1.6", "go1.5", "go1.4"}, map[string]string{"a": "go1.6 a", "b": "go1.4 b", "c": "go1.5 c"}},
+	} {
+		ns, dirs := getVersionedNS(localfs, tc.version)
+		if !reflect.DeepEqual(dirs, tc.dirs) {
+			t.Errorf("%s: dirs = %v; want %v", tc.version, dirs, tc.dirs)
+		}
+		for pkg, want := range tc.files {
+			data, err := vfs.ReadFile(ns, "/src/"+pkg+"/doc_zh_CN.go")
+			if err != nil || string(data) != want {
+				t.Errorf("%s: %s = %q, %v; want %q", tc.version, pkg, data, err, want)
+			}
+		}
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	for s, want := range map[string][]int{
+		"go1.5":      {1, 5},
+		"go1.5.1":    {1, 5, 1},
+		"go1.5beta1": {1, 5},
+		"go1":        {1},
+		"devel +abc": nil,
+		"notes":      nil,
+		"go":         nil,
+	} {
+		if got := parseVersion(s); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseVersion(%q) = %v; want %v", s, got, want)
+		}
+	}
+}
@@ -32,13 +32,15 @@ import (
 	"flag"
 	"fmt"
 	"go/build"
-	"go/doc"
+	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	_ "net/http/pprof" // to serve /debug/pprof/*
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
@@ -60,6 +62,9 @@ var (
 	// (with e.g.: zip -r go.zip $GOROOT -i \*.go -i \*.html -i \*.css -i \*.js -i \*.txt -i \*.c -i \*.h -i \*.s -i \*.png -i \*.jpg -i \*.sh -i favicon.ico)
 	flagZipfile = flag.String("zip", "", "zip file providing the file system to serve; disabled if empty")
 
+	// watch the file system for changes
+	flagWatch = flag.Bool("watch", false, "refresh the directory tree, metadata and index when files change under GOROOT or GOPATH, and reload the translations when they change")
+
 	// file-based index
 	flagWriteIndex = flag.Bool("write_index", false, "write index to a file; the file name must be specified with -index_files")
 
@@ -97,12 +102,21 @@ var (
 	flagIndexFiles    = flag.String("index_files", "", "glob pattern specifying index files; if not empty, the index is read from these files in sorted order")
 	flagMaxResults    = flag.Int("maxresults", 10000, "maximum number of full text search results shown")
 	flagIndexThrottle = flag.Float64("index_throttle", 0.75, "index throttle value; 0.0 = no time allocated, 1.0 = full throttle")
+	flagIndexLangs    = flag.String("index_langs", "", "comma-separated languages of the translated docs to index; default is -lang")
 
 	// source code notes
 	flagNotesRx = flag.String("notes", "BUG", "regular expression matching note markers to show")
 
 	// local language
-	flagLang = flag.String("lang", "", "local language")
+	flagLang      = flag.String("lang", "", "local language")
+	flagMinStatus = flag.String("minstatus", "", "minimum review status of the shown translations (machine, draft, reviewed)")
+
+	// translation suggestions
+	flagSuggestDir   = flag.String("suggest_dir", "", "directory storing translation suggestions of readers; disabled if empty")
+	flagSuggestAdmin = flag.String("suggest_admin", "", "password of the /admin/suggest/ page; if empty, the page is disabled")
+
+	// translation checks
+	flagCheckSpec = flag.Bool("check_spec", false, "check the grammar of the spec and its translations, and exit")
 )
 
 func usage() {
@@ -156,6 +170,67 @@ func handleURLFlag() {
 	log.Fatalf("too many redirects")
 }
 
+// watchRoots returns the directories of the operating system bound in
+// the file system: GOROOT and the GOPATH source trees. The translations
+// are watched apart, see Corpus.TranslationRoots.
+func watchRoots() []string {
+	if *flagZipfile != "" {
+		return nil
+	}
+	roots := []string{*flagGoroot}
+	for _, p := range filepath.SplitList(build.Default.GOPATH) {
+		roots = append(roots, filepath.Join(p, "src"))
+	}
+	return roots
+}
+
+// writeIndex writes the search index of c to the file filename. The
+// unchanged shards of the index file already there are reused; the file
+// is replaced only if the index was written.
+func writeIndex(c *godoc.Corpus, filename string) (err error) {
+	var old io.ReaderAt
+	oldFile, err := os.Open(filename)
+	if err == nil {
+		old = oldFile
+	}
+	closeOld := func() {
+		if oldFile != nil {
+			oldFile.Close()
+			oldFile = nil
+		}
+	}
+	defer closeOld()
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err = c.WriteIndex(f, old); err != nil {
+		return err
+	}
+	closeOld() // an open file cannot be replaced on Windows
+	if err = f.Chmod(0644); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// reloadTranslations drops the loaded translations and the translated
+// templates after a change of the translation files.
+func reloadTranslations() {
+	local.Reload()
+	pres.ResetTemplates()
+}
+
 func runGodoc() {
 	if *flagLocalRoot == "" {
 		if s := os.Getenv("GODOC_LOCAL_ROOT"); s != "" {
@@ -169,6 +244,13 @@ func runGodoc() {
 	fs.Bind("/lib/godoc", local.StaticFS(*flagLang), "/", vfs.BindReplace)
 	fs.Bind("/doc", local.DocumentFS(*flagLang), "/", vfs.BindReplace)
 
+	if *flagCheckSpec {
+		if checkSpec(os.Stdout) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
 	httpMode := *flagHttpAddr != ""
 
 	var typeAnalysis, pointerAnalysis bool
@@ -188,30 +270,15 @@ func runGodoc() {
 	corpus := godoc.NewCorpus(fs)
 
 	// translate hook
-	corpus.SummarizePackage = func(importPath string, langs ...string) (summary string, showList, ok bool) {
-		lang := *flagLang
-		if len(langs) > 0 && langs[0] != "" {
-			lang = langs[0]
-		}
-		if lang == "en" || lang == "raw" || lang == "EN" {
-			lang = ""
-		}
-		if pkg := local.Package(lang, importPath); pkg != nil {
-			summary = doc.Synopsis(pkg.Doc)
-		}
-		ok = (summary != "")
-		return
-	}
-	corpus.TranslateDocPackage = func(pkg *doc.Package, langs ...string) *doc.Package {
-		lang := *flagLang
-		if len(langs) > 0 && langs[0] != "" {
-			lang = langs[0]
-		}
-		if lang == "en" || lang == "raw" || lang == "EN" {
-			lang = ""
-		}
-		return local.Package(lang, pkg.ImportPath, pkg)
+	corpus.SummarizePackage = summarizePackage
+	corpus.TranslateDocPackage = translateDocPackage
+	corpus.DocumentFS = documentFS
+	corpus.Synonyms = synonyms
+	corpus.TranslateDoc = translateDoc
+	if root := local.TranslationsRoot(); root != "" {
+		corpus.TranslationRoots = []string{root}
 	}
+	corpus.ReloadTranslations = reloadTranslations
 
 	corpus.Verbose = *flagVerbose
 	corpus.MaxResults = *flagMaxResults
@@ -221,6 +288,7 @@ func runGodoc() {
 	}
 	corpus.IndexFiles = *flagIndexFiles
 	corpus.IndexThrottle = *flagIndexThrottle
+	corpus.IndexLangs = indexLangs(*flagIndexLangs)
 	if *flagWriteIndex {
 		corpus.IndexThrottle = 1.0
 		corpus.IndexEnabled = true
@@ -243,8 +311,18 @@ func runGodoc() {
 		pres.NotesRx = regexp.MustCompile(*flagNotesRx)
 	}
 
+	pres.Lang = localLang("")
+	pres.TranslateMessage = local.Message
+	pres.TemplatesFor = langTemplates
+	pres.PackageHeader = statusBadge
+
 	readTemplates(pres, httpMode || *flagUrlFlag != "")
 	registerHandlers(pres)
+	if *flagSuggestDir != "" {
+		if err := registerSuggestHandlers(pres, *flagSuggestDir); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	if *flagWriteIndex {
 		// Write search index and exit.
@@ -255,16 +333,8 @@ func runGodoc() {
 		log.Println("initialize file systems")
 		*flagVerbose = true // want to see what happens
 
-		corpus.UpdateIndex()
-
 		log.Println("writing index file", *flagIndexFiles)
-		f, err := os.Create(*flagIndexFiles)
-		if err != nil {
-			log.Fatal(err)
-		}
-		index, _ := corpus.CurrentIndex()
-		_, err = index.WriteTo(f)
-		if err != nil {
+		if err := writeIndex(corpus, *flagIndexFiles); err != nil {
 			log.Fatal(err)
 		}
 
@@ -303,6 +373,13 @@ func runGodoc() {
 			go corpus.RunIndexer()
 		}
 
+		// Watch the file system.
+		if *flagWatch {
+			if roots := watchRoots(); len(roots) > 0 {
+				go corpus.RunWatcher(roots)
+			}
+		}
+
 		// Start type/pointer analysis.
 		if typeAnalysis || pointerAnalysis {
 			go analysis.Run(pointerAnalysis, &corpus.Analysis)
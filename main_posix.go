@@ -18,7 +18,7 @@ func main() {
 	playEnabled = *flagShowPlayground
 
 	// Check usage: either server and no args, command line and args, or index creation mode
-	if (*flagHttpAddr != "" || *flagUrlFlag != "") != (flag.NArg() == 0) && !*flagWriteIndex {
+	if (*flagHttpAddr != "" || *flagUrlFlag != "") != (flag.NArg() == 0) && !*flagWriteIndex && !*flagCheckSpec {
 		usage()
 	}
 
@@ -7,41 +7,133 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
+
+	"github.com/golang-china/golangdoc/godoc"
 )
 
 func handleRemoteSearch() {
 	// Command-line queries.
 	for i := 0; i < flag.NArg(); i++ {
-		res, err := remoteSearch(flag.Arg(i))
+		query := flag.Arg(i)
+		mode := "json"
+		if *flagHtml {
+			mode = ""
+		}
+		res, err := remoteSearch(query, mode)
 		if err != nil {
 			log.Fatalf("remoteSearch: %s", err)
 		}
-		io.Copy(os.Stdout, res.Body)
+		if mode == "json" && !isJSON(res.Header.Get("Content-Type")) {
+			// the server does not support m=json: ask for text
+			res.Body.Close()
+			if res, err = remoteSearch(query, "text"); err != nil {
+				log.Fatalf("remoteSearch: %s", err)
+			}
+			mode = "text"
+		}
+		if mode == "json" {
+			var result godoc.SearchJSON
+			if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+				log.Fatalf("remoteSearch: %s", err)
+			}
+			printSearchJSON(os.Stdout, &result)
+		} else {
+			io.Copy(os.Stdout, res.Body)
+		}
+		res.Body.Close()
 	}
 	return
 }
 
+// isJSON reports whether the media type of the Content-Type header
+// value contentType is JSON.
+func isJSON(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	return err == nil && t == "application/json"
+}
+
+// printSearchJSON prints the search result r in textual form.
+func printSearchJSON(w io.Writer, r *godoc.SearchJSON) {
+	fmt.Fprintf(w, "QUERY\n\t%s\n\n", r.Query)
+	if r.Alert != "" {
+		fmt.Fprintf(w, "%s\n\n", r.Alert)
+	}
+	if len(r.Alts) > 0 {
+		fmt.Fprintf(w, "DID YOU MEAN\n\n")
+		for _, alt := range r.Alts {
+			fmt.Fprintf(w, "\t%s\n", alt)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Packages) > 0 {
+		fmt.Fprintf(w, "PACKAGE %s\n\n", r.Query)
+		for _, path := range r.Packages {
+			fmt.Fprintf(w, "\t%s\n", path)
+		}
+		fmt.Fprintln(w)
+	}
+	printHits := func(title string, list []godoc.PakHitsJSON) {
+		if len(list) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s\n\n", title)
+		for _, pak := range list {
+			fmt.Fprintf(w, "package %s (%s)\n", pak.Name, pak.Path)
+			for _, hit := range pak.Hits {
+				fmt.Fprintf(w, "\t%s:%d\t%s\n", hit.File, hit.Line, hit.Kind)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	printHits("PACKAGE-LEVEL DECLARATIONS", r.Decls)
+	printHits("LOCAL DECLARATIONS AND USES", r.Others)
+	if len(r.Idents) > 0 {
+		fmt.Fprintf(w, "IDENTIFIERS\n\n")
+		for _, id := range r.Idents {
+			fmt.Fprintf(w, "\t%s.%s\t%s\t%s\n", id.Path, id.Name, id.Kind, id.Doc)
+		}
+		fmt.Fprintln(w)
+	}
+	if len(r.Textual) > 0 {
+		if r.Complete {
+			fmt.Fprintf(w, "%d TEXTUAL OCCURRENCES\n\n", r.Found)
+		} else {
+			fmt.Fprintf(w, "MORE THAN %d TEXTUAL OCCURRENCES\n\n", r.Found)
+		}
+		for _, f := range r.Textual {
+			fmt.Fprintf(w, "\t%s:", f.File)
+			for _, line := range f.Lines {
+				fmt.Fprintf(w, " %d", line)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
+
 // remoteSearchURL returns the search URL for a given query as needed by
-// remoteSearch. If html is set, an html result is requested; otherwise
-// the result is in textual form.
+// remoteSearch. The mode is the form value m, e.g. "json" or "text";
+// an empty mode requests an html result.
 // Adjust this function as necessary if modeNames or FormValue parameters
 // change.
-func remoteSearchURL(query string, html bool) string {
-	s := "/search?m=text&q="
-	if html {
-		s = "/search?q="
+func remoteSearchURL(query, mode string) string {
+	s := "/search?q="
+	if mode != "" {
+		s = "/search?m=" + mode + "&q="
 	}
 	return s + url.QueryEscape(query)
 }
 
-func remoteSearch(query string) (res *http.Response, err error) {
+func remoteSearch(query, mode string) (res *http.Response, err error) {
 	// list of addresses to try
 	var addrs []string
 	if *flagServerAddr != "" {
@@ -55,7 +147,7 @@ func remoteSearch(query string) (res *http.Response, err error) {
 	}
 
 	// remote search
-	search := remoteSearchURL(query, *flagHtml)
+	search := remoteSearchURL(query, mode)
 	for _, addr := range addrs {
 		url := "http://" + addr + search
 		res, err = http.Get(url)
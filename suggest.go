@@ -0,0 +1,281 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+
+// This file contains the handlers that collect translation suggestions
+// from readers, and the admin page to review them.
+//
+//	/suggest/		POST a suggestion for a package identifier
+//	/admin/suggest/		list, accept or reject the suggestions
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang-china/golangdoc/godoc"
+	"github.com/golang-china/golangdoc/local"
+)
+
+var (
+	suggestQueue *local.SuggestionQueue
+
+	// suggestAdminToken is the CSRF token of the admin page forms:
+	// a page of another site can post to the admin page of a local
+	// server, but cannot read the token.
+	suggestAdminToken string
+)
+
+// maxSuggestBody is the maximum size of a suggestion request body.
+const maxSuggestBody = 2 * local.MaxSuggestionText
+
+func registerSuggestHandlers(pres *godoc.Presentation, dir string) error {
+	q, err := local.NewSuggestionQueue(dir)
+	if err != nil {
+		return err
+	}
+	suggestQueue = q
+
+	var token [16]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return err
+	}
+	suggestAdminToken = fmt.Sprintf("%x", token)
+
+	pres.PackageFooter = suggestFooter
+	http.HandleFunc("/suggest/", suggestHandler)
+	if *flagSuggestAdmin == "" {
+		// behind a reverse proxy, every client would be local
+		log.Print("suggestion admin page disabled: no -suggest_admin password")
+		return nil
+	}
+	http.HandleFunc("/admin/suggest/", suggestAdminHandler)
+	return nil
+}
+
+// suggestFooter returns the suggestion form for a translated package page.
+func suggestFooter(info *godoc.PageInfo) []byte {
+	lang := localLang(info.Lang)
+	if lang == "" || info.PDoc == nil {
+		return nil
+	}
+	if _, ok := local.DocText(lang, info.PDoc.ImportPath, ""); !ok {
+		return nil // package is not translated
+	}
+	data := struct {
+		Lang, ImportPath string
+		Ids              []string
+	}{lang, info.PDoc.ImportPath, docIds(info.PDoc)}
+
+	var buf bytes.Buffer
+	if err := executeLang(&buf, suggestFooterTemplate, lang, data); err != nil {
+		log.Println("suggestFooter:", err)
+	}
+	return buf.Bytes()
+}
+
+func suggestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSuggestBody)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lang := r.FormValue("lang")
+	importPath := r.FormValue("pkg")
+	s, err := suggestQueue.Add(lang, importPath, r.FormValue("id"), r.FormValue("text"), r.RemoteAddr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("suggest: %s from %s", s.Key, s.Remote)
+
+	pkgURL := "/pkg/" + importPath + "/?lang=" + url.QueryEscape(lang)
+	pres.ServePage(w, godoc.Page{
+		Title: "Translation suggestion",
+		Body: []byte(fmt.Sprintf(`<p>Thank you, your suggestion has been queued for review.</p><p><a href="%s">Back to package %s</a></p>`,
+			template.HTMLEscapeString(pkgURL), template.HTMLEscapeString(importPath))),
+	})
+}
+
+func suggestAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if !suggestAdminAllowed(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="golangdoc"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == "POST" {
+		if !sameOrigin(r) || subtle.ConstantTimeCompare([]byte(r.FormValue("token")), []byte(suggestAdminToken)) != 1 {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var err error
+		switch name := r.FormValue("name"); r.FormValue("action") {
+		case "accept":
+			err = suggestQueue.Accept(name)
+		case "reject":
+			err = suggestQueue.Reject(name)
+		default:
+			err = fmt.Errorf("unknown action %q", r.FormValue("action"))
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.Redirect(w, r, "/admin/suggest/", http.StatusSeeOther)
+		return
+	}
+
+	list, err := suggestQueue.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	type item struct {
+		*local.Suggestion
+		Diff []diffLine
+	}
+	var items []item
+	for _, s := range list {
+		current, _ := local.DocText(s.Lang, s.ImportPath, s.Id)
+		items = append(items, item{s, diffLines(current, s.Text)})
+	}
+
+	data := struct {
+		Token string
+		Items []item
+	}{suggestAdminToken, items}
+	var buf bytes.Buffer
+	if err := suggestAdminTemplate.Execute(&buf, data); err != nil {
+		log.Println("suggestAdminHandler:", err)
+	}
+	pres.ServePage(w, godoc.Page{
+		Title: "Translation suggestions",
+		Body:  buf.Bytes(),
+	})
+}
+
+// suggestAdminAllowed reports whether r may use the admin page: with
+// the -suggest_admin password, which must be set.
+func suggestAdminAllowed(r *http.Request) bool {
+	_, password, ok := r.BasicAuth()
+	return ok && *flagSuggestAdmin != "" &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(*flagSuggestAdmin)) == 1
+}
+
+// sameOrigin reports whether the Origin or, without it, the Referer
+// header of r is on the host of r. Requests without both are allowed,
+// the CSRF token protects them.
+func sameOrigin(r *http.Request) bool {
+	for _, h := range []string{"Origin", "Referer"} {
+		if v := r.Header.Get(h); v != "" {
+			u, err := url.Parse(v)
+			return err == nil && u.Host == r.Host
+		}
+	}
+	return true
+}
+
+// A diffLine is a line of a line-based diff.
+type diffLine struct {
+	Op   string // " " for unchanged, "-" for deleted, "+" for added
+	Text string
+}
+
+// diffLines returns the diff from a to b, computed from the
+// longest common subsequence of their lines.
+func diffLines(a, b string) []diffLine {
+	x := strings.Split(a, "\n")
+	y := strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			switch {
+			case x[i] == y[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff []diffLine
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			diff = append(diff, diffLine{" ", x[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, diffLine{"-", x[i]})
+			i++
+		default:
+			diff = append(diff, diffLine{"+", y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		diff = append(diff, diffLine{"-", x[i]})
+	}
+	for ; j < len(y); j++ {
+		diff = append(diff, diffLine{"+", y[j]})
+	}
+	return diff
+}
+
+var suggestFooterTemplate = template.Must(template.New("suggestFooter").Funcs(langFuncs).Parse(`
+<div id="suggest">
+<h2>{{T "Suggest a better translation"}}</h2>
+<form method="POST" action="/suggest/">
+<input type="hidden" name="lang" value="{{.Lang}}">
+<input type="hidden" name="pkg" value="{{.ImportPath}}">
+<p>
+<select name="id">
+<option value="">{{T "package %s" .ImportPath}}</option>
+{{range .Ids}}<option value="{{.}}">{{.}}</option>
+{{end}}</select>
+</p>
+<p><textarea name="text" rows="8" cols="80"></textarea></p>
+<p><input type="submit" value="{{T "Submit"}}"></p>
+</form>
+</div>
+`))
+
+var suggestAdminTemplate = template.Must(template.New("suggestAdmin").Parse(`
+{{$token := .Token}}{{range .Items}}
+<h2 id="{{.Name}}">{{.ImportPath}}{{with .Id}}.{{.}}{{end}} @{{.Lang}}</h2>
+<p>{{.Time.Format "2006-01-02 15:04:05"}} from {{.Remote}}</p>
+<pre>{{range .Diff}}{{if eq .Op "-"}}<span style="color: #c00">- {{.Text}}</span>{{else if eq .Op "+"}}<span style="color: #080">+ {{.Text}}</span>{{else}}  {{.Text}}{{end}}
+{{end}}</pre>
+<form method="POST" action="/admin/suggest/">
+<input type="hidden" name="name" value="{{.Name}}">
+<input type="hidden" name="token" value="{{$token}}">
+<button type="submit" name="action" value="accept">Accept</button>
+<button type="submit" name="action" value="reject">Reject</button>
+</form>
+{{else}}
+<p>No pending suggestions.</p>
+{{end}}
+`))
@@ -0,0 +1,298 @@
+// Copyright 2015 ChaiShushan <chaishushan{AT}gmail.com>. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains the translate hooks of the corpus and the
+// presentation, and the review status badge of the translated
+// package pages.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/doc"
+	"html/template"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/tools/godoc/vfs"
+
+	"github.com/golang-china/golangdoc/godoc"
+	"github.com/golang-china/golangdoc/local"
+)
+
+// localLang returns the translation language for the request language lang.
+// An empty lang selects the -lang flag, and "en" or "raw" the original
+// documentation.
+func localLang(lang string) string {
+	if lang == "" {
+		lang = *flagLang
+	}
+	if lang == "en" || lang == "raw" || lang == "EN" {
+		lang = ""
+	}
+	return lang
+}
+
+var langRx = regexp.MustCompile(`^[a-zA-Z]{2,3}([_-][a-zA-Z0-9]{2,8})?$`)
+
+// requestLang returns the translation language for the request language
+// lang, and reports whether it differs from the default language.
+func requestLang(lang string) (string, bool) {
+	l := localLang(lang)
+	if lang == "" || l == localLang("") {
+		return "", false
+	}
+	if l != "" && !langRx.MatchString(l) {
+		return "", false // unknown language, use the default
+	}
+	return l, true
+}
+
+// documentFS returns the /doc tree of the request language lang,
+// nil if it has no translated /doc tree.
+func documentFS(lang string) vfs.FileSystem {
+	l, ok := requestLang(lang)
+	if !ok || l != "" && !hasLang(local.DocumentLangs(), l) {
+		return nil
+	}
+	return local.DocumentFS(l)
+}
+
+// hasLang reports whether the sorted list langs contains lang.
+func hasLang(langs []string, lang string) bool {
+	i := sort.SearchStrings(langs, lang)
+	return i < len(langs) && langs[i] == lang
+}
+
+// indexLangs returns the languages of the comma-separated list,
+// whose translated docs are indexed. An empty list selects the
+// -lang flag.
+func indexLangs(list string) []string {
+	var langs []string
+	for _, lang := range strings.Split(list, ",") {
+		if lang = localLang(strings.TrimSpace(lang)); lang != "" && langRx.MatchString(lang) {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
+}
+
+// synonyms returns the Go identifiers of the search query in the
+// request language lang.
+func synonyms(lang, query string) []string {
+	if lang = localLang(lang); lang == "" || !langRx.MatchString(lang) {
+		return nil
+	}
+	return local.Synonyms(lang, query)
+}
+
+// translateDoc returns the translated doc of the identifier id of
+// the package importPath in the request language lang.
+func translateDoc(lang, importPath, id string) (string, bool) {
+	if lang = localLang(lang); lang == "" || !langRx.MatchString(lang) {
+		return "", false
+	}
+	return local.DocText(lang, importPath, id)
+}
+
+// langTemplates returns the templates of the request language lang,
+// parsed from its lib/godoc files over the original ones, nil if it
+// has neither translated templates nor message catalog.
+func langTemplates(lang string) *godoc.Templates {
+	l, ok := requestLang(lang)
+	if !ok || l != "" && !hasLang(local.StaticLangs(), l) && !hasLang(local.MessageLangs(), l) {
+		return nil
+	}
+	staticFS := make(vfs.NameSpace)
+	staticFS.Bind("/", local.StaticFS(""), "/", vfs.BindReplace)
+	if l != "" {
+		staticFS.Bind("/", local.StaticFS(l), "/", vfs.BindBefore)
+	}
+	funcs := pres.FuncMapFor(l)
+
+	t := new(godoc.Templates)
+	for _, x := range []struct {
+		p    **texttemplate.Template
+		name string
+	}{
+		{&t.PackageText, "package.txt"},
+		{&t.SearchText, "search.txt"},
+		{&t.CallGraphHTML, "callgraph.html"},
+		{&t.DirlistHTML, "dirlist.html"},
+		{&t.ErrorHTML, "error.html"},
+		{&t.ExampleHTML, "example.html"},
+		{&t.GodocHTML, "godoc.html"},
+		{&t.ImplementsHTML, "implements.html"},
+		{&t.MethodSetHTML, "methodset.html"},
+		{&t.PackageHTML, "package.html"},
+		{&t.SearchHTML, "search.html"},
+		{&t.SearchDocHTML, "searchdoc.html"},
+		{&t.SearchCodeHTML, "searchcode.html"},
+		{&t.SearchTxtHTML, "searchtxt.html"},
+		{&t.SearchDescXML, "opensearch.xml"},
+	} {
+		var err error
+		if *x.p, err = readTemplateFS(staticFS, "/"+x.name, funcs); err != nil {
+			log.Printf("langTemplates(%s): %v", lang, err)
+			return nil
+		}
+	}
+	return t
+}
+
+// langFuncs are the functions of the templates executed by executeLang.
+// The "T" placeholder is replaced by the translation of the messages.
+var langFuncs = template.FuncMap{"T": fmt.Sprintf}
+
+// executeLang applies the template t to data, with the messages
+// translated into lang: {{T "Suggest a better translation"}}.
+func executeLang(w io.Writer, t *template.Template, lang string, data interface{}) error {
+	t, err := t.Clone()
+	if err != nil {
+		return err
+	}
+	t.Funcs(template.FuncMap{"T": func(msg string, args ...interface{}) string {
+		return pres.Translate(lang, msg, args...)
+	}})
+	return t.Execute(w, data)
+}
+
+// minStatus returns the minimum review status for the request status s.
+// An empty s selects the -minstatus flag.
+func minStatus(s string) local.Status {
+	if s == "" {
+		s = *flagMinStatus
+	}
+	return local.ParseStatus(s)
+}
+
+// langArgs returns the language and the minimum review status
+// of the optional lang arguments of the corpus hooks.
+func langArgs(langs []string) (lang string, min local.Status) {
+	lang, min = localLang(""), minStatus("")
+	if len(langs) > 0 {
+		lang = localLang(langs[0])
+	}
+	if len(langs) > 1 {
+		min = minStatus(langs[1])
+	}
+	return
+}
+
+func summarizePackage(importPath string, langs ...string) (summary string, showList, ok bool) {
+	lang, min := langArgs(langs)
+	if min > local.StatusUnknown {
+		if m, _ := local.LookupMeta(lang, importPath, ""); m == nil || m.Status < min {
+			return // fallback to the original synopsis
+		}
+	}
+	if pkg := local.Package(lang, importPath); pkg != nil {
+		summary = doc.Synopsis(pkg.Doc)
+	}
+	ok = (summary != "")
+	return
+}
+
+func translateDocPackage(pkg *doc.Package, langs ...string) *doc.Package {
+	lang, min := langArgs(langs)
+	return local.PackageWithStatus(lang, min, pkg)
+}
+
+// docIds returns the identifiers of pkg which have a doc comment,
+// in the form used by local.DocText.
+func docIds(pkg *doc.Package) []string {
+	var ids []string
+	addValues := func(values []*doc.Value) {
+		for _, v := range values {
+			ids = append(ids, v.Names[0])
+		}
+	}
+	addFuncs := func(funcs []*doc.Func, typeName string) {
+		for _, f := range funcs {
+			if typeName != "" {
+				ids = append(ids, typeName+"."+f.Name)
+			} else {
+				ids = append(ids, f.Name)
+			}
+		}
+	}
+	addValues(pkg.Consts)
+	addValues(pkg.Vars)
+	addFuncs(pkg.Funcs, "")
+	for _, t := range pkg.Types {
+		ids = append(ids, t.Name)
+		addValues(t.Consts)
+		addValues(t.Vars)
+		addFuncs(t.Funcs, "")
+		addFuncs(t.Methods, t.Name)
+	}
+	return ids
+}
+
+// statusBadge returns the review status badge of a translated package page.
+func statusBadge(info *godoc.PageInfo) []byte {
+	lang := localLang(info.Lang)
+	if lang == "" || info.PDoc == nil {
+		return nil
+	}
+	if _, ok := local.DocText(lang, info.PDoc.ImportPath, ""); !ok {
+		return nil // package is not translated
+	}
+
+	type count struct {
+		Status local.Status
+		N      int
+	}
+	counts := []count{
+		{local.StatusReviewed, 0},
+		{local.StatusDraft, 0},
+		{local.StatusMachine, 0},
+		{local.StatusUnknown, 0},
+	}
+	for _, id := range docIds(info.PDoc) {
+		if _, ok := local.DocText(lang, info.PDoc.ImportPath, id); !ok {
+			continue
+		}
+		status := local.StatusUnknown
+		if m, ok := local.LookupMeta(lang, info.PDoc.ImportPath, id); ok {
+			status = m.Status
+		}
+		for i := range counts {
+			if counts[i].Status == status {
+				counts[i].N++
+			}
+		}
+	}
+
+	meta, _ := local.LookupMeta(lang, info.PDoc.ImportPath, "")
+	if meta == nil {
+		meta = new(local.Meta)
+	}
+	data := struct {
+		Lang, ImportPath string
+		MinStatus        local.Status
+		Meta             *local.Meta
+		Counts           []count
+	}{lang, info.PDoc.ImportPath, minStatus(info.MinStatus), meta, counts}
+
+	var buf bytes.Buffer
+	if err := executeLang(&buf, statusBadgeTemplate, lang, data); err != nil {
+		log.Println("statusBadge:", err)
+	}
+	return buf.Bytes()
+}
+
+var statusBadgeTemplate = template.Must(template.New("statusBadge").Funcs(langFuncs).Parse(`
+<div id="translation-status" class="translation-status-{{.Meta.Status}}" style="font-size: 90%; margin: 0.5em 0;">
+<span style="padding: 0 0.5em; border-radius: 3px; color: #fff; background: {{if eq .Meta.Status.String "reviewed"}}#2a2{{else if eq .Meta.Status.String "draft"}}#d90{{else if eq .Meta.Status.String "machine"}}#c33{{else}}#888{{end}};"
+	title="{{with .Meta.Translator}}{{T "translator"}}: {{.}} {{end}}{{with .Meta.Reviewer}}{{T "reviewer"}}: {{.}} {{end}}{{with .Meta.Date}}{{T "date"}}: {{.}}{{end}}">{{.Lang}}: {{T .Meta.Status.String}}</span>
+{{range .Counts}}{{if .N}}{{T .Status.String}} {{.N}} {{end}}{{end}}
+{{if .MinStatus.String | eq "reviewed"}}<a href="?lang={{.Lang}}">{{T "show all translations"}}</a>{{else}}<a href="?lang={{.Lang}}&amp;minstatus=reviewed">{{T "show reviewed translations only"}}</a>{{end}}
+</div>
+`))